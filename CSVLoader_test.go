@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devbiu/CsvView/loader"
+)
+
+// writeTestCsv 生成 rows 条数据记录，第 7 行包含引号内换行
+func writeTestCsv(t *testing.T, name string, rows int) string {
+	t.Helper()
+	var sb strings.Builder
+	sb.WriteString("id,name,note\n")
+	for i := range rows {
+		note := fmt.Sprintf("note %d padding padding padding padding", i)
+		if i == 7 {
+			note = "\"multi\nline\""
+		}
+		fmt.Fprintf(&sb, "%d,name_%d,%s\n", i, i, note)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func waitRow(t *testing.T, l *loader.CSVLoader, row int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if r, ok := l.GetRowV2(row); ok {
+			return r
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("row %d never loaded", row)
+	return nil
+}
+
+func TestCsvLoaderSmallFile(t *testing.T) {
+	l, err := loader.NewCsvLoaderV2(writeTestCsv(t, "small.csv", 100), 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if !l.Indexed() || l.RowCount() != 100 || l.ColCount() != 3 {
+		t.Fatalf("rows=%d cols=%d indexed=%v", l.RowCount(), l.ColCount(), l.Indexed())
+	}
+	if r := waitRow(t, l, 8); r[2] != "multi\nline" {
+		t.Fatalf("quoted newline not kept: %q", r)
+	}
+}
+
+func TestCsvLoaderLargeFile(t *testing.T) {
+	const rows = 200000
+	l, err := loader.NewCsvLoaderV2(writeTestCsv(t, "large.csv", rows), 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	deadline := time.Now().Add(10 * time.Second)
+	for !l.Indexed() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := l.RowCount(); got != rows {
+		t.Fatalf("RowCount = %d, want %d", got, rows)
+	}
+	if h := l.Header(); len(h) != 3 || h[0] != "id" {
+		t.Fatalf("Header = %q", h)
+	}
+	if r := waitRow(t, l, 8); r[2] != "multi\nline" {
+		t.Fatalf("quoted newline not kept: %q", r)
+	}
+	loaded := make(chan loader.Event, 16)
+	cancel := l.Subscribe(func(e loader.Event) {
+		if e.Kind == loader.EventRowsLoaded {
+			loaded <- e
+		}
+	})
+	if _, ok := l.GetRow(150000); ok {
+		t.Fatal("row 150000 should not be cached yet")
+	}
+	select {
+	case e := <-loaded:
+		if !e.Contains(150000) {
+			t.Fatalf("loaded range [%d,%d) misses row 150000", e.From, e.To)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no EventRowsLoaded published")
+	}
+	cancel()
+	if r, ok := l.GetRow(150000); !ok || r[0] != "150000" {
+		t.Fatalf("row 150000 = %q", r)
+	}
+	var got []string
+	if err := l.ReadRows(6, 9, func(row int, rec []string) error {
+		got = append(got, rec[0]+":"+rec[2])
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, "|") != "6:note 6 padding padding padding padding|7:multi\nline|8:note 8 padding padding padding padding" {
+		t.Fatalf("ReadRows = %q", got)
+	}
+	if l.TryLockFunc(func() bool { return len(l.Cache) > 256 }) {
+		t.Fatal("cache grew beyond its capacity")
+	}
+}
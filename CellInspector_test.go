@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/devbiu/CsvView/loader"
+	"github.com/devbiu/CsvView/shower"
+)
+
+func TestInspectValue(t *testing.T) {
+	for _, tc := range []struct {
+		in        string
+		typ       string
+		chars     int
+		bytes     int
+		invisible int
+	}{
+		{"", "empty", 0, 0, 0},
+		{"42", "int", 2, 2, 0},
+		{"2024-05-01", "date", 10, 10, 0},
+		{"東京", "string", 2, 6, 0},
+		{"a\u200bb\ufeff", "string", 4, 8, 2},
+		{"x\x00y\xff", "string", 4, 4, 2},
+		{"line1\nline2\tend", "string", 15, 15, 0},
+		{`{"a":1,"b":[true]}`, "json", 18, 18, 0},
+		{"<a><b>1</b></a>", "xml", 15, 15, 0},
+		{"<not xml", "string", 8, 8, 0},
+	} {
+		got := shower.InspectValue(tc.in)
+		if got.Type != tc.typ || got.Chars != tc.chars || got.Bytes != tc.bytes || got.Invisible != tc.invisible {
+			t.Errorf("InspectValue(%q) = %+v", tc.in, got)
+		}
+	}
+	if got := shower.InspectValue(`{"a":1}`).Pretty; got != "{\n  \"a\": 1\n}" {
+		t.Errorf("pretty JSON = %q", got)
+	}
+	if got := shower.InspectValue("<a><b>1</b></a>").Pretty; got != "<a>\n  <b>1</b>\n</a>" {
+		t.Errorf("pretty XML = %q", got)
+	}
+}
+
+func TestCellInspector(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	long := strings.Repeat("word ", 1000)
+	vt := shower.NewVirtualTable(loader.NewMemSource([]string{"id", "note"}, [][]string{{"1", long}}))
+	defer vt.Close()
+	ci := shower.NewCellInspector(vt)
+	ci.Toggle()
+	if !ci.Visible() {
+		t.Fatal("inspector should be visible after Toggle")
+	}
+	vt.GoTo(0, 1)
+	ci.Toggle()
+	if ci.Visible() {
+		t.Fatal("inspector should be hidden after a second Toggle")
+	}
+}
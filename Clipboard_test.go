@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/devbiu/CsvView/export"
+	"github.com/devbiu/CsvView/loader"
+	"github.com/devbiu/CsvView/shower"
+)
+
+func TestCopyPasteRange(t *testing.T) {
+	test.NewTempApp(t)
+	l, err := loader.NewCsvLoaderV2(writeTestCsv(t, "clip.csv", 20), 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vt := shower.NewVirtualTable(l)
+	defer vt.Close()
+
+	vt.GoTo(2, 1)
+	if err := vt.Paste("x\t\"two\nlines\"\ny\tz\textra\n"); err != nil {
+		t.Fatal(err)
+	}
+	sel, ok := vt.Selection()
+	if want := (shower.Selection{Top: 2, Left: 1, Bottom: 3, Right: 2}); !ok || sel != want {
+		t.Fatalf("selection after paste = %+v, want %+v", sel, want)
+	}
+	if got, _ := l.GetRow(3); got[1] != "y" || got[2] != "z" {
+		t.Errorf("row 3 after paste = %q", got)
+	}
+
+	text, err := vt.SelectionText(export.FormatTSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "x\t\"two\nlines\"\ny\tz\n"; text != want {
+		t.Errorf("TSV = %q, want %q", text, want)
+	}
+	md, _ := vt.SelectionText(export.FormatMarkdown)
+	if want := "| name | note |\n| --- | --- |\n| x | two<br>lines |\n| y | z |\n"; md != want {
+		t.Errorf("Markdown = %q, want %q", md, want)
+	}
+
+	vt.SelectAll()
+	if sel, _ := vt.Selection(); sel.Bottom != 19 || sel.Right != 2 {
+		t.Errorf("select all = %+v", sel)
+	}
+	if err := shower.NewVirtualTable(loader.NewMemSource([]string{"a"}, [][]string{{"1"}})).Paste("x"); err != shower.ErrReadOnly {
+		t.Errorf("paste into read-only source: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/devbiu/CsvView/export"
+	"github.com/devbiu/CsvView/loader"
+	"github.com/devbiu/CsvView/shower"
+)
+
+func TestColumnLayout(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	newTable := func() *shower.VirtualTable {
+		view := loader.NewView(loader.NewMemSource([]string{"a", "b", "c", "a"}, [][]string{{"1", "2", "3", "4"}}))
+		vt := shower.NewVirtualTable(view)
+		vt.SetLayoutKey("/data/wide.csv")
+		return vt
+	}
+
+	vt := newTable()
+	defer vt.Close()
+	vt.HideColumns([]int{1})
+	vt.MoveColumn(2, 0)
+	vt.SetFrozen(1)
+	if got := vt.VisibleColumns(); !reflect.DeepEqual(got, []int{3, 0, 2}) {
+		t.Fatalf("visible = %v", got)
+	}
+	vt.GoTo(0, 0)
+	if text, err := vt.SelectionText(export.FormatCSV); err != nil || text != "4\n" {
+		t.Errorf("copy first displayed column = %q, %v", text, err)
+	}
+	want := shower.ColumnLayout{Order: []string{"a#2", "a", "b", "c"}, Hidden: []string{"b"}, Frozen: 1}
+	if got := vt.Layout(); !reflect.DeepEqual(got, want) {
+		t.Errorf("layout = %+v", got)
+	}
+
+	// 重新打开同一个文件时恢复布局
+	again := newTable()
+	defer again.Close()
+	if got := again.VisibleColumns(); !reflect.DeepEqual(got, []int{3, 0, 2}) || again.Frozen() != 1 {
+		t.Fatalf("restored visible = %v, frozen = %d", got, again.Frozen())
+	}
+
+	// 新增的计算列显示在最后
+	again.Source().(*loader.View).AddComputed(loader.Computed{Name: "sum", Eval: func([]string) string { return "" }})
+	if got := again.VisibleColumns(); !reflect.DeepEqual(got, []int{3, 0, 2, 4}) {
+		t.Errorf("visible after adding a column = %v", got)
+	}
+	again.HideColumns([]int{0, 1, 2, 3})
+	if got := again.VisibleColumns(); len(got) != 4 {
+		t.Errorf("hiding every column should be refused, visible = %v", got)
+	}
+}
+
+func TestColumnWidths(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	long := strings.Repeat("x", 5000)
+	newTable := func(rows [][]string) *shower.VirtualTable {
+		vt := shower.NewVirtualTable(loader.NewMemSource([]string{"id", "ascii", "cjk", "note"}, rows))
+		vt.SetLayoutKey("/data/names.csv")
+		return vt
+	}
+	vt := newTable([][]string{{"1", "abcdef", "東京都渋谷区", long}})
+	defer vt.Close()
+	if a, c := vt.ColumnWidth(1), vt.ColumnWidth(2); c <= a {
+		t.Errorf("CJK column width %v should exceed ASCII column width %v", c, a)
+	}
+	if w := vt.ColumnWidth(3); w != 400 {
+		t.Errorf("long column width = %v, want the 400 cap", w)
+	}
+
+	// 自动适配的宽度按列名保存，下次打开时恢复，即使内容不同
+	vt.AutoFitColumns(0)
+	fitted := vt.ColumnWidth(0)
+	if got := vt.Layout().Widths; len(got) != 1 || got["id"] != fitted {
+		t.Fatalf("saved widths = %v", got)
+	}
+	again := newTable([][]string{{"1000000000000", "", "", ""}})
+	defer again.Close()
+	if got := again.ColumnWidth(0); got != fitted {
+		t.Errorf("restored width = %v, want %v", got, fitted)
+	}
+}
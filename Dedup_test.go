@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/devbiu/CsvView/export"
+	"github.com/devbiu/CsvView/loader"
+	"github.com/devbiu/CsvView/ops"
+)
+
+func TestDedup(t *testing.T) {
+	src := loader.NewMemSource([]string{"id", "email", "note"}, [][]string{
+		{"1", "ann@example.com", "a"},
+		{"2", "bob@example.com", "b"},
+		{"3", " Ann@Example.com", "c"},
+		{"1", "ann@example.com", "a"},
+		{"4", "cy@example.com", "d"},
+		{"5", "bob@example.com", "e"},
+	})
+	cases := []struct {
+		opts ops.DedupOptions
+		want []int
+	}{
+		{ops.DedupOptions{}, []int{3}},
+		{ops.DedupOptions{Keys: []string{"email"}}, []int{3, 5}},
+		{ops.DedupOptions{Keys: []string{"email"}, Trim: true, FoldCase: true}, []int{2, 3, 5}},
+		{ops.DedupOptions{Keys: []string{"email"}, Trim: true, FoldCase: true, Keep: ops.KeepLast}, []int{0, 1, 2}},
+		{ops.DedupOptions{Keys: []string{"email"}, Keep: ops.KeepNone}, []int{0, 1, 3, 5}},
+	}
+	for _, c := range cases {
+		for _, memRows := range []int{0, 2} {
+			c.opts.MemRows = memRows
+			res, err := ops.Dedup(src, c.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.DuplicateRows(); !reflect.DeepEqual(got, c.want) || res.Duplicates != len(c.want) || res.Rows != 6 {
+				t.Errorf("%+v: duplicates = %v (%d of %d)", c.opts, got, res.Duplicates, res.Rows)
+			}
+		}
+	}
+
+	res, err := ops.Dedup(src, ops.DedupOptions{Keys: []string{"email"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	preview, err := res.Preview(src, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sourceRows(t, preview); !reflect.DeepEqual(got, []string{"4,1,ann@example.com,a"}) {
+		t.Errorf("preview = %v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := export.Export(&buf, src, export.Options{Format: export.FormatCSV, Skip: res.IsDuplicate}); err != nil {
+		t.Fatal(err)
+	}
+	want := "id,email,note\n1,ann@example.com,a\n2,bob@example.com,b\n3,\" Ann@Example.com\",c\n4,cy@example.com,d\n"
+	if buf.String() != want {
+		t.Errorf("export = %q", buf.String())
+	}
+}
+
+func TestMarkDeleted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dup.csv")
+	if err := os.WriteFile(path, []byte("id,name\n1,a\n2,b\n1,a\n3,c\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := loader.NewCsvLoaderV2(path, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view := loader.NewView(l)
+	defer view.Close()
+
+	res, err := ops.Dedup(view, ops.DedupOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed, ok := loader.AsEditor(view)
+	if !ok {
+		t.Fatal("csv view is not editable")
+	}
+	ed.SetDeleted(res.DuplicateRows(), true)
+	if !ed.Deleted(2) || ed.Deleted(0) {
+		t.Errorf("deleted marks = %v %v", ed.Deleted(0), ed.Deleted(2))
+	}
+	var buf bytes.Buffer
+	if err := export.Export(&buf, view, export.Options{Format: export.FormatCSV}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,name\n1,a\n2,b\n3,c\n"; buf.String() != want {
+		t.Errorf("export = %q", buf.String())
+	}
+
+	ed.SetDeleted([]int{2}, false)
+	buf.Reset()
+	if err := export.Export(&buf, view, export.Options{Format: export.FormatCSV}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,name\n1,a\n2,b\n1,a\n3,c\n"; buf.String() != want {
+		t.Errorf("export after restore = %q", buf.String())
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/devbiu/CsvView/loader"
+	"github.com/devbiu/CsvView/ops"
+)
+
+func TestDiff(t *testing.T) {
+	oldSrc := loader.NewMemSource([]string{"id", "region", "price", "note"}, [][]string{
+		{"1", "eu", "10", "a"},
+		{"2", "eu", "20", "b"},
+		{"2", "us", "21", "c"},
+		{"3", "eu", "30", "d"},
+	})
+	newSrc := loader.NewMemSource([]string{"region", "id", "price", "qty"}, [][]string{
+		{"us", "2", "25", ""},
+		{"eu", "1", "10", ""},
+		{"eu", "4", "40", "7"},
+		{"eu", "2", "20", "1"},
+	})
+	res, err := ops.Diff(oldSrc, newSrc, ops.DiffOptions{Keys: []string{"id", "region"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	wantHeader := []string{"status", "id", "region", "old.price", "new.price", "old.note", "new.note", "old.qty", "new.qty"}
+	if !reflect.DeepEqual(res.Header(), wantHeader) {
+		t.Fatalf("header = %v", res.Header())
+	}
+	want := []string{
+		"changed,2,us,21,25,c,,,",
+		"changed,1,eu,10,10,a,,,",
+		"added,4,eu,,40,,,,7",
+		"changed,2,eu,20,20,b,,,1",
+		"removed,3,eu,30,,d,,,",
+	}
+	if got := sourceRows(t, res); !reflect.DeepEqual(got, want) {
+		t.Fatalf("rows =\n%s", strings.Join(got, "\n"))
+	}
+	if res.Added != 1 || res.Removed != 1 || res.Changed != 3 {
+		t.Errorf("counts = %d %d %d", res.Added, res.Removed, res.Changed)
+	}
+	rec, _ := res.GetRow(0)
+	if c, isNew := res.Cell(4); c != 0 || !isNew || !res.CellChanged(rec, 0) || res.CellChanged(rec, 2) {
+		t.Errorf("cell info for %v", rec)
+	}
+
+	if _, err := ops.Diff(oldSrc, newSrc, ops.DiffOptions{Keys: []string{"note"}}); err == nil {
+		t.Error("expected error for key missing in new file")
+	}
+}
+
+// 分区落盘与内存索引得到相同的结果（顺序按分区不同）
+func TestDiffPartitioned(t *testing.T) {
+	var oldRows, newRows [][]string
+	for i := range 2000 {
+		id := strconv.Itoa(i)
+		if i%7 != 0 {
+			oldRows = append(oldRows, []string{id, "v" + id})
+		}
+		v := "v" + id
+		if i%5 == 0 {
+			v = "w" + id
+		}
+		if i%11 != 0 {
+			newRows = append(newRows, []string{id, v})
+		}
+	}
+	header := []string{"id", "value"}
+	mem, err := ops.Diff(loader.NewMemSource(header, oldRows), loader.NewMemSource(header, newRows), ops.DiffOptions{Keys: []string{"id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mem.Close()
+	part, err := ops.Diff(loader.NewMemSource(header, oldRows), loader.NewMemSource(header, newRows), ops.DiffOptions{Keys: []string{"id"}, MemRows: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer part.Close()
+
+	a, b := sourceRows(t, mem), sourceRows(t, part)
+	slices.Sort(a)
+	slices.Sort(b)
+	if !reflect.DeepEqual(a, b) || len(a) == 0 {
+		t.Fatalf("partitioned diff differs: %d vs %d rows", len(a), len(b))
+	}
+	if mem.Added != part.Added || mem.Removed != part.Removed || mem.Changed != part.Changed {
+		t.Errorf("counts differ: %+v vs %+v", []int{mem.Added, mem.Removed, mem.Changed}, []int{part.Added, part.Removed, part.Changed})
+	}
+}
+
+func TestDiffCommand(t *testing.T) {
+	dir := t.TempDir()
+	oldPath, newPath := filepath.Join(dir, "old.csv"), filepath.Join(dir, "new.csv")
+	os.WriteFile(oldPath, []byte("id,name\n1,a\n2,b\n"), 0o644)
+	os.WriteFile(newPath, []byte("id,name\n2,B\n3,c\n"), 0o644)
+	out := filepath.Join(dir, "diff.json")
+	var stderr strings.Builder
+	if err := runDiff([]string{"--key", "id", "--output", out, oldPath, newPath}, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if stderr.String() != "1 added, 1 removed, 1 changed\n" {
+		t.Errorf("summary = %q", stderr.String())
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"status":"changed","id":"2","old.name":"b","new.name":"B"`) {
+		t.Errorf("diff.json = %s", b)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/devbiu/CsvView/export"
+	"github.com/devbiu/CsvView/loader"
+	"github.com/parquet-go/parquet-go"
+)
+
+func exportString(t *testing.T, src loader.RowSource, opts export.Options) string {
+	t.Helper()
+	var sb strings.Builder
+	if err := export.Export(&sb, src, opts); err != nil {
+		t.Fatal(err)
+	}
+	return sb.String()
+}
+
+func TestExportFormats(t *testing.T) {
+	src := loader.NewMemSource([]string{"id", "name", "price"}, [][]string{
+		{"1", "a|b", "1.5"},
+		{"2", "O'Neil\n<x>", ""},
+	})
+
+	cases := []struct {
+		opts export.Options
+		want string
+	}{
+		{export.Options{Format: export.FormatNDJSON},
+			"{\"id\":\"1\",\"name\":\"a|b\",\"price\":\"1.5\"}\n{\"id\":\"2\",\"name\":\"O'Neil\\n\\u003cx\\u003e\",\"price\":\"\"}\n"},
+		{export.Options{Format: export.FormatJSON, Rows: &export.Range{From: 0, To: 1}, Cols: []int{1, 0}},
+			"[\n  {\"name\":\"a|b\",\"id\":\"1\"}\n]\n"},
+		{export.Options{Format: export.FormatMarkdown, Rows: &export.Range{From: 0, To: 1}},
+			"| id | name | price |\n| --- | --- | --- |\n| 1 | a\\|b | 1.5 |\n"},
+		{export.Options{Format: export.FormatHTML, Rows: &export.Range{From: 1, To: 2}, Cols: []int{1}},
+			"<table>\n  <thead>\n    <tr><th>name</th></tr>\n  </thead>\n  <tbody>\n    <tr><td>O&#39;Neil<br>&lt;x&gt;</td></tr>\n  </tbody>\n</table>\n"},
+		{export.Options{Format: export.FormatSQL, Dialect: export.DialectPostgres, Table: "items", BatchSize: 1},
+			"CREATE TABLE \"items\" (\n  \"id\" BIGINT,\n  \"name\" TEXT,\n  \"price\" DOUBLE PRECISION\n);\n\n" +
+				"INSERT INTO \"items\" (\"id\", \"name\", \"price\") VALUES\n  (1, 'a|b', 1.5);\n" +
+				"INSERT INTO \"items\" (\"id\", \"name\", \"price\") VALUES\n  (2, 'O''Neil\n<x>', NULL);\n"},
+	}
+	for _, c := range cases {
+		if got := exportString(t, src, c.opts); got != c.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", c.opts.Format, got, c.want)
+		}
+	}
+}
+
+// TestExportSQLTypeMismatch 列类型按全部导出行推断：采样之后才出现不符合类型的值时，整列按文本建表
+func TestExportSQLTypeMismatch(t *testing.T) {
+	rows := make([][]string, loader.DefaultInferSample+2)
+	for i := range rows {
+		rows[i] = []string{strconv.Itoa(i), "1.5", "true", strconv.Itoa(i), "0.5"}
+	}
+	n := len(rows)
+	rows[n-2] = []string{"1); DROP TABLE t; --", "NaN", "maybe", "7", "2"}
+	rows[n-1] = []string{"x", "0x1p3", "no", "8", "-1e3"}
+	src := loader.NewMemSource([]string{"id", "price", "ok", "qty", "rate"}, rows)
+	got := exportString(t, src, export.Options{Format: export.FormatSQL, Dialect: export.DialectPostgres, Table: "t", BatchSize: 1})
+	for _, want := range []string{
+		"CREATE TABLE \"t\" (\n  \"id\" TEXT,\n  \"price\" TEXT,\n  \"ok\" TEXT,\n  \"qty\" BIGINT,\n  \"rate\" DOUBLE PRECISION\n);",
+		"('0', '1.5', 'true', 0, 0.5);",
+		"('1); DROP TABLE t; --', 'NaN', 'maybe', 7, 2);",
+		"('x', '0x1p3', 'no', 8, -1e3);",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("SQL export is missing %q", want)
+		}
+	}
+}
+
+// TestExportSQLHeader 重复和空的列名在 CREATE TABLE 中改为不重复的名字
+func TestExportSQLHeader(t *testing.T) {
+	src := loader.NewMemSource([]string{"id", "id", "", "column_3"}, [][]string{{"1", "2", "a", "b"}})
+	got := exportString(t, src, export.Options{Format: export.FormatSQL, Table: "t"})
+	want := "CREATE TABLE \"t\" (\n  \"id\" BIGINT,\n  \"id_2\" BIGINT,\n  \"column_3\" TEXT,\n  \"column_3_2\" TEXT\n);"
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("SQL header:\n%s", got)
+	}
+}
+
+// TestExportUniqueNames 导出的列名两两不同
+func TestExportUniqueNames(t *testing.T) {
+	cases := []struct{ header, want []string }{
+		{[]string{"a", "b"}, []string{"a", "b"}},
+		{[]string{"a", "a", "a"}, []string{"a", "a_2", "a_3"}},
+		{[]string{"a_2", "a", "a"}, []string{"a_2", "a", "a_3"}},
+		{[]string{"a", "a", "a_2"}, []string{"a", "a_2", "a_2_2"}},
+		{[]string{"", "column_1", ""}, []string{"column_1", "column_1_2", "column_3"}},
+	}
+	for _, c := range cases {
+		rec := make([]string, len(c.header))
+		got := exportString(t, loader.NewMemSource(c.header, [][]string{rec}), export.Options{Format: export.FormatSQL, Table: "t"})
+		_, cols, _ := strings.Cut(got, "INSERT INTO \"t\" (")
+		cols, _, _ = strings.Cut(cols, ")")
+		want := "\"" + strings.Join(c.want, "\", \"") + "\""
+		if cols != want {
+			t.Errorf("%q: columns = %s, want %s", c.header, cols, want)
+		}
+	}
+}
+
+func TestExportParquet(t *testing.T) {
+	src := loader.NewMemSource([]string{"id", "price", "day", "name"}, [][]string{
+		{"1", "1.5", "2024-01-02", "a"},
+		{"2", "", "2024-01-03", ""},
+	})
+	var buf bytes.Buffer
+	if err := export.Export(&buf, src, export.Options{Format: export.FormatParquet, Compression: "zstd"}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.NumRows() != 2 {
+		t.Fatalf("NumRows = %d", f.NumRows())
+	}
+	// 列保持表头顺序
+	var fields []string
+	for _, field := range f.Schema().Fields() {
+		fields = append(fields, field.Name()+" "+field.Type().String())
+	}
+	want := []string{"id INT(64,true)", "price DOUBLE", "day DATE", "name STRING"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("schema fields = %q, want %q", fields, want)
+	}
+
+	rows := make([]parquet.Row, 2)
+	r := parquet.NewReader(bytes.NewReader(buf.Bytes()))
+	if n, _ := r.ReadRows(rows); n != 2 {
+		t.Fatalf("read %d rows", n)
+	}
+	if got := rows[1][1]; !got.IsNull() {
+		t.Errorf("empty price should be null, got %v", got)
+	}
+	if got := rows[0][0].Int64(); got != 1 {
+		t.Errorf("id = %d", got)
+	}
+	if got := rows[0][3].String(); got != "a" {
+		t.Errorf("name = %q", got)
+	}
+
+	// 采样之后才出现的非数字值不能丢，整列按字符串写出
+	many := make([][]string, loader.DefaultInferSample+1)
+	for i := range many {
+		many[i] = []string{strconv.Itoa(i)}
+	}
+	many[len(many)-1][0] = "n/a"
+	buf.Reset()
+	if err := export.Export(&buf, loader.NewMemSource([]string{"id"}, many), export.Options{Format: export.FormatParquet}); err != nil {
+		t.Fatal(err)
+	}
+	if f, err = parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Schema().Fields()[0].Type().String(); got != "STRING" {
+		t.Errorf("mixed column type = %s, want STRING", got)
+	}
+	rows = make([]parquet.Row, len(many))
+	r = parquet.NewReader(bytes.NewReader(buf.Bytes()))
+	if n, _ := r.ReadRows(rows); n != len(many) {
+		t.Fatalf("read %d rows", n)
+	}
+	if got := rows[len(many)-1][0].String(); got != "n/a" {
+		t.Errorf("last id = %q, want n/a", got)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/devbiu/CsvView/export"
+	"github.com/devbiu/CsvView/expr"
+	"github.com/devbiu/CsvView/loader"
+)
+
+func TestExpr(t *testing.T) {
+	header := []string{"name", "price", "qty", "start", "end", "order id"}
+	rec := []string{" ann ", "2.5", "4", "2024-01-30", "2024-03-01", "A-17"}
+	cases := map[string]string{
+		"price * qty":                           "10",
+		"upper(trim(name)) & '!'":               "ANN!",
+		"qty % 3 + -1":                          "0",
+		"price % 0.5":                           "0",
+		"qty % 1.5":                             "1",
+		"date_diff(end, start)":                 "31",
+		"date_add(start, 2)":                    "2024-02-01",
+		"month(end) == 3 and not (qty < 4)":     "true",
+		"if(price > 3, 'high', 'low')":          "low",
+		"regex_extract([order id], '\\d+')":     "17",
+		"round(price / 3, 2)":                   "0.83",
+		"len(name) >= 5 || contains(name, 'z')": "true",
+		"'it''s'":                               "it's",
+	}
+	for src, want := range cases {
+		e, err := expr.Compile(src, header)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		v, err := e.Eval(rec)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if v.String() != want {
+			t.Errorf("%s = %q, want %q", src, v.String(), want)
+		}
+	}
+
+	for _, src := range []string{"price *", "unknown + 1", "upper()", "(price"} {
+		if _, err := expr.Compile(src, header); err == nil {
+			t.Errorf("%s: expected compile error", src)
+		}
+	}
+	e, _ := expr.Compile("name / 2", header)
+	if _, err := e.Eval(rec); err == nil {
+		t.Error("expected error dividing text")
+	}
+	e, _ = expr.Compile("qty % 0", header)
+	if _, err := e.Eval(rec); err == nil {
+		t.Error("expected error for modulo by zero")
+	}
+}
+
+func TestView(t *testing.T) {
+	base := loader.NewMemSource([]string{"item", "price", "qty"}, [][]string{
+		{"pen", "1.5", "10"},
+		{"ink", "", "3"},
+		{"pad", "4", "2"},
+		{"cap", "12", "x"},
+	})
+	view := loader.NewView(base)
+	defer view.Close()
+
+	total, err := expr.Compile("price * qty", view.Header())
+	if err != nil {
+		t.Fatal(err)
+	}
+	view.AddComputed(total.Computed("total"))
+	if got := view.Header(); !reflect.DeepEqual(got, []string{"item", "price", "qty", "total"}) {
+		t.Errorf("header = %q", got)
+	}
+	want := []string{"pen,1.5,10,15", "ink,,3,", "pad,4,2,8", "cap,12,x,#ERR"}
+	if got := sourceRows(t, view); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %v", got)
+	}
+
+	// 计算列可以参与筛选和排序
+	filter, err := expr.Compile("total > 5", view.Header())
+	if err != nil {
+		t.Fatal(err)
+	}
+	view.SetFilter(filter.String(), filter.Predicate())
+	want = []string{"pen,1.5,10,15", "pad,4,2,8"}
+	if got := sourceRows(t, view); !reflect.DeepEqual(got, want) {
+		t.Errorf("filtered rows = %v", got)
+	}
+	if view.RowCount() != 2 {
+		t.Errorf("row count = %d", view.RowCount())
+	}
+
+	view.SetFilter("", nil)
+	view.SetSort([]loader.SortKey{{Col: 1, Desc: true}})
+	want = []string{"cap,12,x,#ERR", "pad,4,2,8", "pen,1.5,10,15", "ink,,3,"}
+	if got := sourceRows(t, view); !reflect.DeepEqual(got, want) {
+		t.Errorf("sorted rows = %v", got)
+	}
+	if rec, ok := view.GetRow(1); !ok || rec[0] != "pad" {
+		t.Errorf("row 1 = %q", rec)
+	}
+
+	// 导出通过视图读取，写出计算列的值
+	var buf bytes.Buffer
+	if err := export.Export(&buf, view, export.Options{Format: export.FormatCSV}); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "item,price,qty,total\ncap,12,x,#ERR\npad,4,2,8\npen,1.5,10,15\nink,,3,\n"
+	if buf.String() != wantCSV {
+		t.Errorf("export = %q", buf.String())
+	}
+
+	view.SetSort(nil)
+	if got := sourceRows(t, view); got[0] != "pen,1.5,10,15" {
+		t.Errorf("unsorted rows = %v", got)
+	}
+}
+
+// countingSource 统计底层被整体扫描的次数
+type countingSource struct {
+	*loader.MemSource
+	reads int
+}
+
+func (c *countingSource) ReadRows(from, to int, fn func(row int, rec []string) error) error {
+	c.reads++
+	return c.MemSource.ReadRows(from, to, fn)
+}
+
+// TestViewSortedRead 大的排序视图只扫描底层一遍
+func TestViewSortedRead(t *testing.T) {
+	const n = 150000
+	rows := make([][]string, n)
+	for i := range rows {
+		// 乘以与 n 互质的数打乱顺序
+		rows[i] = []string{strconv.Itoa(i * 7919 % n)}
+	}
+	base := &countingSource{MemSource: loader.NewMemSource([]string{"k"}, rows)}
+	view := loader.NewView(base)
+	defer view.Close()
+	view.SetSort([]loader.SortKey{{Col: 0}})
+	for !view.Indexed() {
+		time.Sleep(5 * time.Millisecond)
+	}
+	base.reads = 0
+	next := 0
+	if err := view.ReadRows(0, -1, func(row int, rec []string) error {
+		if row != next || rec[0] != strconv.Itoa(row) {
+			return fmt.Errorf("row %d = %q", row, rec)
+		}
+		next++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if next != n || base.reads != 1 {
+		t.Errorf("read %d rows with %d base scans", next, base.reads)
+	}
+}
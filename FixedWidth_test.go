@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devbiu/CsvView/export"
+	"github.com/devbiu/CsvView/loader"
+)
+
+func TestFixedWidthLoader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "extract.dat")
+	data := "ID   NAME      AMOUNT\n" +
+		"00001Alice     0012.50\r\n" +
+		"00002Bob, Jr.  0003.00\n" +
+		"00003\"Q\"\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, headerLine := range []bool{true, false} {
+		spec := loader.FixedWidthSpec{Boundaries: []int{15, 5, 5}, HeaderLine: headerLine, Trim: true}
+		l, err := loader.NewFixedWidthLoader(path, spec, 1024)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for !l.Indexed() && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		wantHeader := []string{"column_1", "column_2", "column_3"}
+		wantRows := 4
+		if headerLine {
+			wantHeader = []string{"ID", "NAME", "AMOUNT"}
+			wantRows = 3
+		}
+		if got := l.Header(); !reflect.DeepEqual(got, wantHeader) {
+			t.Errorf("header = %q, want %q", got, wantHeader)
+		}
+		if l.RowCount() != wantRows || l.ColCount() != 3 {
+			t.Fatalf("rows=%d cols=%d", l.RowCount(), l.ColCount())
+		}
+		if got := waitRow(t, l, wantRows); !reflect.DeepEqual(got, []string{"00003", `"Q"`, ""}) {
+			t.Errorf("last row = %q", got)
+		}
+
+		var sb strings.Builder
+		if err := export.Export(&sb, l, export.Options{Format: export.FormatCSV}); err != nil {
+			t.Fatal(err)
+		}
+		want := strings.Join(wantHeader, ",") + "\n"
+		if !headerLine {
+			want += "ID,NAME,AMOUNT\n"
+		}
+		want += "00001,Alice,0012.50\n00002,\"Bob, Jr.\",0003.00\n00003,\"\"\"Q\"\"\",\n"
+		if sb.String() != want {
+			t.Errorf("headerLine=%v csv:\ngot  %q\nwant %q", headerLine, sb.String(), want)
+		}
+		l.Close()
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/devbiu/CsvView/loader"
+)
+
+// appendFile 在文件末尾追加内容，模拟写日志的程序
+func appendFile(t *testing.T, path, s string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitRowCount 等待跟踪把行数扩展到 rows
+func waitRowCount(t *testing.T, l *loader.CSVLoader, rows int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for l.RowCount() != rows && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if got := l.RowCount(); got != rows {
+		t.Fatalf("RowCount = %d, want %d", got, rows)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		rows int
+	}{{"small", 10}, {"large", 200000}} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeTestCsv(t, tc.name+".csv", tc.rows)
+			l, err := loader.NewCsvLoaderV2(path, 256)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+			deadline := time.Now().Add(10 * time.Second)
+			for !l.Indexed() && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			replaced := make(chan struct{}, 1)
+			l.Subscribe(func(e loader.Event) {
+				if e.Kind == loader.EventFileReplaced {
+					replaced <- struct{}{}
+				}
+			})
+			if err := l.Follow(true); err != nil {
+				t.Fatal(err)
+			}
+			if !l.Following() {
+				t.Fatal("Following = false")
+			}
+
+			// 最后一条记录还没写完时先按已有内容显示，写完后重新读取
+			appendFile(t, path, "new1,a,b\nnew2,pa")
+			waitRowCount(t, l, tc.rows+2)
+			appendFile(t, path, "rtial,done\n")
+			deadline = time.Now().Add(5 * time.Second)
+			var r []string
+			for time.Now().Before(deadline) {
+				if r = waitRow(t, l, tc.rows+2); r[1] == "partial" {
+					break
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			if r[0] != "new2" || r[1] != "partial" || r[2] != "done" {
+				t.Fatalf("completed row = %q", r)
+			}
+			if got := l.RowCount(); got != tc.rows+2 {
+				t.Fatalf("RowCount = %d after completing last row", got)
+			}
+			if r := waitRow(t, l, tc.rows+1); r[0] != "new1" {
+				t.Fatalf("appended row = %q", r)
+			}
+
+			// 截断视为替换
+			if err := os.WriteFile(path, []byte("id,name,note\n1,x,y\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case <-replaced:
+			case <-time.After(5 * time.Second):
+				t.Fatal("no EventFileReplaced after truncation")
+			}
+			if l.Following() {
+				t.Fatal("still following after replacement")
+			}
+			src, err := l.Reopen()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer src.Close()
+			if src.RowCount() != 1 {
+				t.Fatalf("reopened RowCount = %d", src.RowCount())
+			}
+		})
+	}
+}
+
+// TestFollowReadRows 跟踪文件时顺序读取和追加并发进行
+func TestFollowReadRows(t *testing.T) {
+	const rows, appended = 200000, 50
+	path := writeTestCsv(t, "large.csv", rows)
+	l, err := loader.NewCsvLoaderV2(path, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	deadline := time.Now().Add(10 * time.Second)
+	for !l.Indexed() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := l.Follow(true); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer f.Close()
+		for i := range appended {
+			if _, err := fmt.Fprintf(f, "new%d,a,b\n", i); err != nil {
+				t.Error(err)
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	read := func() int {
+		n := 0
+		if err := l.ReadRows(rows-5, -1, func(row int, rec []string) error {
+			if row != rows-5+n {
+				return fmt.Errorf("row %d out of order", row)
+			}
+			n++
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	for reading := true; reading; {
+		select {
+		case <-done:
+			reading = false
+		default:
+			read()
+		}
+	}
+	waitRowCount(t, l, rows+appended)
+	if n := read(); n != 5+appended {
+		t.Errorf("read %d rows after appending, want %d", n, 5+appended)
+	}
+}
+
+func TestViewReplace(t *testing.T) {
+	view := loader.NewView(loader.NewMemSource([]string{"a"}, [][]string{{"1"}}))
+	defer view.Close()
+	view.AddComputed(loader.Computed{Name: "b", Eval: func(r []string) string { return r[0] + "!" }})
+	view.Replace(loader.NewMemSource([]string{"a"}, [][]string{{"2"}, {"3"}}))
+	if got := sourceRows(t, view); len(got) != 2 || got[1] != "3,3!" {
+		t.Fatalf("rows = %v", got)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/devbiu/CsvView/ipc"
+)
+
+func TestInstanceForward(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	if err := ipc.Forward([]ipc.Request{{Path: "/tmp/a.csv"}}); err == nil {
+		t.Fatal("forward should fail without a running instance")
+	}
+
+	got := make(chan ipc.Request, 4)
+	srv, err := ipc.Listen(func(r ipc.Request) { got <- r })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	if _, err := ipc.Listen(func(ipc.Request) {}); err == nil {
+		t.Fatal("second listener should be rejected")
+	}
+
+	want := []ipc.Request{{Path: "/tmp/a.csv"}, {Path: "/tmp/b.tsv", Row: 10, Col: 2}}
+	if err := ipc.Forward(want); err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range want {
+		select {
+		case r := <-got:
+			if r != w {
+				t.Fatalf("got %+v, want %+v", r, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("request not delivered")
+		}
+	}
+}
+
+// TestParseArgs 文件管理器传入的 file:// URI 和普通路径一样打开
+func TestParseArgs(t *testing.T) {
+	opts, err := parseArgs([]string{"a.csv", "--row", "3", "file:///tmp/my%20data.tsv", "--col=2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a.csv", filepath.FromSlash("/tmp/my data.tsv")}
+	if !reflect.DeepEqual(opts.files, want) || opts.row != 3 || opts.col != 2 {
+		t.Errorf("opts = %+v", opts)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/devbiu/CsvView/loader"
+)
+
+func TestJSONLinesLoader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	data := `{"ts":"2024-01-02T03:04:05Z","level":"info","req":{"id":7,"path":"/a"}}
+{"ts":"2024-01-02T03:04:06Z","level":"error","err":null,"tags":["x", "y"]}
+not json
+{"level":"debug","req":{"id":8}}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	src, err := loader.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	l := src.(*loader.JSONLinesLoader)
+	deadline := time.Now().Add(5 * time.Second)
+	for !l.Indexed() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	wantHeader := []string{"ts", "level", "req.id", "req.path", "err", "tags"}
+	if got := l.Header(); !reflect.DeepEqual(got, wantHeader) {
+		t.Fatalf("header = %q", got)
+	}
+	if l.RowCount() != 4 {
+		t.Fatalf("RowCount = %d", l.RowCount())
+	}
+	want := [][]string{
+		{"2024-01-02T03:04:05Z", "info", "7", "/a", "", ""},
+		{"2024-01-02T03:04:06Z", "error", "", "", "", `["x","y"]`},
+		{"not json", "", "", "", "", ""},
+		{"", "debug", "8", "", "", ""},
+	}
+	var got [][]string
+	if err := l.ReadRows(0, -1, func(_ int, rec []string) error {
+		got = append(got, rec)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %q", got)
+	}
+	if r := waitRow(t, l.CSVLoader, 4); !reflect.DeepEqual(r, want[3]) {
+		t.Errorf("GetRow(3) = %q", r)
+	}
+
+	raw, err := l.RawRecord(3)
+	if err != nil || string(raw) != `{"level":"debug","req":{"id":8}}` {
+		t.Errorf("RawRecord(3) = %q, %v", raw, err)
+	}
+}
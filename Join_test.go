@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/devbiu/CsvView/loader"
+	"github.com/devbiu/CsvView/ops"
+)
+
+func sourceRows(t *testing.T, src loader.RowSource) []string {
+	t.Helper()
+	var rows []string
+	if err := src.ReadRows(0, -1, func(_ int, rec []string) error {
+		rows = append(rows, strings.Join(rec, ","))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestJoin(t *testing.T) {
+	orders := loader.NewMemSource([]string{"order", "customer", "name"}, [][]string{
+		{"o1", "c1", "first"},
+		{"o2", "c2", "second"},
+		{"o3", "c9", "third"},
+	})
+	customers := loader.NewMemSource([]string{"id", "name", "city"}, [][]string{
+		{"c2", "Bob", "Oslo"},
+		{"c1", "Ann", "Rome"},
+		{"c2", "Bob", "Bergen"},
+	})
+	cases := []struct {
+		typ    ops.JoinType
+		header []string
+		rows   []string
+	}{
+		{ops.JoinLeft, []string{"order", "customer", "name", "name_right", "city"},
+			[]string{"o1,c1,first,Ann,Rome", "o2,c2,second,Bob,Oslo", "o2,c2,second,Bob,Bergen", "o3,c9,third,,"}},
+		{ops.JoinInner, []string{"order", "customer", "name", "name_right", "city"},
+			[]string{"o1,c1,first,Ann,Rome", "o2,c2,second,Bob,Oslo", "o2,c2,second,Bob,Bergen"}},
+		{ops.JoinAnti, []string{"order", "customer", "name"},
+			[]string{"o3,c9,third"}},
+	}
+	for _, c := range cases {
+		res, err := ops.Join(orders, customers, ops.JoinOptions{Type: c.typ, LeftKeys: []string{"customer"}, RightKeys: []string{"id"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(res.Header(), c.header) {
+			t.Errorf("%s header = %v", c.typ, res.Header())
+		}
+		if got := sourceRows(t, res); !reflect.DeepEqual(got, c.rows) {
+			t.Errorf("%s rows = %v", c.typ, got)
+		}
+		res.Close()
+	}
+
+	res, err := ops.Join(orders, customers, ops.JoinOptions{Type: ops.JoinInner, LeftKeys: []string{"customer"}, RightKeys: []string{"id"}, Columns: []string{"city"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if got := sourceRows(t, res); got[0] != "o1,c1,first,Rome" {
+		t.Errorf("selected columns: %v", got)
+	}
+}
+
+func TestJoinPartitioned(t *testing.T) {
+	var left, right [][]string
+	for i := range 3000 {
+		left = append(left, []string{strconv.Itoa(i % 1500), "l" + strconv.Itoa(i)})
+		if i%3 != 0 {
+			right = append(right, []string{strconv.Itoa(i), "r" + strconv.Itoa(i)})
+		}
+	}
+	join := func(memRows int) []string {
+		res, err := ops.Join(loader.NewMemSource([]string{"k", "a"}, left), loader.NewMemSource([]string{"k", "b"}, right),
+			ops.JoinOptions{Type: ops.JoinLeft, LeftKeys: []string{"k"}, RightKeys: []string{"k"}, MemRows: memRows})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Close()
+		rows := sourceRows(t, res)
+		slices.Sort(rows)
+		return rows
+	}
+	mem, part := join(0), join(100)
+	if len(mem) != 3000 || !reflect.DeepEqual(mem, part) {
+		t.Fatalf("partitioned join differs: %d vs %d rows", len(mem), len(part))
+	}
+}
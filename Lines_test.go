@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devbiu/CsvView/export"
+	"github.com/devbiu/CsvView/loader"
+	"github.com/devbiu/CsvView/shower"
+)
+
+func TestLineNumbers(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		rows int
+	}{{"small", 100}, {"large", 200000}} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeTestCsv(t, tc.name+".csv", tc.rows)
+			l, err := loader.NewCsvLoaderV2(path, 256)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+			deadline := time.Now().Add(10 * time.Second)
+			for !l.Indexed() && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			// 第 7 行的引号内有换行，占两行物理行，之后的行号都多一
+			for row, want := range map[int]int{0: 2, 7: 9, 8: 11, tc.rows - 1: tc.rows + 2} {
+				if got := l.LineOf(row); got != want {
+					t.Errorf("LineOf(%d) = %d, want %d", row, got, want)
+				}
+			}
+			for line, want := range map[int]int{9: 7, 10: 7, 11: 8, tc.rows + 2: tc.rows - 1} {
+				if got, ok := l.RowAtLine(line); !ok || got != want {
+					t.Errorf("RowAtLine(%d) = %d, %v, want %d", line, got, ok, want)
+				}
+			}
+			if _, ok := l.RowAtLine(1); ok {
+				t.Error("the header line should not map to a row")
+			}
+
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			off := int64(strings.Index(string(b), "\n8,name_8") + 1)
+			for o, want := range map[int64]int{off: 8, off + 3: 8, off - 1: 7} {
+				if got, ok := l.RowAtOffset(o); !ok || got != want {
+					t.Errorf("RowAtOffset(%d) = %d, %v, want %d", o, got, ok, want)
+				}
+			}
+			if _, ok := l.RowAtOffset(int64(len(b))); ok {
+				t.Error("offset past the end should not map to a row")
+			}
+
+			// 跟踪追加时接着编号，没写完的记录写完后重新计算
+			if err := l.Follow(true); err != nil {
+				t.Fatal(err)
+			}
+			appendFile(t, path, "x,y,\"a\nb")
+			waitRowCount(t, l, tc.rows+1)
+			appendFile(t, path, "\"\nz,w,v\n")
+			waitRowCount(t, l, tc.rows+2)
+			if got := l.LineOf(tc.rows); got != tc.rows+3 {
+				t.Errorf("appended LineOf(%d) = %d, want %d", tc.rows, got, tc.rows+3)
+			}
+			if got := l.LineOf(tc.rows + 1); got != tc.rows+5 {
+				t.Errorf("appended LineOf(%d) = %d, want %d", tc.rows+1, got, tc.rows+5)
+			}
+			l.Follow(false)
+
+			// 筛选后换算成视图行，被筛选掉的行找不到
+			view := loader.NewView(l)
+			view.SetFilter("id >= 8", func(rec []string) bool { return len(rec[0]) > 1 || rec[0] >= "8" })
+			deadline = time.Now().Add(10 * time.Second)
+			for !view.Indexed() && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if got := view.LineOf(0); got != 11 {
+				t.Errorf("view LineOf(0) = %d, want 11", got)
+			}
+			if got, ok := view.RowAtLine(12); !ok || got != 1 {
+				t.Errorf("view RowAtLine(12) = %d, %v, want 1", got, ok)
+			}
+			if _, ok := view.RowAtLine(9); ok {
+				t.Error("a filtered out line should not map to a view row")
+			}
+			vt := shower.NewVirtualTable(view)
+			defer vt.Close()
+			if err := vt.GoToLine(12); err != nil {
+				t.Fatal(err)
+			}
+			if text, err := vt.SelectionText(export.FormatCSV); err != nil || text != "9\n" {
+				t.Errorf("selection after going to line 12 = %q, %v", text, err)
+			}
+			if err := vt.GoToLine(9); err == nil {
+				t.Error("going to a filtered out line should fail")
+			}
+		})
+	}
+}
+
+func TestParseGoToTarget(t *testing.T) {
+	cases := []struct {
+		text   string
+		offset bool
+		want   int64
+	}{
+		{"010", false, 10},
+		{"0100", true, 100},
+		{"1,000,000", false, 1000000},
+		{"0x1F00", true, 0x1F00},
+		{"0X1f", true, 0x1F},
+	}
+	for _, c := range cases {
+		if got, err := shower.ParseGoToTarget(c.text, c.offset); err != nil || got != c.want {
+			t.Errorf("ParseGoToTarget(%q, %v) = %d, %v, want %d", c.text, c.offset, got, err, c.want)
+		}
+	}
+	for _, text := range []string{"0x10", "0b101", "abc", ""} {
+		if _, err := shower.ParseGoToTarget(text, false); err == nil {
+			t.Errorf("ParseGoToTarget(%q) should fail for rows", text)
+		}
+	}
+}
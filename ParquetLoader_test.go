@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devbiu/CsvView/loader"
+	"github.com/parquet-go/parquet-go"
+)
+
+type parquetAddress struct {
+	City string `parquet:"city"`
+	Zip  string `parquet:"zip,optional"`
+}
+
+type parquetPerson struct {
+	ID      int64          `parquet:"id"`
+	Name    string         `parquet:"name"`
+	Address parquetAddress `parquet:"address"`
+	Tags    []string       `parquet:"tags,list"`
+}
+
+func writeTestParquet(t *testing.T, rows int, opts ...parquet.WriterOption) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "people.parquet")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := parquet.NewGenericWriter[parquetPerson](f, append([]parquet.WriterOption{parquet.MaxRowsPerRowGroup(1000)}, opts...)...)
+	for i := range rows {
+		p := parquetPerson{ID: int64(i), Name: fmt.Sprintf("n%d", i), Address: parquetAddress{City: "c" + fmt.Sprint(i%3)}}
+		if i%2 == 0 {
+			p.Tags = []string{"x", fmt.Sprint(i)}
+		}
+		if _, err := w.Write([]parquetPerson{p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParquetLoader(t *testing.T) {
+	src, err := loader.Open(writeTestParquet(t, 2500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	if got := strings.Join(src.Header(), ","); got != "id,name,address.city,address.zip,tags" {
+		t.Fatalf("Header = %s", got)
+	}
+	if src.RowCount() != 2500 {
+		t.Fatalf("RowCount = %d", src.RowCount())
+	}
+
+	// 只投影 name 列：其他列不解码
+	src.(loader.ColumnProjector).SetProjection([]int{1})
+	var row []string
+	deadline := time.Now().Add(5 * time.Second)
+	for ok := false; !ok && time.Now().Before(deadline); {
+		row, ok = src.GetRow(1502)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if row == nil || row[1] != "n1502" || row[0] != "" {
+		t.Fatalf("projected row = %q", row)
+	}
+
+	// 投影加入 id 列后，缓存的旧行不能当作已加载返回，否则新列一直显示为空
+	src.(loader.ColumnProjector).SetProjection([]int{0, 1})
+	row = nil
+	deadline = time.Now().Add(5 * time.Second)
+	for ok := false; !ok && time.Now().Before(deadline); {
+		row, ok = src.GetRow(1502)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if row == nil || row[0] != "1502" || row[1] != "n1502" {
+		t.Fatalf("row after projection change = %q", row)
+	}
+
+	var got []string
+	if err := src.ReadRows(998, 1002, func(_ int, rec []string) error {
+		got = append(got, strings.Join(rec, "|"))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"998|n998|c2||[x, 998]", "999|n999|c0||", "1000|n1000|c1||[x, 1000]", "1001|n1001|c2||"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("ReadRows across row groups:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+// TestParquetLoaderPages 小页面的文件：按行定位跨页，整表读取顺序解码每个行组
+func TestParquetLoaderPages(t *testing.T) {
+	const rows = 2500
+	src, err := loader.Open(writeTestParquet(t, rows, parquet.PageBufferSize(256)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	want := func(i int) string {
+		tags := ""
+		if i%2 == 0 {
+			tags = fmt.Sprintf("[x, %d]", i)
+		}
+		return fmt.Sprintf("%d|n%d|c%d||%s", i, i, i%3, tags)
+	}
+
+	var row []string
+	deadline := time.Now().Add(5 * time.Second)
+	for ok := false; !ok && time.Now().Before(deadline); {
+		row, ok = src.GetRow(1777)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := strings.Join(row, "|"); got != want(1777) {
+		t.Fatalf("GetRow(1777) = %s", got)
+	}
+
+	n := 0
+	if err := src.ReadRows(5, -1, func(i int, rec []string) error {
+		if got := strings.Join(rec, "|"); i != n+5 || got != want(i) {
+			return fmt.Errorf("row %d = %s", i, got)
+		}
+		n++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n != rows-5 {
+		t.Fatalf("ReadRows returned %d rows", n)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/devbiu/CsvView/loader"
+	"github.com/devbiu/CsvView/ops"
+)
+
+func TestPivot(t *testing.T) {
+	src := loader.NewMemSource([]string{"region", "year", "amount", "day"}, [][]string{
+		{"north", "2024", "10", "2024-03-01"},
+		{"south", "2024", "5", "2024-01-09"},
+		{"north", "2025", "2.5", "2025-11-30"},
+		{"north", "2024", "20", "2024-12-01"},
+		{"south", "2025", "", "2025-02-02"},
+		{"north", "2024", "9", "2024-10-01"},
+	})
+	res, err := ops.Pivot(src, ops.PivotOptions{
+		Rows:    []string{"region"},
+		Columns: []string{"year"},
+		Values:  []ops.PivotValue{{Column: "amount", Agg: ops.AggSum}, {Column: "amount", Agg: ops.AggMedian}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantHeader := []string{"region", "2024 | sum(amount)", "2024 | median(amount)", "2025 | sum(amount)", "2025 | median(amount)"}
+	if !reflect.DeepEqual(res.Header(), wantHeader) {
+		t.Errorf("header = %q", res.Header())
+	}
+	want := []string{"north,39,10,2.5,2.5", "south,5,5,,"}
+	if got := sourceRows(t, res); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %v", got)
+	}
+
+	res, err = ops.Pivot(src, ops.PivotOptions{
+		Rows: []string{"region"},
+		Values: []ops.PivotValue{{Agg: ops.AggCount}, {Column: "amount", Agg: ops.AggAvg}, {Column: "amount", Agg: ops.AggMax},
+			{Column: "day", Agg: ops.AggMin}, {Column: "year", Agg: ops.AggDistinct}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"north,4,10.375,20,2024-03-01,2", "south,2,5,5,2024-01-09,2"}
+	if got := sourceRows(t, res); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %v", got)
+	}
+
+	if _, err := ops.Pivot(src, ops.PivotOptions{Values: []ops.PivotValue{{Column: "region", Agg: ops.AggSum}}}); err == nil {
+		t.Error("expected error summing a text column")
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/devbiu/CsvView/loader"
+)
+
+// waitChanged 等待加载器发现文件被修改
+func waitChanged(t *testing.T, l *loader.CSVLoader) {
+	t.Helper()
+	deadline := time.Now().Add(6 * time.Second)
+	for !l.Changed() && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !l.Changed() {
+		t.Fatal("change was not detected")
+	}
+}
+
+func TestReloadMergesEdits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.csv")
+	if err := os.WriteFile(path, []byte("id,name\n1,ann\n2,bob\n3,cy\n4,dan\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := loader.NewCsvLoaderV2(path, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	changed := make(chan struct{}, 1)
+	l.Subscribe(func(e loader.Event) {
+		if e.Kind == loader.EventFileChanged {
+			changed <- struct{}{}
+		}
+	})
+	l.SetEdit(0, 1, "Ann")
+	l.SetEdit(1, 1, "Bob")
+	l.SetEdit(3, 1, "Dan")
+	l.SetDeleted([]int{2, 3}, true)
+	want := []loader.CellEdit{{Row: 0, Col: 1, Old: "ann", New: "Ann"}, {Row: 1, Col: 1, Old: "bob", New: "Bob"}, {Row: 3, Col: 1, Old: "dan", New: "Dan"}}
+	if got := l.PendingEdits(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("PendingEdits = %+v", got)
+	}
+
+	// 另一个程序改了 bob 并删掉了最后一行
+	if err := os.WriteFile(path, []byte("id,name\n1,ann\n2,robert\n3,cy\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitChanged(t, l)
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("no EventFileChanged")
+	}
+	// 小文件已经整个读入内存，仍然显示打开时的内容
+	if r, ok := l.GetRow(1); !ok || r[1] != "Bob" {
+		t.Fatalf("row 1 = %q", r)
+	}
+
+	src, err := l.Reopen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	conflicts, err := loader.ReapplyEdits(src, l.PendingEdits(), l.DeletedRows())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantConflicts := []loader.EditConflict{
+		{CellEdit: want[1], Current: "robert"},
+		{CellEdit: want[2], Missing: true},
+	}
+	if !reflect.DeepEqual(conflicts, wantConflicts) {
+		t.Fatalf("conflicts = %+v", conflicts)
+	}
+	if got := sourceRows(t, src); !reflect.DeepEqual(got, []string{"1,Ann", "2,robert", "3,cy"}) {
+		t.Errorf("rows = %v", got)
+	}
+	ed, _ := loader.AsEditor(src)
+	if !ed.Deleted(2) {
+		t.Error("delete mark on row 2 was not kept")
+	}
+}
+
+func TestChangedInPlace(t *testing.T) {
+	const rows = 200000
+	path := writeTestCsv(t, "large.csv", rows)
+	l, err := loader.NewCsvLoaderV2(path, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	waitRow(t, l, 1)
+	if err := os.WriteFile(path, []byte("id,name,note\n1,x,y\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitChanged(t, l)
+	if _, ok := l.GetRow(0); ok {
+		t.Error("stale row still served after the file was rewritten")
+	}
+	err = l.ReadRows(0, 10, func(int, []string) error { return nil })
+	if !errors.Is(err, loader.ErrFileChanged) {
+		t.Errorf("ReadRows error = %v", err)
+	}
+}
+
+// TestEditOriginalValue 缓存外的行同步读取原值；读不到原值的修改重新打开时不和空值比较
+func TestEditOriginalValue(t *testing.T) {
+	const rows = 200000
+	path := writeTestCsv(t, "large.csv", rows)
+	l, err := loader.NewCsvLoaderV2(path, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	deadline := time.Now().Add(10 * time.Second)
+	for !l.Indexed() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !l.Indexed() {
+		t.Fatal("index was not built")
+	}
+	l.SetEdit(150000, 1, "x")
+	want := []loader.CellEdit{{Row: 150000, Col: 1, Old: "name_150000", New: "x"}}
+	if got := l.PendingEdits(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("PendingEdits = %+v", got)
+	}
+
+	// 原地改写后偏移表失效，此时的编辑读不到原值
+	if err := os.WriteFile(path, []byte("id,name,note\n1,,y\n2,b,z\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitChanged(t, l)
+	l.SetEdit(0, 1, "a")
+	l.SetEdit(1, 1, "b")
+	got := l.PendingEdits()
+	if len(got) != 3 || !got[0].OldUnknown || !got[1].OldUnknown || got[2].OldUnknown {
+		t.Fatalf("PendingEdits = %+v", got)
+	}
+
+	src, err := l.Reopen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	conflicts, err := loader.ReapplyEdits(src, got, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第 0 行文件中为空，不能当作原值自动覆盖；第 1 行已经是新值
+	wantConflicts := []loader.EditConflict{
+		{CellEdit: got[0], Current: ""},
+		{CellEdit: got[2], Missing: true},
+	}
+	if !reflect.DeepEqual(conflicts, wantConflicts) {
+		t.Fatalf("conflicts = %+v", conflicts)
+	}
+}
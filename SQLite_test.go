@@ -0,0 +1,110 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/devbiu/CsvView/export"
+	"github.com/devbiu/CsvView/loader"
+)
+
+func waitSource(t *testing.T, src loader.RowSource, row int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if r, ok := src.GetRow(row); ok {
+			return r
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("row %d never loaded", row)
+	return nil
+}
+
+func TestSQLiteRoundTrip(t *testing.T) {
+	var rows [][]string
+	for i := range 3000 {
+		rows = append(rows, []string{strconv.Itoa(i), strconv.FormatFloat(float64(i)/4, 'g', -1, 64), "name " + strconv.Itoa(i), ""})
+	}
+	rows[5][3] = "yes"
+	src := loader.NewMemSource([]string{"id", "score", "name", "flag"}, rows)
+	path := filepath.Join(t.TempDir(), "fixtures.db")
+	if err := export.ExportFile(path, src, export.Options{Table: "people"}); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idType, scoreType string
+	if err := db.QueryRow(`SELECT typeof(id), typeof(score) FROM people WHERE id = 1`).Scan(&idType, &scoreType); err != nil {
+		t.Fatal(err)
+	}
+	if idType != "integer" || scoreType != "real" {
+		t.Errorf("typeof = %s, %s", idType, scoreType)
+	}
+	if _, err := db.Exec(`CREATE VIEW odd AS SELECT name FROM people WHERE id % 2 = 1`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	objs, err := loader.SQLiteTables(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []loader.SQLiteObject{{Name: "people", Type: "table"}, {Name: "odd", Type: "view"}}
+	if !reflect.DeepEqual(objs, want) {
+		t.Fatalf("tables = %+v", objs)
+	}
+
+	l, err := loader.NewSQLiteLoader(path, "people", 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if got := l.Header(); !reflect.DeepEqual(got, []string{"id", "score", "name", "flag"}) {
+		t.Fatalf("header = %q", got)
+	}
+	for _, row := range []int{0, 2500, 127, 128, 2999} {
+		if got := waitSource(t, l, row); got[2] != rows[row][2] {
+			t.Errorf("row %d = %q", row, got)
+		}
+	}
+	if got := waitSource(t, l, 5); got[3] != "1" {
+		t.Errorf("bool flag = %q", got[3])
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !l.Indexed() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if l.RowCount() != 3000 {
+		t.Fatalf("RowCount = %d", l.RowCount())
+	}
+	n := 0
+	if err := l.ReadRows(10, -1, func(row int, rec []string) error {
+		if rec[0] != strconv.Itoa(row) {
+			t.Fatalf("ReadRows row %d = %q", row, rec)
+		}
+		n++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2990 {
+		t.Errorf("ReadRows read %d rows", n)
+	}
+
+	v, err := loader.NewSQLiteLoader(path, "odd", 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+	if got := waitSource(t, v, 700); got[0] != "name 1401" {
+		t.Errorf("view row 700 = %q", got)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/devbiu/CsvView/loader"
+	"github.com/devbiu/CsvView/ops"
+)
+
+// readFiles 读出文件内容，用于比较拆分结果
+func readFiles(t *testing.T, files []string) []string {
+	t.Helper()
+	var out []string
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, string(b))
+	}
+	return out
+}
+
+func TestSplitFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.csv")
+	data := "id,city,note\n1,Oslo,a\n2,Rome,\"two\nlines\"\n3,Oslo,c\n4,Paris/Nord,d\n5,,e"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "rows")
+	os.Mkdir(out, 0o755)
+	files, err := ops.SplitFile(path, ops.SplitOptions{By: ops.SplitRows, Rows: 2, Dir: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"id,city,note\n1,Oslo,a\n2,Rome,\"two\nlines\"\n",
+		"id,city,note\n3,Oslo,c\n4,Paris/Nord,d\n",
+		"id,city,note\n5,,e",
+	}
+	if got := readFiles(t, files); !reflect.DeepEqual(got, want) {
+		t.Errorf("split by rows = %q", got)
+	}
+	if filepath.Base(files[0]) != "data-0001.csv" {
+		t.Errorf("file name = %s", files[0])
+	}
+
+	out = filepath.Join(dir, "bytes")
+	os.Mkdir(out, 0o755)
+	files, err = ops.SplitFile(path, ops.SplitOptions{By: ops.SplitBytes, Bytes: 20, Dir: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		"id,city,note\n1,Oslo,a\n2,Rome,\"two\nlines\"\n",
+		"id,city,note\n3,Oslo,c\n4,Paris/Nord,d\n",
+		"id,city,note\n5,,e",
+	}
+	if got := readFiles(t, files); !reflect.DeepEqual(got, want) {
+		t.Errorf("split by bytes = %q", got)
+	}
+
+	out = filepath.Join(dir, "column")
+	os.Mkdir(out, 0o755)
+	files, err = ops.SplitFile(path, ops.SplitOptions{By: ops.SplitColumn, Column: "city", Dir: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, filepath.Base(f))
+	}
+	if want := []string{"data-Oslo.csv", "data-Rome.csv", "data-Paris_Nord.csv", "data-(empty).csv"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("files = %v", names)
+	}
+	if got := readFiles(t, files[:1]); got[0] != "id,city,note\n1,Oslo,a\n3,Oslo,c\n" {
+		t.Errorf("Oslo = %q", got[0])
+	}
+
+	if _, err := ops.SplitFile(path, ops.SplitOptions{By: ops.SplitColumn, Column: "missing", Dir: out}); err == nil {
+		t.Error("expected error for unknown column")
+	}
+
+	// 已经存在的文件不覆盖：报错并只删除本次写出的文件
+	out = filepath.Join(dir, "existing")
+	os.Mkdir(out, 0o755)
+	if err := os.WriteFile(filepath.Join(out, "data-Rome.csv"), []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ops.SplitFile(path, ops.SplitOptions{By: ops.SplitColumn, Column: "city", Dir: out}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("split over an existing file: err = %v", err)
+	}
+	entries, _ := os.ReadDir(out)
+	if b, _ := os.ReadFile(filepath.Join(out, "data-Rome.csv")); string(b) != "keep" || len(entries) != 1 {
+		t.Errorf("existing file = %q, %d files left", b, len(entries))
+	}
+	if _, err := ops.SplitFile(path, ops.SplitOptions{By: ops.SplitRows, Rows: 2, Dir: filepath.Join(dir, "rows")}); err == nil {
+		t.Error("splitting into the same folder twice should fail")
+	}
+	if got := readFiles(t, []string{filepath.Join(dir, "rows", "data-0001.csv")}); got[0] != "id,city,note\n1,Oslo,a\n2,Rome,\"two\nlines\"\n" {
+		t.Errorf("first split was overwritten: %q", got[0])
+	}
+
+	// 规范化后同名的列值写到不同的文件
+	same := filepath.Join(dir, "same.csv")
+	if err := os.WriteFile(same, []byte("k\na/b\na_b\na:b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out = filepath.Join(dir, "same")
+	os.Mkdir(out, 0o755)
+	files, err = ops.SplitFile(same, ops.SplitOptions{By: ops.SplitColumn, Column: "k", Dir: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readFiles(t, files); !reflect.DeepEqual(got, []string{"k\na/b\n", "k\na_b\n", "k\na:b\n"}) {
+		t.Errorf("colliding names = %q", got)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a := loader.NewMemSource([]string{"id", "name"}, [][]string{{"1", "ann"}})
+	b := loader.NewMemSource([]string{"name", "city", "id"}, [][]string{{"bob", "Oslo", "2"}, {"cy", "Rome", "3"}})
+	res, err := ops.Merge([]loader.RowSource{a, b}, ops.MergeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if got := res.Header(); !reflect.DeepEqual(got, []string{"id", "name", "city"}) {
+		t.Errorf("header = %q", got)
+	}
+	want := []string{"1,ann,", "2,bob,Oslo", "3,cy,Rome"}
+	if got := sourceRows(t, res); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %v", got)
+	}
+}
+
+func TestSplitMergeCommands(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "in.csv")
+	os.WriteFile(path, []byte("id,v\n1,a\n2,b\n3,c\n"), 0o644)
+	var stderr strings.Builder
+	if err := runSplit([]string{"--rows", "2", path}, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	files := strings.Fields(stderr.String())
+	if len(files) != 2 {
+		t.Fatalf("split wrote %v", files)
+	}
+
+	out := filepath.Join(dir, "merged.csv")
+	if err := runMerge(append([]string{"--output", out}, files...), &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(out); string(b) != "id,v\n1,a\n2,b\n3,c\n" {
+		t.Errorf("merged = %q", b)
+	}
+
+	if err := runSplit([]string{"--rows", "2", "--column", "v", path}, &stderr); err == nil {
+		t.Error("expected error for two split modes")
+	}
+	if n, err := parseSize("1.5k"); err != nil || n != 1536 {
+		t.Errorf("parseSize = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/devbiu/CsvView/export"
+	"github.com/devbiu/CsvView/loader"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestXLSXRoundTrip(t *testing.T) {
+	rows := [][]string{
+		{"1", "1.5", "2024-01-02", "a"},
+		{"2", "", "2024-01-03", "b,c"},
+		{"3", "2", "", "\"q\""},
+	}
+	src := loader.NewMemSource([]string{"id", "price", "day", "name"}, rows)
+	path := filepath.Join(t.TempDir(), "out.xlsx")
+	if err := export.ExportFile(path, src, export.Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ, _ := f.GetCellType("Sheet1", "A2"); typ == excelize.CellTypeInlineString || typ == excelize.CellTypeSharedString {
+		t.Errorf("id cell should be numeric, got type %v", typ)
+	}
+	if panes, _ := f.GetPanes("Sheet1"); !panes.Freeze || panes.YSplit != 1 {
+		t.Errorf("header pane not frozen: %+v", panes)
+	}
+	if tables, _ := f.GetTables("Sheet1"); len(tables) != 1 || tables[0].Range != "A1:D4" {
+		t.Errorf("tables = %+v", tables)
+	}
+	f.Close()
+
+	sheets, err := loader.OpenSheets(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheets) != 1 || sheets[0].Name != "Sheet1" {
+		t.Fatalf("sheets = %+v", sheets)
+	}
+	l := sheets[0].Source
+	defer l.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for !l.Indexed() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := l.Header(); !reflect.DeepEqual(got, []string{"id", "price", "day", "name"}) {
+		t.Fatalf("header = %q", got)
+	}
+	if l.RowCount() != len(rows) {
+		t.Fatalf("RowCount = %d", l.RowCount())
+	}
+	for i, want := range rows {
+		got, ok := l.GetRow(i)
+		if !ok {
+			t.Fatalf("row %d not loaded", i)
+		}
+		// 行尾的空单元格不会写入工作表
+		for len(got) < len(want) {
+			got = append(got, "")
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("row %d = %q, want %q", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/devbiu/CsvView/export"
+	"github.com/devbiu/CsvView/loader"
+	"github.com/devbiu/CsvView/ops"
+)
+
+// subcommands 不启动界面、直接在命令行执行的子命令
+var subcommands = map[string]func(args []string, stderr io.Writer) error{
+	"convert": runConvert,
+	"diff":    runDiff,
+	"split":   runSplit,
+	"merge":   runMerge,
+}
+
+// runSubcommand 执行子命令；args[0] 不是子命令时返回 false
+func runSubcommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	cmd, ok := subcommands[args[0]]
+	if !ok {
+		return false
+	}
+	if err := cmd(args[1:], os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "csvview %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+	return true
+}
+
+// runConvert csvview convert [options] input output
+// 输入可以是任何能打开的格式，输出格式由扩展名决定（或 --format 指定）
+func runConvert(args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "", "output format (default: from output extension)")
+	table := fs.String("table", "data", "SQL table name")
+	dialect := fs.String("dialect", string(export.DialectSQLite), "SQL dialect: sqlite, postgres, mysql, sqlserver")
+	batch := fs.Int("batch", 500, "rows per SQL INSERT statement")
+	rowGroup := fs.Int("row-group", 128*1024, "rows per Parquet row group")
+	compression := fs.String("compression", "snappy", "Parquet compression: snappy, zstd, gzip, none")
+	fixedWidth := fs.String("fixed-width", "", "read input as fixed-width text using a saved layout (name or .json path)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: csvview convert [options] input output")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected input and output paths")
+	}
+
+	var src loader.RowSource
+	if *fixedWidth != "" {
+		spec, err := loader.LoadFixedWidthSpec(*fixedWidth)
+		if err != nil {
+			return err
+		}
+		if src, err = loader.NewFixedWidthLoader(fs.Arg(0), spec, 1024); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if src, err = loader.Open(fs.Arg(0)); err != nil {
+			return err
+		}
+	}
+	defer src.Close()
+	return export.ExportFile(fs.Arg(1), src, export.Options{
+		Format:       export.Format(*format),
+		Table:        *table,
+		Dialect:      export.Dialect(*dialect),
+		BatchSize:    *batch,
+		RowGroupSize: *rowGroup,
+		Compression:  *compression,
+	})
+}
+
+// runDiff csvview diff --key col[,col...] [options] old new
+// 按键比较两个文件，只输出新增、删除和修改的行；不指定 --output 时写到标准输出
+func runDiff(args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	keys := fs.String("key", "", "comma-separated key columns (required)")
+	format := fs.String("format", "", "output format: csv, json, ... (default: from output extension, or csv)")
+	output := fs.String("output", "", "write the diff to this file instead of stdout")
+	memRows := fs.Int("mem-rows", ops.DefaultMemRows, "rows indexed in memory before partitioning to disk")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: csvview diff --key col[,col...] [options] old new")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 || *keys == "" {
+		fs.Usage()
+		return fmt.Errorf("expected --key and two input paths")
+	}
+
+	oldSrc, err := loader.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer oldSrc.Close()
+	newSrc, err := loader.Open(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer newSrc.Close()
+	res, err := ops.Diff(oldSrc, newSrc, ops.DiffOptions{Keys: strings.Split(*keys, ","), MemRows: *memRows})
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+	fmt.Fprintf(stderr, "%d added, %d removed, %d changed\n", res.Added, res.Removed, res.Changed)
+
+	opts := export.Options{Format: export.Format(*format)}
+	if *output != "" {
+		return export.ExportFile(*output, res, opts)
+	}
+	if opts.Format == "" {
+		opts.Format = export.FormatCSV
+	}
+	return export.Export(os.Stdout, res, opts)
+}
+
+// runSplit csvview split (--rows N | --bytes SIZE | --column NAME) [--out-dir DIR] input
+// 拆分 CSV/TSV 文件，每个文件都带表头；写出的文件名逐行打印到标准错误
+func runSplit(args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("split", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rows := fs.Int("rows", 0, "data rows per file")
+	size := fs.String("bytes", "", "approximate size per file, e.g. 500M or 2G (records are never cut)")
+	column := fs.String("column", "", "write one file per distinct value of this column")
+	dir := fs.String("out-dir", "", "output directory (default: next to the input)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: csvview split (--rows N | --bytes SIZE | --column NAME) [options] input")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	opts := ops.SplitOptions{Dir: *dir}
+	modes := 0
+	if *rows > 0 {
+		opts.By, opts.Rows = ops.SplitRows, *rows
+		modes++
+	}
+	if *size != "" {
+		n, err := parseSize(*size)
+		if err != nil {
+			return err
+		}
+		opts.By, opts.Bytes = ops.SplitBytes, n
+		modes++
+	}
+	if *column != "" {
+		opts.By, opts.Column = ops.SplitColumn, *column
+		modes++
+	}
+	if fs.NArg() != 1 || modes != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one of --rows, --bytes, --column and one input path")
+	}
+	files, err := ops.SplitFile(fs.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		fmt.Fprintln(stderr, f)
+	}
+	return nil
+}
+
+// parseSize 解析 500K、20M、2G 这样的大小，单位为 1024 的倍数，不带单位时为字节
+func parseSize(s string) (int64, error) {
+	mult := int64(1)
+	num := strings.ToUpper(strings.TrimSpace(s))
+	num = strings.TrimSuffix(num, "B")
+	switch {
+	case strings.HasSuffix(num, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(num, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(num, "G"):
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		num = num[:len(num)-1]
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// runMerge csvview merge [--output out] input...
+// 上下拼接多个文件，列按名称对应，缺少的列填空；不指定 --output 时以 CSV 写到标准输出
+func runMerge(args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "", "output format: csv, json, ... (default: from output extension, or csv)")
+	output := fs.String("output", "", "write the merged table to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: csvview merge [options] input input...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return fmt.Errorf("expected at least two input paths")
+	}
+	var srcs []loader.RowSource
+	defer func() {
+		for _, src := range srcs {
+			src.Close()
+		}
+	}()
+	for _, path := range fs.Args() {
+		src, err := loader.Open(path)
+		if err != nil {
+			return err
+		}
+		srcs = append(srcs, src)
+	}
+	res, err := ops.Merge(srcs, ops.MergeOptions{})
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+
+	opts := export.Options{Format: export.Format(*format)}
+	if *output != "" {
+		return export.ExportFile(*output, res, opts)
+	}
+	if opts.Format == "" {
+		opts.Format = export.FormatCSV
+	}
+	return export.Export(os.Stdout, res, opts)
+}
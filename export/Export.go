@@ -0,0 +1,318 @@
+// Package export 把数据源（当前视图或选区）流式写出为其他格式，
+// 逐行读取逐行写出，导出大文件时不会把数据整体读入内存。
+package export
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/devbiu/CsvView/loader"
+)
+
+// Format 导出格式
+type Format string
+
+const (
+	FormatCSV      Format = "csv"
+	FormatTSV      Format = "tsv"
+	FormatJSON     Format = "json"
+	FormatNDJSON   Format = "ndjson"
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+	FormatSQL      Format = "sql"
+	FormatParquet  Format = "parquet"
+	FormatXLSX     Format = "xlsx"
+	// FormatSQLiteDB 写入 SQLite 数据库文件中的新表（不是 SQL 脚本）
+	FormatSQLiteDB Format = "sqlite"
+)
+
+// Formats 支持的导出格式，按菜单展示顺序排列
+var Formats = []Format{FormatCSV, FormatTSV, FormatJSON, FormatNDJSON, FormatMarkdown, FormatHTML, FormatSQL, FormatParquet, FormatXLSX, FormatSQLiteDB}
+
+// Ext 格式对应的文件扩展名
+func (f Format) Ext() string {
+	switch f {
+	case FormatMarkdown:
+		return ".md"
+	default:
+		return "." + string(f)
+	}
+}
+
+// Typed 格式是否需要列类型（建表语句、二进制列存、电子表格单元格类型等）
+func (f Format) Typed() bool {
+	return f == FormatSQL || f == FormatParquet || f == FormatXLSX || f == FormatSQLiteDB
+}
+
+// FormatFromPath 根据扩展名推断导出格式
+func FormatFromPath(path string) (Format, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".jsonl":
+		return FormatNDJSON, nil
+	case ".htm":
+		return FormatHTML, nil
+	case ".txt":
+		return FormatTSV, nil
+	case ".db", ".sqlite3":
+		return FormatSQLiteDB, nil
+	}
+	for _, f := range Formats {
+		if f.Ext() == ext {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported export format %q", ext)
+}
+
+// Range 行范围 [From, To)
+type Range struct {
+	From, To int
+}
+
+// Options 导出选项
+type Options struct {
+	Format Format
+	// Rows 只导出这些行，nil 表示整个视图
+	Rows *Range
+	// Cols 导出的列及顺序，nil 表示全部列
+	Cols []int
+	// Skip 跳过返回 true 的行（如去重时的重复行）；编辑中标记删除的行总是跳过
+	Skip func(row int) bool
+	// NoHeader 不写表头行（只对 CSV、TSV 有效）
+	NoHeader bool
+
+	// Table SQL 或 SQLite 数据库中的表名，默认 data
+	Table string
+	// Dialect SQL 方言
+	Dialect Dialect
+	// BatchSize 每条 INSERT 语句包含的行数，默认 500
+	BatchSize int
+
+	// RowGroupSize Parquet 每个行组的行数，默认 131072
+	RowGroupSize int
+	// Compression Parquet 压缩算法：snappy（默认）、zstd、gzip、none
+	Compression string
+
+	// Progress 每写出一批行回调一次，total 未知时为 -1
+	Progress func(done, total int)
+	// Cancel 关闭后导出以 ErrCanceled 结束
+	Cancel <-chan struct{}
+}
+
+// RecordWriter 按行写出一种格式
+type RecordWriter interface {
+	WriteHeader(header []string) error
+	Write(record []string) error
+	// Close 写出格式结尾并刷新缓冲，不关闭底层 io.Writer
+	Close() error
+}
+
+// NewWriter 创建格式对应的 RecordWriter；types 为列类型，SQL 等格式用它生成建表语句
+func NewWriter(w io.Writer, opts Options, types []loader.ColumnType) (RecordWriter, error) {
+	switch opts.Format {
+	case FormatCSV:
+		return newCsvWriter(w, ',', opts.NoHeader), nil
+	case FormatTSV:
+		return newCsvWriter(w, '\t', opts.NoHeader), nil
+	case FormatJSON:
+		return newJSONWriter(w, false), nil
+	case FormatNDJSON:
+		return newJSONWriter(w, true), nil
+	case FormatMarkdown:
+		return newMarkdownWriter(w), nil
+	case FormatHTML:
+		return newHTMLWriter(w), nil
+	case FormatSQL:
+		return newSQLWriter(w, opts, types), nil
+	case FormatParquet:
+		return newParquetWriter(w, opts, types), nil
+	case FormatXLSX:
+		return newXLSXWriter(w, types), nil
+	case FormatSQLiteDB:
+		return nil, errSQLiteNeedsFile
+	}
+	return nil, fmt.Errorf("unsupported export format %q", opts.Format)
+}
+
+// Export 把 src 按 opts 写出到 w
+func Export(w io.Writer, src loader.RowSource, opts Options) error {
+	if opts.Format == FormatSQLiteDB {
+		return errSQLiteNeedsFile
+	}
+	cols := opts.Cols
+	if cols == nil {
+		cols = make([]int, src.ColCount())
+		for i := range cols {
+			cols[i] = i
+		}
+	}
+	var types []loader.ColumnType
+	switch {
+	case opts.Format == FormatParquet || opts.Format == FormatSQL:
+		// Parquet 的文件结构和 SQL 的 CREATE TABLE 写出后列类型不能再改，
+		// 按要导出的全部行推断，采样之后的值也符合列类型
+		var err error
+		if types, err = inferAll(src, opts, cols); err != nil {
+			return err
+		}
+	case opts.Format.Typed():
+		all, err := loader.InferTypes(src, loader.DefaultInferSample)
+		if err != nil {
+			return err
+		}
+		types = project(all, cols)
+	}
+
+	bw := bufio.NewWriterSize(w, 64*1024)
+	rw, err := NewWriter(bw, opts, types)
+	if err != nil {
+		return err
+	}
+	if err := rw.WriteHeader(project(src.Header(), cols)); err != nil {
+		return err
+	}
+	if err := ForEach(src, opts, cols, func(record []string) error {
+		return rw.Write(record)
+	}); err != nil {
+		return err
+	}
+	if err := rw.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// inferAll 遍历要导出的全部行推断列类型，进度只在写出时报告。
+// SQL 中 NaN、Inf 和十六进制小数不是数字字面量，含有这些值的列按字符串处理
+func inferAll(src loader.RowSource, opts Options, cols []int) ([]loader.ColumnType, error) {
+	ti := loader.NewTypeInferrer(len(cols))
+	notNumber := make([]bool, len(cols))
+	opts.Progress = nil
+	err := ForEach(src, opts, cols, func(record []string) error {
+		ti.Observe(record)
+		if opts.Format == FormatSQL {
+			for i, v := range record {
+				if s := strings.TrimSpace(v); s != "" && !notNumber[i] && !isSQLNumber(s) {
+					notNumber[i] = true
+				}
+			}
+		}
+		return nil
+	})
+	types := ti.Types()
+	for i, t := range types {
+		if t == loader.TypeFloat && notNumber[i] {
+			types[i] = loader.TypeString
+		}
+	}
+	return types, err
+}
+
+// ExportFile 导出到文件；opts.Format 为空时根据扩展名推断。
+// SQLite 格式在已有数据库中新建表，其他格式覆盖文件。
+func ExportFile(path string, src loader.RowSource, opts Options) (err error) {
+	if opts.Format == "" {
+		if opts.Format, err = FormatFromPath(path); err != nil {
+			return err
+		}
+	}
+	if opts.Format == FormatSQLiteDB {
+		return exportSQLite(path, src, opts)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return Export(f, src, opts)
+}
+
+// ForEach 按 opts 的行范围遍历 src，回调投影到 cols 之后的行（record 会被复用）
+func ForEach(src loader.RowSource, opts Options, cols []int, fn func(record []string) error) error {
+	from, to := 0, -1
+	total := -1
+	if opts.Rows != nil {
+		from, to = opts.Rows.From, opts.Rows.To
+		total = to - from
+	} else if src.Indexed() {
+		total = src.RowCount()
+	}
+	done := 0
+	record := make([]string, len(cols))
+	ed, _ := loader.AsEditor(src)
+	err := src.ReadRows(from, to, func(row int, rec []string) error {
+		if (opts.Skip != nil && opts.Skip(row)) || (ed != nil && ed.Deleted(row)) {
+			return nil
+		}
+		for i, c := range cols {
+			if c < len(rec) {
+				record[i] = rec[c]
+			} else {
+				record[i] = ""
+			}
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+		done++
+		if done%1000 == 0 && opts.Cancel != nil {
+			select {
+			case <-opts.Cancel:
+				return ErrCanceled
+			default:
+			}
+		}
+		if opts.Progress != nil && done%10000 == 0 {
+			opts.Progress(done, total)
+		}
+		return nil
+	})
+	if opts.Progress != nil && err == nil {
+		opts.Progress(done, done)
+	}
+	return err
+}
+
+// ErrCanceled 导出被用户取消
+var ErrCanceled = errors.New("export canceled")
+
+func project[T any](values []T, cols []int) []T {
+	out := make([]T, len(cols))
+	for i, c := range cols {
+		if c < len(values) {
+			out[i] = values[c]
+		}
+	}
+	return out
+}
+
+// uniqueNames 空列名补为 column_N，重复列名追加序号；追加序号后与其他列名相同时继续递增
+func uniqueNames(header []string) []string {
+	names := make([]string, len(header))
+	seen := make(map[string]bool)
+	next := make(map[string]int) // 每个列名下一个尝试的序号
+	for i, h := range header {
+		if h == "" {
+			h = "column_" + strconv.Itoa(i+1)
+		}
+		name := h
+		for n := max(next[h], 2); seen[name]; n++ {
+			name = h + "_" + strconv.Itoa(n)
+			next[h] = n + 1
+		}
+		seen[name] = true
+		names[i] = name
+	}
+	return names
+}
@@ -0,0 +1,199 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/devbiu/CsvView/loader"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+)
+
+const (
+	defaultRowGroupSize = 128 * 1024
+	parquetWriteBatch   = 1024
+)
+
+// Compressions 支持的 Parquet 压缩算法
+var Compressions = []string{"snappy", "zstd", "gzip", "none"}
+
+func parquetCodec(name string) (compress.Codec, error) {
+	switch strings.ToLower(name) {
+	case "", "snappy":
+		return &parquet.Snappy, nil
+	case "zstd":
+		return &parquet.Zstd, nil
+	case "gzip":
+		return &parquet.Gzip, nil
+	case "none", "uncompressed":
+		return &parquet.Uncompressed, nil
+	}
+	return nil, fmt.Errorf("unsupported parquet compression %q", name)
+}
+
+// parquetNode 推断类型对应的 Parquet 逻辑类型，所有列都可为空
+func parquetNode(t loader.ColumnType) parquet.Node {
+	switch t {
+	case loader.TypeInt:
+		return parquet.Optional(parquet.Int(64))
+	case loader.TypeFloat:
+		return parquet.Optional(parquet.Leaf(parquet.DoubleType))
+	case loader.TypeBool:
+		return parquet.Optional(parquet.Leaf(parquet.BooleanType))
+	case loader.TypeDate:
+		return parquet.Optional(parquet.Date())
+	case loader.TypeTimestamp:
+		return parquet.Optional(parquet.Timestamp(parquet.Millisecond))
+	default:
+		return parquet.Optional(parquet.String())
+	}
+}
+
+// orderedGroup 字段按表头顺序排列的 Group。parquet.Group 是 map，字段总是按名称排序
+type orderedGroup struct {
+	parquet.Group
+	fields []parquet.Field
+}
+
+func newOrderedGroup(g parquet.Group, names []string) orderedGroup {
+	byName := make(map[string]parquet.Field, len(g))
+	for _, f := range g.Fields() {
+		byName[f.Name()] = f
+	}
+	fields := make([]parquet.Field, len(names))
+	for i, name := range names {
+		fields[i] = byName[name]
+	}
+	return orderedGroup{Group: g, fields: fields}
+}
+
+func (g orderedGroup) Fields() []parquet.Field { return g.fields }
+
+// parquetWriter 按行组写出 Parquet。列类型按全部行推断，正常情况下每个值都能转换；
+// 推断之后数据源又有变化时无法转换的值写为 null，并在 Close 时报告个数
+type parquetWriter struct {
+	out      io.Writer
+	opts     Options
+	types    []loader.ColumnType
+	w        *parquet.Writer
+	cols     int
+	pending  []parquet.Row
+	mismatch int // 无法按列类型转换的非空值个数
+}
+
+func newParquetWriter(w io.Writer, opts Options, types []loader.ColumnType) *parquetWriter {
+	return &parquetWriter{out: w, opts: opts, types: types}
+}
+
+func (p *parquetWriter) WriteHeader(header []string) error {
+	codec, err := parquetCodec(p.opts.Compression)
+	if err != nil {
+		return err
+	}
+	names := uniqueNames(header)
+	group := parquet.Group{}
+	for i, name := range names {
+		t := loader.TypeString
+		if i < len(p.types) {
+			t = p.types[i]
+		}
+		group[name] = parquetNode(t)
+	}
+	schema := parquet.NewSchema("data", newOrderedGroup(group, names))
+	p.cols = len(names)
+
+	groupSize := p.opts.RowGroupSize
+	if groupSize <= 0 {
+		groupSize = defaultRowGroupSize
+	}
+	p.w = parquet.NewWriter(p.out, schema,
+		parquet.Compression(codec),
+		parquet.MaxRowsPerRowGroup(int64(groupSize)),
+		parquet.CreatedBy("CsvView", "", ""),
+	)
+	return nil
+}
+
+func (p *parquetWriter) Write(record []string) error {
+	row := make(parquet.Row, p.cols)
+	for i := range row {
+		v := ""
+		if i < len(record) {
+			v = record[i]
+		}
+		t := loader.TypeString
+		if i < len(p.types) {
+			t = p.types[i]
+		}
+		row[i] = parquetValue(v, t).Level(0, 1, i)
+		if row[i].IsNull() {
+			row[i] = parquet.NullValue().Level(0, 0, i)
+			if strings.TrimSpace(v) != "" {
+				p.mismatch++
+			}
+		}
+	}
+	p.pending = append(p.pending, row)
+	if len(p.pending) >= parquetWriteBatch {
+		return p.flush()
+	}
+	return nil
+}
+
+func (p *parquetWriter) flush() error {
+	if len(p.pending) == 0 {
+		return nil
+	}
+	_, err := p.w.WriteRows(p.pending)
+	p.pending = p.pending[:0]
+	return err
+}
+
+func (p *parquetWriter) Close() error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	if err := p.w.Close(); err != nil {
+		return err
+	}
+	if p.mismatch > 0 {
+		return fmt.Errorf("%d values did not match their column type and were written as null", p.mismatch)
+	}
+	return nil
+}
+
+// parquetValue 按列类型转换单元格，无法转换时返回 null
+func parquetValue(v string, t loader.ColumnType) parquet.Value {
+	if t == loader.TypeString {
+		if v == "" {
+			return parquet.NullValue()
+		}
+		return parquet.ByteArrayValue([]byte(v))
+	}
+	v = strings.TrimSpace(v)
+	switch t {
+	case loader.TypeInt:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return parquet.Int64Value(n)
+		}
+	case loader.TypeFloat:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return parquet.DoubleValue(f)
+		}
+	case loader.TypeBool:
+		if b, ok := loader.ParseBool(v); ok {
+			return parquet.BooleanValue(b)
+		}
+	case loader.TypeDate:
+		if d, ok := loader.ParseDate(v); ok {
+			return parquet.Int32Value(int32(d.Unix() / 86400))
+		}
+	case loader.TypeTimestamp:
+		if ts, ok := loader.ParseTimestamp(v); ok {
+			return parquet.Int64Value(ts.UnixMilli())
+		}
+	}
+	return parquet.NullValue()
+}
@@ -0,0 +1,232 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devbiu/CsvView/loader"
+)
+
+// Dialect SQL 方言
+type Dialect string
+
+const (
+	DialectSQLite    Dialect = "sqlite"
+	DialectPostgres  Dialect = "postgres"
+	DialectMySQL     Dialect = "mysql"
+	DialectSQLServer Dialect = "sqlserver"
+)
+
+// Dialects 支持的 SQL 方言
+var Dialects = []Dialect{DialectSQLite, DialectPostgres, DialectMySQL, DialectSQLServer}
+
+const defaultBatchSize = 500
+
+// QuoteIdent 按方言引用标识符
+func (d Dialect) QuoteIdent(name string) string {
+	switch d {
+	case DialectMySQL:
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case DialectSQLServer:
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	default:
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+}
+
+// ColumnType 推断类型在方言中的列类型
+func (d Dialect) ColumnType(t loader.ColumnType) string {
+	switch t {
+	case loader.TypeInt:
+		return "BIGINT"
+	case loader.TypeFloat:
+		if d == DialectPostgres {
+			return "DOUBLE PRECISION"
+		}
+		if d == DialectSQLServer {
+			return "FLOAT"
+		}
+		return "DOUBLE"
+	case loader.TypeBool:
+		if d == DialectSQLServer {
+			return "BIT"
+		}
+		return "BOOLEAN"
+	case loader.TypeDate:
+		return "DATE"
+	case loader.TypeTimestamp:
+		if d == DialectSQLServer || d == DialectMySQL {
+			return "DATETIME"
+		}
+		return "TIMESTAMP"
+	default:
+		if d == DialectSQLServer {
+			return "NVARCHAR(MAX)"
+		}
+		return "TEXT"
+	}
+}
+
+// quoteValue 按列类型生成字面量，空值写为 NULL。
+// 不符合列类型的值按字符串写出并返回 false，这样的脚本在严格的数据库中无法导入
+func (d Dialect) quoteValue(v string, t loader.ColumnType) (string, bool) {
+	if v == "" {
+		return "NULL", true
+	}
+	fits := true
+	switch t {
+	case loader.TypeInt:
+		if s := strings.TrimSpace(v); isSQLInt(s) {
+			return s, true
+		}
+		fits = false
+	case loader.TypeFloat:
+		if s := strings.TrimSpace(v); isSQLNumber(s) {
+			return s, true
+		}
+		fits = false
+	case loader.TypeBool:
+		b, ok := loader.ParseBool(strings.TrimSpace(v))
+		switch {
+		case !ok:
+			fits = false
+		case d == DialectSQLServer || d == DialectSQLite:
+			if b {
+				return "1", true
+			}
+			return "0", true
+		case b:
+			return "TRUE", true
+		default:
+			return "FALSE", true
+		}
+	case loader.TypeDate:
+		var tm time.Time
+		if tm, fits = loader.ParseDate(strings.TrimSpace(v)); fits {
+			v = tm.Format("2006-01-02")
+		}
+	case loader.TypeTimestamp:
+		var tm time.Time
+		if tm, fits = loader.ParseTimestamp(strings.TrimSpace(v)); fits {
+			v = tm.Format("2006-01-02 15:04:05")
+		}
+	}
+	s := "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	if d == DialectMySQL {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	if d == DialectSQLServer && t == loader.TypeString {
+		s = "N" + s
+	}
+	return s, fits
+}
+
+// isSQLInt 是否为可以直接写进 SQL 的十进制整数
+func isSQLInt(s string) bool {
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err == nil
+}
+
+// isSQLNumber 是否为可以直接写进 SQL 的十进制数。
+// ParseFloat 还接受 NaN、Inf 和十六进制写法，这些不是 SQL 数字字面量
+func isSQLNumber(s string) bool {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return false
+	}
+	return !strings.ContainsFunc(s, func(r rune) bool {
+		return !strings.ContainsRune("0123456789+-.eE", r)
+	})
+}
+
+// sqlWriter 输出 CREATE TABLE 和按批合并的 INSERT 语句
+type sqlWriter struct {
+	w       io.Writer
+	dialect Dialect
+	table   string
+	types   []loader.ColumnType
+	batch   int
+	insert  string
+	pending []string
+	// mismatch 不符合列类型、按字符串写出的值个数
+	mismatch int
+}
+
+func newSQLWriter(w io.Writer, opts Options, types []loader.ColumnType) *sqlWriter {
+	s := &sqlWriter{w: w, dialect: opts.Dialect, table: opts.Table, types: types, batch: opts.BatchSize}
+	if s.dialect == "" {
+		s.dialect = DialectSQLite
+	}
+	if s.table == "" {
+		s.table = "data"
+	}
+	if s.batch <= 0 {
+		s.batch = defaultBatchSize
+	}
+	if s.dialect == DialectSQLServer {
+		// SQL Server 单条 INSERT 最多 1000 行
+		s.batch = min(s.batch, 1000)
+	}
+	return s
+}
+
+func (s *sqlWriter) WriteHeader(header []string) error {
+	names := uniqueNames(header)
+	defs := make([]string, len(names))
+	for i, h := range names {
+		names[i] = s.dialect.QuoteIdent(h)
+		t := loader.TypeString
+		if i < len(s.types) {
+			t = s.types[i]
+		}
+		defs[i] = "  " + names[i] + " " + s.dialect.ColumnType(t)
+	}
+	table := s.dialect.QuoteIdent(s.table)
+	s.insert = "INSERT INTO " + table + " (" + strings.Join(names, ", ") + ") VALUES\n"
+	_, err := fmt.Fprintf(s.w, "CREATE TABLE %s (\n%s\n);\n\n", table, strings.Join(defs, ",\n"))
+	return err
+}
+
+func (s *sqlWriter) Write(record []string) error {
+	values := make([]string, len(record))
+	for i, v := range record {
+		t := loader.TypeString
+		if i < len(s.types) {
+			t = s.types[i]
+		}
+		lit, ok := s.dialect.quoteValue(v, t)
+		if !ok {
+			s.mismatch++
+		}
+		values[i] = lit
+	}
+	s.pending = append(s.pending, "  ("+strings.Join(values, ", ")+")")
+	if len(s.pending) >= s.batch {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *sqlWriter) flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(s.w, s.insert+strings.Join(s.pending, ",\n")+";\n")
+	s.pending = s.pending[:0]
+	return err
+}
+
+// Close 写出剩余的行。推断类型之后数据源又有变化时，报告不符合列类型的值个数
+func (s *sqlWriter) Close() error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	if s.mismatch > 0 {
+		return fmt.Errorf("%d values did not match their column type and were written as strings", s.mismatch)
+	}
+	return nil
+}
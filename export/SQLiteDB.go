@@ -0,0 +1,111 @@
+package export
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/devbiu/CsvView/loader"
+	_ "modernc.org/sqlite"
+)
+
+// errSQLiteNeedsFile SQLite 数据库只能写到文件
+var errSQLiteNeedsFile = errors.New("sqlite export needs a file path, use ExportFile")
+
+// exportSQLite 在 path 指向的数据库（不存在时新建）中创建 opts.Table 并写入 src。
+// 建表和写入在同一个事务中，出错或取消时数据库保持原样。
+func exportSQLite(path string, src loader.RowSource, opts Options) error {
+	cols := opts.Cols
+	if cols == nil {
+		cols = make([]int, src.ColCount())
+		for i := range cols {
+			cols[i] = i
+		}
+	}
+	all, err := loader.InferTypes(src, loader.DefaultInferSample)
+	if err != nil {
+		return err
+	}
+	types := project(all, cols)
+	table := opts.Table
+	if table == "" {
+		table = "data"
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	d := DialectSQLite
+	names := uniqueNames(project(src.Header(), cols))
+	defs := make([]string, len(names))
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = d.QuoteIdent(name)
+		defs[i] = quoted[i] + " " + d.ColumnType(types[i])
+	}
+	if _, err := tx.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", d.QuoteIdent(table), strings.Join(defs, ", "))); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		d.QuoteIdent(table), strings.Join(quoted, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	args := make([]any, len(names))
+	if err := ForEach(src, opts, cols, func(record []string) error {
+		for i, v := range record {
+			args[i] = sqliteValue(v, types[i])
+		}
+		_, err := stmt.Exec(args...)
+		return err
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// sqliteValue 按列类型转换单元格，空值写为 NULL，无法转换的值按文本写入
+func sqliteValue(v string, t loader.ColumnType) any {
+	if v == "" {
+		return nil
+	}
+	s := strings.TrimSpace(v)
+	switch t {
+	case loader.TypeInt:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case loader.TypeFloat:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case loader.TypeBool:
+		if b, ok := loader.ParseBool(s); ok {
+			if b {
+				return 1
+			}
+			return 0
+		}
+	case loader.TypeDate:
+		if tm, ok := loader.ParseDate(s); ok {
+			return tm.Format("2006-01-02")
+		}
+	case loader.TypeTimestamp:
+		if tm, ok := loader.ParseTimestamp(s); ok {
+			return tm.Format("2006-01-02 15:04:05")
+		}
+	}
+	return v
+}
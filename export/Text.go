@@ -0,0 +1,178 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"html"
+	"io"
+	"strings"
+)
+
+type csvWriter struct {
+	w        *csv.Writer
+	noHeader bool
+}
+
+func newCsvWriter(w io.Writer, comma rune, noHeader bool) *csvWriter {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	return &csvWriter{w: cw, noHeader: noHeader}
+}
+
+func (c *csvWriter) WriteHeader(header []string) error {
+	if c.noHeader {
+		return nil
+	}
+	return c.w.Write(header)
+}
+
+func (c *csvWriter) Write(record []string) error { return c.w.Write(record) }
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonWriter 输出以表头为键的对象；lines 为 true 时输出 NDJSON，否则输出 JSON 数组
+type jsonWriter struct {
+	w     io.Writer
+	lines bool
+	keys  [][]byte
+	n     int
+}
+
+func newJSONWriter(w io.Writer, lines bool) *jsonWriter {
+	return &jsonWriter{w: w, lines: lines}
+}
+
+func (j *jsonWriter) WriteHeader(header []string) error {
+	// 重复或空的列名会导致键冲突
+	for _, h := range uniqueNames(header) {
+		k, err := json.Marshal(h)
+		if err != nil {
+			return err
+		}
+		j.keys = append(j.keys, k)
+	}
+	if !j.lines {
+		_, err := io.WriteString(j.w, "[")
+		return err
+	}
+	return nil
+}
+
+func (j *jsonWriter) Write(record []string) error {
+	var sb strings.Builder
+	switch {
+	case j.lines:
+	case j.n == 0:
+		sb.WriteString("\n  ")
+	default:
+		sb.WriteString(",\n  ")
+	}
+	sb.WriteByte('{')
+	for i, k := range j.keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.Write(k)
+		sb.WriteByte(':')
+		v, err := json.Marshal(record[i])
+		if err != nil {
+			return err
+		}
+		sb.Write(v)
+	}
+	sb.WriteByte('}')
+	if j.lines {
+		sb.WriteByte('\n')
+	}
+	j.n++
+	_, err := io.WriteString(j.w, sb.String())
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	if j.lines {
+		return nil
+	}
+	_, err := io.WriteString(j.w, "\n]\n")
+	return err
+}
+
+// markdownWriter 输出 GitHub Markdown 表格
+type markdownWriter struct {
+	w io.Writer
+}
+
+func newMarkdownWriter(w io.Writer) *markdownWriter {
+	return &markdownWriter{w: w}
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+func (m *markdownWriter) row(cells []string) error {
+	var sb strings.Builder
+	sb.WriteByte('|')
+	for _, c := range cells {
+		sb.WriteByte(' ')
+		sb.WriteString(markdownEscaper.Replace(c))
+		sb.WriteString(" |")
+	}
+	sb.WriteByte('\n')
+	_, err := io.WriteString(m.w, sb.String())
+	return err
+}
+
+func (m *markdownWriter) WriteHeader(header []string) error {
+	if err := m.row(header); err != nil {
+		return err
+	}
+	sep := make([]string, len(header))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	return m.row(sep)
+}
+
+func (m *markdownWriter) Write(record []string) error { return m.row(record) }
+func (m *markdownWriter) Close() error                { return nil }
+
+// htmlWriter 输出 HTML 表格片段
+type htmlWriter struct {
+	w io.Writer
+}
+
+func newHTMLWriter(w io.Writer) *htmlWriter {
+	return &htmlWriter{w: w}
+}
+
+func (h *htmlWriter) row(cells []string, tag string) error {
+	var sb strings.Builder
+	sb.WriteString("    <tr>")
+	for _, c := range cells {
+		sb.WriteString("<" + tag + ">")
+		sb.WriteString(strings.ReplaceAll(html.EscapeString(c), "\n", "<br>"))
+		sb.WriteString("</" + tag + ">")
+	}
+	sb.WriteString("</tr>\n")
+	_, err := io.WriteString(h.w, sb.String())
+	return err
+}
+
+func (h *htmlWriter) WriteHeader(header []string) error {
+	if _, err := io.WriteString(h.w, "<table>\n  <thead>\n"); err != nil {
+		return err
+	}
+	if err := h.row(header, "th"); err != nil {
+		return err
+	}
+	_, err := io.WriteString(h.w, "  </thead>\n  <tbody>\n")
+	return err
+}
+
+func (h *htmlWriter) Write(record []string) error { return h.row(record, "td") }
+
+func (h *htmlWriter) Close() error {
+	_, err := io.WriteString(h.w, "  </tbody>\n</table>\n")
+	return err
+}
@@ -0,0 +1,141 @@
+package export
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/devbiu/CsvView/loader"
+	"github.com/xuri/excelize/v2"
+)
+
+const xlsxSheet = "Sheet1"
+
+// ErrTooManyRows 导出行数超过 Excel 工作表上限
+var ErrTooManyRows = errors.New("xlsx: more than 1048576 rows, export a selection or use another format")
+
+// xlsxWriter 通过 StreamWriter 逐行写出工作表：表头加粗并冻结，数据区域带自动筛选；
+// 推断为数值、布尔、日期的列写为对应类型的单元格，无法转换的值按文本写出
+type xlsxWriter struct {
+	out   io.Writer
+	types []loader.ColumnType
+	f     *excelize.File
+	sw    *excelize.StreamWriter
+	row   int
+	cols  int
+
+	headerStyle, dateStyle, timeStyle int
+}
+
+func newXLSXWriter(w io.Writer, types []loader.ColumnType) *xlsxWriter {
+	return &xlsxWriter{out: w, types: types}
+}
+
+func (x *xlsxWriter) WriteHeader(header []string) error {
+	x.f = excelize.NewFile()
+	var err error
+	if x.headerStyle, err = x.f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}}); err != nil {
+		return err
+	}
+	dateFmt, timeFmt := "yyyy-mm-dd", "yyyy-mm-dd hh:mm:ss"
+	if x.dateStyle, err = x.f.NewStyle(&excelize.Style{CustomNumFmt: &dateFmt}); err != nil {
+		return err
+	}
+	if x.timeStyle, err = x.f.NewStyle(&excelize.Style{CustomNumFmt: &timeFmt}); err != nil {
+		return err
+	}
+	if x.sw, err = x.f.NewStreamWriter(xlsxSheet); err != nil {
+		return err
+	}
+	if err := x.sw.SetPanes(&excelize.Panes{
+		Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft",
+	}); err != nil {
+		return err
+	}
+	// 表格的列名必须唯一
+	names := uniqueNames(header)
+	x.cols = len(names)
+	cells := make([]interface{}, len(names))
+	for i, name := range names {
+		cells[i] = excelize.Cell{StyleID: x.headerStyle, Value: name}
+	}
+	return x.setRow(cells)
+}
+
+func (x *xlsxWriter) Write(record []string) error {
+	if x.row >= excelize.TotalRows {
+		return ErrTooManyRows
+	}
+	cells := make([]interface{}, x.cols)
+	for i := range cells {
+		v := ""
+		if i < len(record) {
+			v = record[i]
+		}
+		t := loader.TypeString
+		if i < len(x.types) {
+			t = x.types[i]
+		}
+		cells[i] = x.cell(v, t)
+	}
+	return x.setRow(cells)
+}
+
+func (x *xlsxWriter) setRow(cells []interface{}) error {
+	x.row++
+	return x.sw.SetRow("A"+strconv.Itoa(x.row), cells)
+}
+
+// cell 按列类型转换单元格，空值写为空单元格
+func (x *xlsxWriter) cell(v string, t loader.ColumnType) interface{} {
+	if v == "" {
+		return nil
+	}
+	if t == loader.TypeString {
+		return v
+	}
+	s := strings.TrimSpace(v)
+	switch t {
+	case loader.TypeInt:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case loader.TypeFloat:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case loader.TypeBool:
+		if b, ok := loader.ParseBool(s); ok {
+			return b
+		}
+	case loader.TypeDate:
+		if d, ok := loader.ParseDate(s); ok {
+			return excelize.Cell{StyleID: x.dateStyle, Value: d}
+		}
+	case loader.TypeTimestamp:
+		if ts, ok := loader.ParseTimestamp(s); ok {
+			return excelize.Cell{StyleID: x.timeStyle, Value: ts}
+		}
+	}
+	return v
+}
+
+func (x *xlsxWriter) Close() error {
+	defer x.f.Close()
+	if x.cols > 0 {
+		last, err := excelize.CoordinatesToCellName(x.cols, max(x.row, 2))
+		if err != nil {
+			return err
+		}
+		// 表格自带表头筛选按钮
+		if err := x.sw.AddTable(&excelize.Table{Range: "A1:" + last, Name: "data", StyleName: "TableStyleLight1"}); err != nil {
+			return err
+		}
+	}
+	if err := x.sw.Flush(); err != nil {
+		return err
+	}
+	_, err := x.f.WriteTo(x.out)
+	return err
+}
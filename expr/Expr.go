@@ -0,0 +1,628 @@
+// Package expr 计算列、筛选条件使用的表达式语言。
+//
+// 列名直接引用（含空格等字符时写成 [列 名]），字符串用单引号或双引号，
+// 支持 + - * / % 运算、& 字符串连接、比较运算、and/or/not，以及字符串、数值、
+// 日期、条件和正则函数，例如：
+//
+//	price * qty
+//	upper(name)
+//	date_diff(end, start) > 30 and status != 'closed'
+//	if(matches(email, '@example\.com$'), 'internal', 'external')
+package expr
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/devbiu/CsvView/loader"
+)
+
+// Expr 编译好的表达式，可以并发求值
+type Expr struct {
+	src  string
+	root node
+	deps []int
+}
+
+// Compile 按表头解析表达式，列名在编译时解析为下标
+func Compile(src string, header []string) (*Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks, header: header, deps: make(map[int]bool)}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+	}
+	e := &Expr{src: src, root: root}
+	for c := range p.deps {
+		e.deps = append(e.deps, c)
+	}
+	sort.Ints(e.deps)
+	return e, nil
+}
+
+// Eval 对一行求值
+func (e *Expr) Eval(rec []string) (Value, error) {
+	return e.root.eval(rec)
+}
+
+// String 表达式原文
+func (e *Expr) String() string { return e.src }
+
+// Deps 表达式引用的列下标
+func (e *Expr) Deps() []int { return e.deps }
+
+// Computed 作为视图的计算列，求值出错的单元格显示 #ERR
+func (e *Expr) Computed(name string) loader.Computed {
+	return loader.Computed{
+		Name: name,
+		Deps: e.deps,
+		Eval: func(rec []string) string {
+			v, err := e.Eval(rec)
+			if err != nil {
+				return "#ERR"
+			}
+			return v.String()
+		},
+	}
+}
+
+// Predicate 作为筛选条件，求值出错的行不保留
+func (e *Expr) Predicate() func(rec []string) bool {
+	return func(rec []string) bool {
+		v, err := e.Eval(rec)
+		return err == nil && v.Truthy()
+	}
+}
+
+type tokKind int
+
+const (
+	tEOF tokKind = iota
+	tNum
+	tStr
+	tIdent
+	tColumn // [带空格的列名]
+	tOp
+	tLParen
+	tRParen
+	tComma
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+// twoCharOps 需要优先匹配的双字符运算符
+var twoCharOps = []string{"==", "!=", "<>", "<=", ">=", "&&", "||"}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.' && i+1 < len(rs) && unicode.IsDigit(rs[i+1]):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.' || rs[j] == 'e' || rs[j] == 'E' ||
+				(rs[j] == '-' || rs[j] == '+') && (rs[j-1] == 'e' || rs[j-1] == 'E')) {
+				j++
+			}
+			toks = append(toks, token{tNum, string(rs[i:j]), i})
+			i = j
+		case r == '\'' || r == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(rs); j++ {
+				if rs[j] == r {
+					// 连续两个引号表示引号本身
+					if j+1 < len(rs) && rs[j+1] == r {
+						sb.WriteRune(r)
+						j++
+						continue
+					}
+					break
+				}
+				sb.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("unterminated string at position %d", i+1)
+			}
+			toks = append(toks, token{tStr, sb.String(), i})
+			i = j + 1
+		case r == '[':
+			j := i + 1
+			for j < len(rs) && rs[j] != ']' {
+				j++
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("unterminated [column] at position %d", i+1)
+			}
+			toks = append(toks, token{tColumn, string(rs[i+1 : j]), i})
+			i = j + 1
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_' || rs[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{tIdent, string(rs[i:j]), i})
+			i = j
+		case r == '(':
+			toks = append(toks, token{tLParen, "(", i})
+			i++
+		case r == ')':
+			toks = append(toks, token{tRParen, ")", i})
+			i++
+		case r == ',':
+			toks = append(toks, token{tComma, ",", i})
+			i++
+		default:
+			op := ""
+			for _, o := range twoCharOps {
+				if strings.HasPrefix(string(rs[i:min(i+2, len(rs))]), o) {
+					op = o
+					break
+				}
+			}
+			if op == "" && strings.ContainsRune("+-*/%<>=!&", r) {
+				op = string(r)
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at position %d", r, i+1)
+			}
+			toks = append(toks, token{tOp, op, i})
+			i += len([]rune(op))
+		}
+	}
+	return append(toks, token{tEOF, "end of expression", len(rs)}), nil
+}
+
+type parser struct {
+	toks   []token
+	i      int
+	header []string
+	deps   map[int]bool
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tEOF {
+		p.i++
+	}
+	return t
+}
+
+// keyword 当前记号是否为不区分大小写的关键字或运算符之一，是则消费它
+func (p *parser) keyword(words ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tOp && t.kind != tIdent {
+		return "", false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			p.next()
+			return w, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseOr() (node, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.keyword("or", "||"); !ok {
+			return l, nil
+		}
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = &logical{or: true, l: l, r: r}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.keyword("and", "&&"); !ok {
+			return l, nil
+		}
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l = &logical{l: l, r: r}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.keyword("not", "!"); ok {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &not{x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	l, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.keyword("==", "=", "!=", "<>", "<=", ">=", "<", ">")
+	if !ok {
+		return l, nil
+	}
+	r, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	return &binary{op: op, l: l, r: r}, nil
+}
+
+func (p *parser) parseAdd() (node, error) {
+	l, err := p.parseMul()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.keyword("+", "-", "&")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseMul()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) parseMul() (node, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.keyword("*", "/", "%")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.keyword("-"); ok {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &binary{op: "-", l: literal{num(0)}, r: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tNum:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return literal{num(f)}, nil
+	case tStr:
+		return literal{Value{Kind: String, Str: t.text}}, nil
+	case tColumn:
+		return p.column(t.text)
+	case tLParen:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tRParen {
+			return nil, fmt.Errorf("missing ) for ( at position %d", t.pos+1)
+		}
+		return x, nil
+	case tIdent:
+		if p.peek().kind == tLParen {
+			p.next()
+			return p.call(t)
+		}
+		switch strings.ToLower(t.text) {
+		case "true":
+			return literal{boolean(true)}, nil
+		case "false":
+			return literal{boolean(false)}, nil
+		case "null":
+			return literal{}, nil
+		}
+		return p.column(t.text)
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+}
+
+// column 解析列引用：先精确匹配，再忽略大小写匹配
+func (p *parser) column(name string) (node, error) {
+	for _, exact := range []bool{true, false} {
+		for i, h := range p.header {
+			if h == name || !exact && strings.EqualFold(h, name) {
+				p.deps[i] = true
+				return column(i), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown column %q", name)
+}
+
+func (p *parser) call(name token) (node, error) {
+	var args []node
+	if p.peek().kind == tRParen {
+		p.next()
+	} else {
+		for {
+			a, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, a)
+			t := p.next()
+			if t.kind == tRParen {
+				break
+			}
+			if t.kind != tComma {
+				return nil, fmt.Errorf("expected , or ) at position %d", t.pos+1)
+			}
+		}
+	}
+	fname := strings.ToLower(name.text)
+	switch fname {
+	case "if":
+		if len(args) < 2 || len(args) > 3 {
+			return nil, fmt.Errorf("if takes 2 or 3 arguments")
+		}
+		return &ifNode{args: args}, nil
+	case "coalesce":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("coalesce needs at least one argument")
+		}
+		return coalesce(args), nil
+	}
+	f, ok := functions[fname]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name.text)
+	}
+	if len(args) < f.min || f.max >= 0 && len(args) > f.max {
+		return nil, fmt.Errorf("%s: wrong number of arguments (%d)", fname, len(args))
+	}
+	return &call{name: fname, fn: f.call, args: args}, nil
+}
+
+// node 语法树节点
+type node interface {
+	eval(rec []string) (Value, error)
+}
+
+type literal struct{ v Value }
+
+func (l literal) eval([]string) (Value, error) { return l.v, nil }
+
+type column int
+
+func (c column) eval(rec []string) (Value, error) {
+	if int(c) < len(rec) {
+		return str(rec[c]), nil
+	}
+	return Value{}, nil
+}
+
+type not struct{ x node }
+
+func (n *not) eval(rec []string) (Value, error) {
+	v, err := n.x.eval(rec)
+	if err != nil {
+		return Value{}, err
+	}
+	return boolean(!v.Truthy()), nil
+}
+
+// logical and/or 短路求值
+type logical struct {
+	or   bool
+	l, r node
+}
+
+func (n *logical) eval(rec []string) (Value, error) {
+	l, err := n.l.eval(rec)
+	if err != nil {
+		return Value{}, err
+	}
+	if l.Truthy() == n.or {
+		return boolean(n.or), nil
+	}
+	r, err := n.r.eval(rec)
+	if err != nil {
+		return Value{}, err
+	}
+	return boolean(r.Truthy()), nil
+}
+
+type binary struct {
+	op   string
+	l, r node
+}
+
+func (n *binary) eval(rec []string) (Value, error) {
+	l, err := n.l.eval(rec)
+	if err != nil {
+		return Value{}, err
+	}
+	r, err := n.r.eval(rec)
+	if err != nil {
+		return Value{}, err
+	}
+	switch n.op {
+	case "&":
+		return str(l.String() + r.String()), nil
+	case "==", "=":
+		return boolean(equal(l, r)), nil
+	case "!=", "<>":
+		return boolean(!equal(l, r)), nil
+	case "<", "<=", ">", ">=":
+		if l.Kind == Null || r.Kind == Null {
+			return boolean(false), nil
+		}
+		c := compare(l, r)
+		switch n.op {
+		case "<":
+			return boolean(c < 0), nil
+		case "<=":
+			return boolean(c <= 0), nil
+		case ">":
+			return boolean(c > 0), nil
+		}
+		return boolean(c >= 0), nil
+	}
+	return arith(n.op, l, r)
+}
+
+func equal(l, r Value) bool {
+	if l.Kind == Null || r.Kind == Null {
+		return l.Kind == r.Kind
+	}
+	return compare(l, r) == 0
+}
+
+// arith 算术运算；空值参与运算结果为空，日期加减数字按天计算，两个日期相减得到天数，
+// 不能转成数字的两个字符串相加时连接
+func arith(op string, l, r Value) (Value, error) {
+	if l.Kind == Null || r.Kind == Null {
+		return Value{}, nil
+	}
+	if l.Kind == Time && (op == "+" || op == "-") {
+		if r.Kind == Time && op == "-" {
+			return num(l.Time.Sub(r.Time).Hours() / 24), nil
+		}
+		d, err := r.asNumber()
+		if err != nil {
+			return Value{}, err
+		}
+		if op == "-" {
+			d = -d
+		}
+		return timeVal(addDays(l.Time, d)), nil
+	}
+	x, errl := l.asNumber()
+	y, errr := r.asNumber()
+	if op == "+" && (errl != nil || errr != nil) && l.Kind == String && r.Kind == String {
+		return str(l.Str + r.Str), nil
+	}
+	if errl != nil {
+		return Value{}, errl
+	}
+	if errr != nil {
+		return Value{}, errr
+	}
+	switch op {
+	case "+":
+		return num(x + y), nil
+	case "-":
+		return num(x - y), nil
+	case "*":
+		return num(x * y), nil
+	case "/":
+		if y == 0 {
+			return Value{}, fmt.Errorf("division by zero")
+		}
+		return num(x / y), nil
+	case "%":
+		if y == 0 {
+			return Value{}, fmt.Errorf("division by zero")
+		}
+		// 按浮点取余，除数是小数（如 0.5）时也不会截断成 0
+		return num(math.Mod(x, y)), nil
+	}
+	return Value{}, fmt.Errorf("unknown operator %s", op)
+}
+
+type ifNode struct{ args []node }
+
+func (n *ifNode) eval(rec []string) (Value, error) {
+	c, err := n.args[0].eval(rec)
+	if err != nil {
+		return Value{}, err
+	}
+	if c.Truthy() {
+		return n.args[1].eval(rec)
+	}
+	if len(n.args) == 3 {
+		return n.args[2].eval(rec)
+	}
+	return Value{}, nil
+}
+
+// coalesce 返回第一个非空的参数
+type coalesce []node
+
+func (n coalesce) eval(rec []string) (Value, error) {
+	for _, a := range n {
+		v, err := a.eval(rec)
+		if err != nil {
+			return Value{}, err
+		}
+		if v.Kind != Null {
+			return v, nil
+		}
+	}
+	return Value{}, nil
+}
+
+type call struct {
+	name string
+	fn   func(args []Value) (Value, error)
+	args []node
+}
+
+func (n *call) eval(rec []string) (Value, error) {
+	vals := make([]Value, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(rec)
+		if err != nil {
+			return Value{}, err
+		}
+		vals[i] = v
+	}
+	v, err := n.fn(vals)
+	if err != nil {
+		return Value{}, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,285 @@
+package expr
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// function 内置函数，max < 0 表示参数个数不限
+type function struct {
+	min, max int
+	call     func(args []Value) (Value, error)
+}
+
+// Functions 内置函数名，用于界面上的提示
+func Functions() []string {
+	names := []string{"if", "coalesce"}
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var functions = map[string]function{
+	// 字符串
+	"upper": {1, 1, stringFn(strings.ToUpper)},
+	"lower": {1, 1, stringFn(strings.ToLower)},
+	"trim":  {1, 1, stringFn(strings.TrimSpace)},
+	"len": {1, 1, func(a []Value) (Value, error) {
+		return num(float64(utf8.RuneCountInString(a[0].String()))), nil
+	}},
+	"substr": {2, 3, func(a []Value) (Value, error) {
+		rs := []rune(a[0].String())
+		start, err := a[1].asNumber()
+		if err != nil {
+			return Value{}, err
+		}
+		from := min(max(int(start)-1, 0), len(rs))
+		to := len(rs)
+		if len(a) == 3 {
+			n, err := a[2].asNumber()
+			if err != nil {
+				return Value{}, err
+			}
+			to = min(from+max(int(n), 0), len(rs))
+		}
+		return str(string(rs[from:to])), nil
+	}},
+	"left": {2, 2, func(a []Value) (Value, error) {
+		rs := []rune(a[0].String())
+		n, err := a[1].asNumber()
+		if err != nil {
+			return Value{}, err
+		}
+		return str(string(rs[:min(max(int(n), 0), len(rs))])), nil
+	}},
+	"right": {2, 2, func(a []Value) (Value, error) {
+		rs := []rune(a[0].String())
+		n, err := a[1].asNumber()
+		if err != nil {
+			return Value{}, err
+		}
+		return str(string(rs[len(rs)-min(max(int(n), 0), len(rs)):])), nil
+	}},
+	"replace": {3, 3, func(a []Value) (Value, error) {
+		return str(strings.ReplaceAll(a[0].String(), a[1].String(), a[2].String())), nil
+	}},
+	"contains": {2, 2, func(a []Value) (Value, error) {
+		return boolean(strings.Contains(a[0].String(), a[1].String())), nil
+	}},
+	"startswith": {2, 2, func(a []Value) (Value, error) {
+		return boolean(strings.HasPrefix(a[0].String(), a[1].String())), nil
+	}},
+	"endswith": {2, 2, func(a []Value) (Value, error) {
+		return boolean(strings.HasSuffix(a[0].String(), a[1].String())), nil
+	}},
+	"concat": {1, -1, func(a []Value) (Value, error) {
+		var sb strings.Builder
+		for _, v := range a {
+			sb.WriteString(v.String())
+		}
+		return str(sb.String()), nil
+	}},
+
+	// 数值
+	"num":   {1, 1, numberFn(func(x float64) float64 { return x })},
+	"abs":   {1, 1, numberFn(math.Abs)},
+	"floor": {1, 1, numberFn(math.Floor)},
+	"ceil":  {1, 1, numberFn(math.Ceil)},
+	"round": {1, 2, func(a []Value) (Value, error) {
+		if a[0].Kind == Null {
+			return Value{}, nil
+		}
+		x, err := a[0].asNumber()
+		if err != nil {
+			return Value{}, err
+		}
+		digits := 0.0
+		if len(a) == 2 {
+			if digits, err = a[1].asNumber(); err != nil {
+				return Value{}, err
+			}
+		}
+		p := math.Pow(10, digits)
+		return num(math.Round(x*p) / p), nil
+	}},
+	"min": {1, -1, extremeFn(-1)},
+	"max": {1, -1, extremeFn(1)},
+
+	// 日期
+	"date": {1, 1, func(a []Value) (Value, error) {
+		if a[0].Kind == Null {
+			return Value{}, nil
+		}
+		t, err := a[0].asTime()
+		return timeVal(t), err
+	}},
+	"today": {0, 0, func([]Value) (Value, error) {
+		y, m, d := time.Now().Date()
+		return timeVal(time.Date(y, m, d, 0, 0, 0, 0, time.UTC)), nil
+	}},
+	"now": {0, 0, func([]Value) (Value, error) {
+		return timeVal(time.Now().UTC().Truncate(time.Second)), nil
+	}},
+	"year":  {1, 1, datePartFn(func(t time.Time) int { return t.Year() })},
+	"month": {1, 1, datePartFn(func(t time.Time) int { return int(t.Month()) })},
+	"day":   {1, 1, datePartFn(func(t time.Time) int { return t.Day() })},
+	"weekday": {1, 1, datePartFn(func(t time.Time) int {
+		// 周一为 1，周日为 7
+		return (int(t.Weekday())+6)%7 + 1
+	})},
+	"date_diff": {2, 2, func(a []Value) (Value, error) {
+		if a[0].Kind == Null || a[1].Kind == Null {
+			return Value{}, nil
+		}
+		x, err := a[0].asTime()
+		if err != nil {
+			return Value{}, err
+		}
+		y, err := a[1].asTime()
+		if err != nil {
+			return Value{}, err
+		}
+		return num(math.Floor(x.Sub(y).Hours() / 24)), nil
+	}},
+	"date_add": {2, 2, func(a []Value) (Value, error) {
+		if a[0].Kind == Null {
+			return Value{}, nil
+		}
+		t, err := a[0].asTime()
+		if err != nil {
+			return Value{}, err
+		}
+		d, err := a[1].asNumber()
+		if err != nil {
+			return Value{}, err
+		}
+		return timeVal(addDays(t, d)), nil
+	}},
+
+	// 条件
+	"isnull": {1, 1, func(a []Value) (Value, error) { return boolean(a[0].Kind == Null), nil }},
+
+	// 正则
+	"matches": {2, 2, func(a []Value) (Value, error) {
+		re, err := compileRegexp(a[1].String())
+		if err != nil {
+			return Value{}, err
+		}
+		return boolean(re.MatchString(a[0].String())), nil
+	}},
+	"regex_extract": {2, 3, func(a []Value) (Value, error) {
+		re, err := compileRegexp(a[1].String())
+		if err != nil {
+			return Value{}, err
+		}
+		group := 0.0
+		if len(a) == 3 {
+			if group, err = a[2].asNumber(); err != nil {
+				return Value{}, err
+			}
+		}
+		m := re.FindStringSubmatch(a[0].String())
+		if int(group) < 0 || int(group) >= len(m) {
+			return Value{}, nil
+		}
+		return str(m[int(group)]), nil
+	}},
+	"regex_replace": {3, 3, func(a []Value) (Value, error) {
+		re, err := compileRegexp(a[1].String())
+		if err != nil {
+			return Value{}, err
+		}
+		return str(re.ReplaceAllString(a[0].String(), a[2].String())), nil
+	}},
+}
+
+func stringFn(f func(string) string) func([]Value) (Value, error) {
+	return func(a []Value) (Value, error) {
+		if a[0].Kind == Null {
+			return Value{}, nil
+		}
+		return str(f(a[0].String())), nil
+	}
+}
+
+func numberFn(f func(float64) float64) func([]Value) (Value, error) {
+	return func(a []Value) (Value, error) {
+		if a[0].Kind == Null {
+			return Value{}, nil
+		}
+		x, err := a[0].asNumber()
+		if err != nil {
+			return Value{}, err
+		}
+		return num(f(x)), nil
+	}
+}
+
+// extremeFn 参数中的最小（sign < 0）或最大值，忽略空值
+func extremeFn(sign int) func([]Value) (Value, error) {
+	return func(a []Value) (Value, error) {
+		var best Value
+		for _, v := range a {
+			if v.Kind == Null {
+				continue
+			}
+			if best.Kind == Null || compare(v, best)*sign > 0 {
+				best = v
+			}
+		}
+		return best, nil
+	}
+}
+
+func datePartFn(f func(time.Time) int) func([]Value) (Value, error) {
+	return func(a []Value) (Value, error) {
+		if a[0].Kind == Null {
+			return Value{}, nil
+		}
+		t, err := a[0].asTime()
+		if err != nil {
+			return Value{}, err
+		}
+		return num(float64(f(t))), nil
+	}
+}
+
+// addDays 加上若干天，整数天按日历日期计算，小数部分按小时
+func addDays(t time.Time, d float64) time.Time {
+	whole := math.Trunc(d)
+	return t.AddDate(0, 0, int(whole)).Add(time.Duration((d - whole) * float64(24*time.Hour)))
+}
+
+// 正则表达式按模式缓存，表达式对每一行求值时不必重复编译
+var (
+	regexMu    sync.Mutex
+	regexCache = make(map[string]*regexp.Regexp)
+)
+
+// maxCachedRegexps 缓存的正则数量上限，模式来自单元格时可能很多
+const maxCachedRegexps = 256
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	regexMu.Lock()
+	defer regexMu.Unlock()
+	if re, ok := regexCache[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.New("invalid regular expression: " + err.Error())
+	}
+	if len(regexCache) >= maxCachedRegexps {
+		clear(regexCache)
+	}
+	regexCache[pattern] = re
+	return re, nil
+}
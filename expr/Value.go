@@ -0,0 +1,142 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devbiu/CsvView/loader"
+)
+
+// Kind 值的类型
+type Kind int
+
+const (
+	Null Kind = iota
+	String
+	Number
+	Bool
+	Time
+)
+
+// Value 表达式求值的结果。单元格读入时是字符串（空单元格为 Null），
+// 参与运算时按需转换成数字、日期或布尔值
+type Value struct {
+	Kind Kind
+	Str  string
+	Num  float64
+	Bool bool
+	Time time.Time
+}
+
+func str(s string) Value {
+	if s == "" {
+		return Value{}
+	}
+	return Value{Kind: String, Str: s}
+}
+
+func num(f float64) Value       { return Value{Kind: Number, Num: f} }
+func boolean(b bool) Value      { return Value{Kind: Bool, Bool: b} }
+func timeVal(t time.Time) Value { return Value{Kind: Time, Time: t} }
+
+// String 格式化为单元格文本
+func (v Value) String() string {
+	switch v.Kind {
+	case String:
+		return v.Str
+	case Number:
+		if math.Abs(v.Num) < 1e15 && v.Num == math.Trunc(v.Num) {
+			return strconv.FormatInt(int64(v.Num), 10)
+		}
+		return strconv.FormatFloat(v.Num, 'f', -1, 64)
+	case Bool:
+		return strconv.FormatBool(v.Bool)
+	case Time:
+		if h, m, s := v.Time.Clock(); h == 0 && m == 0 && s == 0 && v.Time.Nanosecond() == 0 {
+			return v.Time.Format("2006-01-02")
+		}
+		return v.Time.Format("2006-01-02 15:04:05")
+	}
+	return ""
+}
+
+// Truthy 作为条件时的真假：空值为假，字符串按 true/yes/false/no 解析，其余非空即真
+func (v Value) Truthy() bool {
+	switch v.Kind {
+	case Bool:
+		return v.Bool
+	case Number:
+		return v.Num != 0
+	case String:
+		if b, ok := loader.ParseBool(v.Str); ok {
+			return b
+		}
+		return v.Str != "0"
+	case Time:
+		return !v.Time.IsZero()
+	}
+	return false
+}
+
+// asNumber 转换为数字
+func (v Value) asNumber() (float64, error) {
+	switch v.Kind {
+	case Number:
+		return v.Num, nil
+	case Bool:
+		if v.Bool {
+			return 1, nil
+		}
+		return 0, nil
+	case String:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v.Str), 64)
+		if err != nil {
+			return 0, fmt.Errorf("not a number: %q", v.Str)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("not a number: %s", v)
+}
+
+// asTime 转换为日期时间，字符串按常见日期和时间戳格式解析
+func (v Value) asTime() (time.Time, error) {
+	switch v.Kind {
+	case Time:
+		return v.Time, nil
+	case String:
+		s := strings.TrimSpace(v.Str)
+		if t, ok := loader.ParseDate(s); ok {
+			return t, nil
+		}
+		if t, ok := loader.ParseTimestamp(s); ok {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("not a date: %q", v.String())
+}
+
+// compare 比较两个值：都能转成数字时按数值，都能转成日期时按时间，否则按文本
+func compare(a, b Value) int {
+	if x, err := a.asNumber(); err == nil {
+		if y, err := b.asNumber(); err == nil {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	if a.Kind == Time || b.Kind == Time {
+		if x, err := a.asTime(); err == nil {
+			if y, err := b.asTime(); err == nil {
+				return x.Compare(y)
+			}
+		}
+	}
+	return strings.Compare(a.String(), b.String())
+}
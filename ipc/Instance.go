@@ -0,0 +1,133 @@
+// Package ipc 实现单实例模式：第一个启动的进程在本地 Unix domain socket 上监听，
+// 之后启动的进程把要打开的文件转发给它然后退出。
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Request 一次打开文件请求：路径以及打开后跳转的位置（从 1 开始，0 表示不跳转）
+type Request struct {
+	Path string `json:"path"`
+	Row  int    `json:"row,omitempty"`
+	Col  int    `json:"col,omitempty"`
+}
+
+const dialTimeout = 500 * time.Millisecond
+
+// SocketPath 返回当前用户的实例 socket 路径
+func SocketPath() string {
+	name := fmt.Sprintf("csvview-%d.sock", os.Getuid())
+	if dir, err := os.UserCacheDir(); err == nil {
+		if err := os.MkdirAll(filepath.Join(dir, "csvview"), 0o700); err == nil {
+			return filepath.Join(dir, "csvview", name)
+		}
+	}
+	return filepath.Join(os.TempDir(), name)
+}
+
+// Forward 尝试把请求转发给已运行的实例。
+// 返回 nil 表示已有实例接管了请求，当前进程可以直接退出。
+func Forward(reqs []Request) error {
+	conn, err := net.DialTimeout("unix", SocketPath(), dialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	enc := json.NewEncoder(conn)
+	for _, r := range reqs {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	// 空请求也要发送一行，让已运行的实例把窗口置前
+	if len(reqs) == 0 {
+		if err := enc.Encode(Request{}); err != nil {
+			return err
+		}
+	}
+	if c, ok := conn.(*net.UnixConn); ok {
+		_ = c.CloseWrite()
+	}
+	// 等待对方确认
+	ack, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if ack != "ok\n" {
+		return errors.New("unexpected reply from running instance")
+	}
+	return nil
+}
+
+// Server 主实例上的监听器
+type Server struct {
+	ln   net.Listener
+	path string
+}
+
+// Listen 在实例 socket 上监听，每收到一个请求就调用 handle（在后台 goroutine 中）。
+// 如果已经有实例在监听则返回错误。
+func Listen(handle func(Request)) (*Server, error) {
+	path := SocketPath()
+	if conn, err := net.DialTimeout("unix", path, dialTimeout); err == nil {
+		conn.Close()
+		return nil, errors.New("another instance is already running")
+	}
+	// 上次异常退出遗留的 socket 文件
+	_ = os.Remove(path)
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{ln: ln, path: path}
+	go s.serve(handle)
+	return s, nil
+}
+
+func (s *Server) serve(handle func(Request)) {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Println("ipc accept error:", err)
+			}
+			return
+		}
+		go s.handleConn(conn, handle)
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn, handle func(Request)) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	dec := json.NewDecoder(conn)
+	for {
+		var r Request
+		if err := dec.Decode(&r); err != nil {
+			break
+		}
+		handle(r)
+	}
+	if _, err := conn.Write([]byte("ok\n")); err != nil {
+		log.Println("ipc reply error:", err)
+	}
+}
+
+// Close 停止监听并删除 socket 文件
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	_ = os.Remove(s.path)
+	return err
+}
@@ -2,201 +2,280 @@ package loader
 
 import (
 	"bufio"
-	"bytes"
 	"encoding/csv"
+	"errors"
 	"io"
+	"log"
 	"os"
+	"path/filepath"
 	"strings"
-	"time"
 )
 
+// smallFileSize 以下的文件一次性读入内存，不再构建偏移表
+const smallFileSize = 8 << 20
+
+// NewCsvLoaderV2 打开 CSV 文件。
+// 小文件直接读入缓存；大文件在后台构建记录偏移表，按需读取可见窗口附近的行，
+// 内存占用只和 cacheCap 有关，与文件大小无关。
 func NewCsvLoaderV2(path string, cacheCap int) (*CSVLoader, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if st.IsDir() {
+		f.Close()
+		return nil, errors.New(path + " is a directory")
+	}
 
 	l := &CSVLoader{
 		Path:      path,
 		f:         f,
 		Cache:     make(map[int][]string),
-		cap:       cacheCap,
+		cap:       max(cacheCap, 64),
 		edits:     make(map[int]map[int]string),
 		requestCh: make(chan int, 100),
 		stopCh:    make(chan struct{}),
 		Offsets:   make([]int64, 0),
 		OffBuilt:  false,
+		Comma:     detectComma(path, f),
+		size:      st.Size(),
+		modTime:   st.ModTime(),
 
 		CacheStart:  0,
 		CacheEnd:    0,
 		ActiveIndex: 0,
-		maxMemory:   1 * 1024, // default 1mb
-		loadRatio:   0.3,
-		cleanRatio:  0.5,
-	}
-
-	// 后台完整构建偏移（非阻塞）
-	//go l.buildOffsetsAsyncV2()
-	l.buildOffsetsAsyncV2(true)
-	//go func() {
-	//	for {
-	//		time.Sleep(1 * time.Second)
-	//		l.TryRLock()
-	//		num := 0
-	//		for i := range l.Cache {
-	//			num += cap(l.Cache[i])
-	//		}
-	//		l.Mu.RUnlock()
-	//		log.Printf("initial cache built, total rows: %d, total memory used: %d bytes\n", len(l.Cache), num)
-	//		time.Sleep(1 * time.Second)
-	//	}
-	//}()
+	}
+
+	l.reopen = func() (RowSource, error) { return NewCsvLoaderV2(path, cacheCap) }
+
+	if st.Size() <= smallFileSize {
+		if err := l.loadAll(); err != nil {
+			f.Close()
+			return nil, err
+		}
+		go l.watchChanges()
+		return l, nil
+	}
+
+	start := bomLen(f)
+	l.Offsets = append(l.Offsets, start)
+	// 先同步扫描第一个块，保证打开后立即能看到第一屏
+	if _, err := scanRecords(f, start, true, func(offs, nl []int64) bool {
+		l.Offsets = append(l.Offsets, offs...)
+		l.addQuotedNL(nl)
+		return len(offs) == 0
+	}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, _, err := l.loadWindow(0); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if first, ok := l.Cache[0]; ok {
+		l.header = first
+		l.cols = len(first)
+	}
+
+	l.start()
 	return l, nil
 }
 
-func (l *CSVLoader) buildOffsetsAsyncV2(next bool) {
-	l.TryLock()
-	//defer l.Mu.Unlock()
-	r := bufio.NewReader(l.f)
-	use := 0
-	// TODO 往上读如何解决
-	for i := l.CacheEnd; ; {
-		var buf bytes.Buffer
-		line, _, err := r.ReadLine()
-		if err != nil && err != io.EOF {
-			l.ErrMsg = err.Error()
-			return
-		}
+// start 启动后台偏移表构建和按需加载（非阻塞）
+func (l *CSVLoader) start() {
+	go l.buildOffsetsAsyncV2()
+	go l.requestLoop()
+	go l.watchChanges()
+}
+
+// loadAll 小文件：一次性解析全部记录放入缓存
+func (l *CSVLoader) loadAll() error {
+	if _, err := l.f.Seek(bomLen(l.f), io.SeekStart); err != nil {
+		return err
+	}
+	cr := csv.NewReader(bufio.NewReader(l.f))
+	cr.Comma = l.Comma
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = false
+	for i := 0; ; i++ {
+		record, err := cr.Read()
 		if err == io.EOF {
-			l.OffBuilt = true
 			break
 		}
-		buf.Write(line)
-		// TODO 处理多行字段/异步处理
-		record, err := csv.NewReader(bytes.NewReader(buf.Bytes())).Read()
 		if err != nil {
-			l.Cache[int(i)] = []string{strings.TrimSpace(buf.String())}
-		} else {
-			l.Cache[int(i)] = record
-		}
-		//l.CacheEnd = i + 1
-		use += cap(l.Cache[int(i)])
-		if float64(use)/float64(l.maxMemory) > l.loadRatio {
-			break
-		}
-		if next {
-			i++
-			l.CacheEnd += 1
-		} else {
-			i--
-			l.CacheStart -= 1
+			return err
 		}
-
+		line, _ := cr.FieldPos(0)
+		l.noteLineLocked(i, line)
+		l.Cache[i] = record
+		l.cols = max(l.cols, len(record))
 	}
-	l.Mu.Unlock()
-	//// 添加完毕之后清理内存
-	//ratio := l.adaptiveTrimRatio()
-	//if ratio > 0 {
-	//	l.trimCache(ratio)
-	//}
+	l.header = l.Cache[0]
+	l.inMemory = true
+	l.OffBuilt = true
+	l.rows = len(l.Cache)
+	l.TotalRow = l.rows
+	l.CacheEnd = int64(l.rows)
+	return nil
 }
 
-// TODO 清理的有问题
-func (l *CSVLoader) trimCache(ratio float64) {
-	l.TryRLock()
-	// 计算需要清理的行数
-	numToTrim := int(float64(l.CacheEnd-l.CacheStart) * ratio)
+// buildOffsetsAsyncV2 后台扫描整个文件建立记录偏移表
+func (l *CSVLoader) buildOffsetsAsyncV2() {
+	l.Mu.RLock()
+	start := l.Offsets[len(l.Offsets)-1]
 	l.Mu.RUnlock()
-	if numToTrim <= 0 {
-		return
-	}
-	// 清理最早的行
-	l.TryLock()
-	defer l.Mu.Unlock()
-	cut := int64(float64(len(l.Cache)) / ratio)
-	if l.ActiveIndex-l.CacheStart > l.CacheEnd-l.ActiveIndex {
-		//// 活跃行在后半部分，清理前半部分
-		for i := l.CacheStart; i < l.CacheStart+cut; i++ {
-			delete(l.Cache, int(i))
+
+	end, err := scanRecords(l.f, start, l.quoted(), func(offs, nl []int64) bool {
+		select {
+		case <-l.stopCh:
+			return false
+		default:
 		}
-		l.CacheStart += cut
-	} else {
-		// 活跃行在前半部分，清理后半部分
-		for i := l.CacheEnd - cut; i < l.CacheEnd; i++ {
-			delete(l.Cache, int(i))
+		l.Mu.Lock()
+		if l.invalid {
+			l.Mu.Unlock()
+			return false
 		}
-		l.CacheEnd -= cut
-	}
+		l.Offsets = append(l.Offsets, offs...)
+		l.addQuotedNL(nl)
+		scanned := l.Offsets[len(l.Offsets)-1]
+		l.Mu.Unlock()
+		l.publishProgress(scanned, false)
+		return true
+	})
 
-}
-
-func (l *CSVLoader) adaptiveTrimRatio() float64 {
-	l.TryRLock()
-	defer l.Mu.RUnlock()
-	use := 0
-	// TODO 取消遍历， 通过其他方式获取内存占用
-	for i := l.CacheStart; i < l.CacheEnd; i++ {
-		use += cap(l.Cache[int(i)])
-	}
-	ratio := float64(use) / float64(l.maxMemory)
-	switch {
-	case ratio < l.loadRatio:
-		return -2.0
-	case ratio < 1-l.loadRatio:
-		return 0.0
-	case ratio < 1.1:
-		return l.cleanRatio / 2
-	case ratio < 1+l.loadRatio:
-		return l.cleanRatio
+	select {
+	case <-l.stopCh:
+		return
 	default:
-		return l.cleanRatio * 2
 	}
+	l.Mu.Lock()
+	if l.invalid {
+		// 文件被改写，扫描到的偏移不可信，保持未完成状态直到重新打开
+		l.Mu.Unlock()
+		return
+	}
+	if err != nil {
+		l.ErrMsg = err.Error()
+		log.Println("buildOffsetsAsyncV2 error:", err)
+		defer l.publish(Event{Kind: EventError, Err: err})
+	}
+	// 最后一条记录没有换行结尾
+	if last := l.Offsets[len(l.Offsets)-1]; end > last {
+		l.Offsets = append(l.Offsets, end)
+	}
+	l.rows = len(l.Offsets) - 1
+	l.TotalRow = l.rows
+	l.OffBuilt = true
+	l.Mu.Unlock()
+	log.Println("buildOffsetsAsyncV2 完成， 总行数：", l.rows)
+	l.publishProgress(end, true)
 }
 
-func (l *CSVLoader) readRowByOffsetNoCacheV2() {
-	//var line
-	//l.TryRLock()
-	//csv.NewReader(bytes.NewReader())
-}
-
-func (l *CSVLoader) TryRLock() {
-	for !l.Mu.TryRLock() {
-		time.Sleep(time.Millisecond * 10)
+// requestLoop 处理按需读取请求：合并排队中的请求，只加载最近请求的窗口
+func (l *CSVLoader) requestLoop() {
+	for {
+		select {
+		case row := <-l.requestCh:
+			rows := []int{row}
+		drain:
+			for {
+				select {
+				case r := <-l.requestCh:
+					rows = append(rows, r)
+				default:
+					break drain
+				}
+			}
+			// 最后的请求最能代表当前可见区域，优先加载
+			for i := len(rows) - 1; i >= 0; i-- {
+				l.Mu.RLock()
+				_, ok := l.Cache[rows[i]]
+				l.Mu.RUnlock()
+				if ok {
+					continue
+				}
+				from, to, err := l.loadWindow(rows[i])
+				if err != nil {
+					l.Mu.Lock()
+					l.ErrMsg = err.Error()
+					l.Mu.Unlock()
+					log.Println("loadWindow error:", err)
+					l.publish(Event{Kind: EventError, Err: err})
+					continue
+				}
+				// 记录号转换为数据行号（不含表头）
+				if to > from {
+					l.publish(Event{Kind: EventRowsLoaded, From: max(from-1, 0), To: to - 1})
+				}
+			}
+		case <-l.stopCh:
+			return
+		}
 	}
 }
 
-func (l *CSVLoader) TryLock() {
-	for !l.Mu.TryLock() {
-		time.Sleep(time.Millisecond * 10)
+// loadWindow 读取 row 附近的一段记录放入缓存，并淘汰离 row 较远的行。
+// 返回实际加载的记录范围 [from, to)
+func (l *CSVLoader) loadWindow(row int) (from, to int, err error) {
+	half := l.cap / 4
+	l.Mu.RLock()
+	count := l.recordCountLocked()
+	if row >= count || l.invalid {
+		l.Mu.RUnlock()
+		return 0, 0, nil
 	}
-}
+	from = max(row-half, 0)
+	to = min(from+2*half, count)
+	offs := append([]int64(nil), l.Offsets[from:to+1]...)
+	l.Mu.RUnlock()
 
-func (l *CSVLoader) TryLockFunc(f func() bool) bool {
-	l.TryRLock()
-	defer l.Mu.RUnlock()
-	return f()
-}
+	buf := make([]byte, offs[len(offs)-1]-offs[0])
+	if _, err := l.f.ReadAt(buf, offs[0]); err != nil && err != io.EOF {
+		return 0, 0, err
+	}
+	records := make([][]string, 0, to-from)
+	for i := range to - from {
+		records = append(records, l.parseRecord(buf[offs[i]-offs[0]:offs[i+1]-offs[0]]))
+	}
 
-func (l *CSVLoader) GetRowV2(row int) ([]string, bool) {
-	l.TryRLock()
-	defer l.Mu.RUnlock()
-
-	update := false
-	if row > 0 {
-		// 判断是否需要加载更多数据
-		if int64(row) > l.ActiveIndex && row >= int(float64(l.CacheEnd)*0.8) {
-			go l.buildOffsetsAsyncV2(true)
-			update = true
-		} else if int64(row) < l.ActiveIndex && row <= int(float64(l.CacheStart)*0.2) {
-			go l.buildOffsetsAsyncV2(false)
-			update = true
+	l.Mu.Lock()
+	defer l.Mu.Unlock()
+	for i, r := range records {
+		l.Cache[from+i] = r
+	}
+	if len(l.Cache) > l.cap {
+		for k := range l.Cache {
+			if k < row-l.cap/2 || k >= row+l.cap/2 {
+				delete(l.Cache, k)
+			}
 		}
 	}
+	l.CacheStart = int64(from)
+	l.CacheEnd = int64(to)
+	return from, to, nil
+}
 
-	if data, ok := l.Cache[row]; ok {
-		// TODO 是否需要替换为写锁
-		l.ActiveIndex = int64(row)
-		return data, update
+// detectComma 根据扩展名和第一行内容推断分隔符
+func detectComma(path string, f *os.File) rune {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tsv", ".tab":
+		return '\t'
+	}
+	head := make([]byte, 64*1024)
+	n, _ := f.ReadAt(head, 0)
+	line, _, _ := strings.Cut(string(head[:n]), "\n")
+	best, bestN := ',', 0
+	for _, c := range []rune{',', '\t', ';', '|'} {
+		if n := strings.Count(line, string(c)); n > bestN {
+			best, bestN = c, n
+		}
 	}
-	return nil, update
+	return best
 }
@@ -0,0 +1,72 @@
+package loader
+
+import "sync"
+
+// EventKind 数据源事件类型
+type EventKind int
+
+const (
+	// EventRowsLoaded [From, To) 范围内的行已加载或内容发生变化
+	EventRowsLoaded EventKind = iota
+	// EventIndexProgress 索引构建进度，Progress 取值 0~1
+	EventIndexProgress
+	// EventRowCountChanged 行数变化，Rows 为新的行数
+	EventRowCountChanged
+	// EventError 后台读取出错
+	EventError
+	// EventFileReplaced 文件被截断或替换（如日志轮转），已有的偏移不再有效，需要重新打开
+	EventFileReplaced
+	// EventFileChanged 文件被其他程序修改，界面提示用户重新打开
+	EventFileChanged
+)
+
+// Event 数据源发布给视图的事件
+type Event struct {
+	Kind     EventKind
+	From     int // EventRowsLoaded: 起始行（含）
+	To       int // EventRowsLoaded: 结束行（不含）
+	Rows     int
+	Progress float64
+	Err      error
+}
+
+// Contains 行是否在事件范围内
+func (e Event) Contains(row int) bool {
+	return row >= e.From && row < e.To
+}
+
+// Notifier 可嵌入数据源的事件发布器，订阅回调在发布者的 goroutine 中调用
+type Notifier struct {
+	subMu sync.Mutex
+	subs  map[int]func(Event)
+	next  int
+}
+
+// Subscribe 订阅事件，返回取消订阅函数
+func (n *Notifier) Subscribe(f func(Event)) func() {
+	n.subMu.Lock()
+	defer n.subMu.Unlock()
+	if n.subs == nil {
+		n.subs = make(map[int]func(Event))
+	}
+	id := n.next
+	n.next++
+	n.subs[id] = f
+	return func() {
+		n.subMu.Lock()
+		defer n.subMu.Unlock()
+		delete(n.subs, id)
+	}
+}
+
+func (n *Notifier) publish(e Event) {
+	n.subMu.Lock()
+	subs := make([]func(Event), 0, len(n.subs))
+	for _, f := range n.subs {
+		subs = append(subs, f)
+	}
+	n.subMu.Unlock()
+	for _, f := range subs {
+		f(e)
+	}
+}
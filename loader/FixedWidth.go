@@ -0,0 +1,250 @@
+package loader
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// FixedWidthSpec 定长文本文件的列布局，可以保存后复用到同一来源的其他文件
+type FixedWidthSpec struct {
+	Name string `json:"name"`
+	// Boundaries 每一列（第一列除外）的起始字符位置，从 0 开始、严格递增
+	Boundaries []int `json:"boundaries"`
+	// Names 列名；为空时使用表头行或 column_N
+	Names []string `json:"names,omitempty"`
+	// HeaderLine 第一行是否为列名
+	HeaderLine bool `json:"header_line"`
+	// Trim 是否去掉字段两端的空白
+	Trim bool `json:"trim"`
+}
+
+// Normalize 去掉重复、非正的边界并排序
+func (s *FixedWidthSpec) Normalize() {
+	sort.Ints(s.Boundaries)
+	out := s.Boundaries[:0]
+	for _, b := range s.Boundaries {
+		if b > 0 && (len(out) == 0 || out[len(out)-1] != b) {
+			out = append(out, b)
+		}
+	}
+	s.Boundaries = out
+}
+
+// Cols 列数
+func (s *FixedWidthSpec) Cols() int {
+	return len(s.Boundaries) + 1
+}
+
+// Split 按边界切分一行；位置按字符（rune）计算，最后一列延伸到行尾
+func (s *FixedWidthSpec) Split(line string) []string {
+	line = strings.TrimRight(line, "\r\n")
+	fields := make([]string, s.Cols())
+	start, col, pos := 0, 0, 0
+	for i := range line {
+		if col < len(s.Boundaries) && pos == s.Boundaries[col] {
+			fields[col] = line[start:i]
+			start = i
+			col++
+		}
+		pos++
+	}
+	if col < len(fields) {
+		fields[col] = line[start:]
+	}
+	if s.Trim {
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+	}
+	return fields
+}
+
+// header 生成列名：优先使用 Names，其次是表头行切分的结果
+func (s *FixedWidthSpec) header(first []string) []string {
+	h := make([]string, s.Cols())
+	for i := range h {
+		switch {
+		case i < len(s.Names) && s.Names[i] != "":
+			h[i] = s.Names[i]
+		case s.HeaderLine && i < len(first) && first[i] != "":
+			h[i] = first[i]
+		default:
+			h[i] = fmt.Sprintf("column_%d", i+1)
+		}
+	}
+	return h
+}
+
+// parseLine 实现 lineParser
+func (s *FixedWidthSpec) parseLine(b []byte) []string {
+	return s.Split(string(b))
+}
+
+// NewFixedWidthLoader 按 spec 打开定长文本文件，复用 CSV 加载器的行偏移表和窗口缓存
+func NewFixedWidthLoader(path string, spec FixedWidthSpec, cacheCap int) (*CSVLoader, error) {
+	spec.Normalize()
+	l, err := newLineLoader(path, &spec, spec.HeaderLine, cacheCap)
+	if err != nil {
+		return nil, err
+	}
+	var first []string
+	if spec.HeaderLine {
+		first = l.Cache[0]
+	}
+	l.header = spec.header(first)
+	l.cols = spec.Cols()
+	l.reopen = func() (RowSource, error) { return NewFixedWidthLoader(path, spec, cacheCap) }
+	l.start()
+	return l, nil
+}
+
+// newLineLoader 打开每行一条记录的文件，同步扫描第一个块并加载第一屏。
+// 没有表头行时插入一条长度为 0 的虚拟记录作为表头，行号与 CSV 保持一致。
+// 调用方设置好表头和列数后调用 start 开始后台索引。
+func newLineLoader(path string, p lineParser, headerLine bool, cacheCap int) (*CSVLoader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if st.IsDir() {
+		f.Close()
+		return nil, errors.New(path + " is a directory")
+	}
+	l := &CSVLoader{
+		Path:      path,
+		f:         f,
+		Cache:     make(map[int][]string),
+		cap:       max(cacheCap, 64),
+		edits:     make(map[int]map[int]string),
+		requestCh: make(chan int, 100),
+		stopCh:    make(chan struct{}),
+		lineFmt:   p,
+		size:      st.Size(),
+		modTime:   st.ModTime(),
+
+		noHeaderLine: !headerLine,
+	}
+
+	start := bomLen(f)
+	l.Offsets = append(l.Offsets, start)
+	if !headerLine {
+		l.Offsets = append(l.Offsets, start)
+	}
+	if _, err := scanOffsets(f, start, false, func(offs []int64) bool {
+		l.Offsets = append(l.Offsets, offs...)
+		return false
+	}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, _, err := l.loadWindow(0); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// ReadSampleLines 读取文件开头最多 n 行，用于在边界编辑器中预览
+func ReadSampleLines(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(io.NewSectionReader(f, bomLen(f), 1<<62))
+	var lines []string
+	for len(lines) < n {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			line = strings.TrimRight(line, "\r\n")
+			if !utf8.ValidString(line) {
+				line = strings.ToValidUTF8(line, "?")
+			}
+			lines = append(lines, line)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return lines, err
+		}
+	}
+	return lines, nil
+}
+
+// fixedWidthSpecDir 保存列布局的目录
+func fixedWidthSpecDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "csvview", "fixedwidth"), nil
+}
+
+// SaveFixedWidthSpec 按名称保存列布局，同名覆盖
+func SaveFixedWidthSpec(spec FixedWidthSpec) error {
+	if spec.Name == "" || strings.ContainsAny(spec.Name, `/\`) {
+		return fmt.Errorf("invalid spec name %q", spec.Name)
+	}
+	dir, err := fixedWidthSpecDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, spec.Name+".json"), b, 0o644)
+}
+
+// LoadFixedWidthSpec 读取已保存的列布局；name 也可以是 JSON 文件路径
+func LoadFixedWidthSpec(name string) (FixedWidthSpec, error) {
+	var spec FixedWidthSpec
+	path := name
+	if !strings.HasSuffix(strings.ToLower(name), ".json") {
+		dir, err := fixedWidthSpecDir()
+		if err != nil {
+			return spec, err
+		}
+		path = filepath.Join(dir, name+".json")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return spec, err
+	}
+	if err := json.Unmarshal(b, &spec); err != nil {
+		return spec, fmt.Errorf("%s: %w", path, err)
+	}
+	spec.Normalize()
+	return spec, nil
+}
+
+// ListFixedWidthSpecs 已保存的列布局名称
+func ListFixedWidthSpecs() []string {
+	dir, err := fixedWidthSpecDir()
+	if err != nil {
+		return nil
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = strings.TrimSuffix(filepath.Base(m), ".json")
+	}
+	return names
+}
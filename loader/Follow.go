@@ -0,0 +1,256 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// followPoll 跟踪时定期检查文件，弥补文件系统通知丢失或合并的情况（如网络盘）
+const followPoll = time.Second
+
+// Follow 开始或停止跟踪文件。跟踪时文件变大就从最后一条完整记录之后继续建立索引，
+// 不重新扫描整个文件；文件被截断或替换时发布 EventFileReplaced 并停止跟踪
+func (l *CSVLoader) Follow(on bool) error {
+	l.Mu.Lock()
+	defer l.Mu.Unlock()
+	if !on {
+		if l.followStop != nil {
+			close(l.followStop)
+			l.followStop = nil
+		}
+		return nil
+	}
+	if l.followStop != nil {
+		return nil
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// 监视所在目录，轮转时文件被改名或重新创建也能收到通知
+	if err := w.Add(filepath.Dir(l.Path)); err != nil {
+		w.Close()
+		return err
+	}
+	l.followStop = make(chan struct{})
+	go l.followLoop(w, l.followStop)
+	return nil
+}
+
+// Following 是否正在跟踪文件
+func (l *CSVLoader) Following() bool {
+	l.Mu.RLock()
+	defer l.Mu.RUnlock()
+	return l.followStop != nil
+}
+
+// Reopen 用相同的参数重新打开文件
+func (l *CSVLoader) Reopen() (RowSource, error) {
+	if l.reopen == nil {
+		return nil, errors.New("this table cannot be reopened")
+	}
+	return l.reopen()
+}
+
+func (l *CSVLoader) followLoop(w *fsnotify.Watcher, stop chan struct{}) {
+	defer w.Close()
+	tick := time.NewTicker(followPoll)
+	defer tick.Stop()
+	path := filepath.Clean(l.Path)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-l.stopCh:
+			return
+		case e := <-w.Events:
+			if filepath.Clean(e.Name) != path {
+				continue
+			}
+		case err := <-w.Errors:
+			log.Println("follow:", err)
+			continue
+		case <-tick.C:
+		}
+		if l.checkGrowth() {
+			l.Follow(false)
+			l.publish(Event{Kind: EventFileReplaced})
+			return
+		}
+	}
+}
+
+// checkGrowth 检查文件：变大时扩展索引；返回 true 表示文件被截断或替换
+func (l *CSVLoader) checkGrowth() (replaced bool) {
+	st, err := os.Stat(l.Path)
+	if err != nil {
+		// 轮转过程中文件可能短暂不存在，等新文件出现
+		return false
+	}
+	fst, err := l.f.Stat()
+	if err != nil {
+		return false
+	}
+	l.Mu.RLock()
+	size := l.size
+	l.Mu.RUnlock()
+	switch {
+	case !os.SameFile(st, fst), fst.Size() < size:
+		return true
+	case fst.Size() > size:
+		if err := l.extend(fst.Size()); err != nil {
+			l.publish(Event{Kind: EventError, Err: err})
+		}
+		// 追加不算外部修改，停止跟踪后以现在的状态为准
+		l.Mu.Lock()
+		l.modTime = fst.ModTime()
+		l.Mu.Unlock()
+	}
+	return false
+}
+
+// extend 文件增长到 size 后，从最后一条完整记录之后扫描新增的记录。
+// 最后一条没有换行结尾的记录会重新读取，因为写入方可能还没写完
+func (l *CSVLoader) extend(size int64) error {
+	l.Mu.Lock()
+	if !l.inMemory && !l.OffBuilt {
+		// 后台索引还在进行，完成后下一次检查再扩展
+		l.Mu.Unlock()
+		return nil
+	}
+	if !l.tailKnown {
+		if err := l.findTailLocked(); err != nil {
+			l.Mu.Unlock()
+			return err
+		}
+	}
+	start := l.tail
+	firstChanged := l.recordCountLocked() - 1
+	if l.partial {
+		firstChanged--
+		delete(l.Cache, firstChanged+1)
+		l.rows--
+		if !l.inMemory {
+			l.Offsets = l.Offsets[:len(l.Offsets)-1]
+		} else if l.rows == 0 {
+			// 没写完的是表头，重新读到时再确定
+			l.header = nil
+		}
+		if l.inMemory {
+			l.dropLinesLocked(l.rows)
+		}
+		l.partial = false
+	}
+	inMemory, quoted := l.inMemory, l.quoted()
+	l.Mu.Unlock()
+
+	var err error
+	if inMemory {
+		err = l.extendInMemory(start, size)
+	} else {
+		err = l.extendOffsets(start, size, quoted)
+	}
+	l.Mu.Lock()
+	l.size = size
+	l.TotalRow = l.rows
+	rows := max(l.rows-1, 0)
+	l.Mu.Unlock()
+	l.publish(Event{Kind: EventRowCountChanged, Rows: rows})
+	l.publish(Event{Kind: EventRowsLoaded, From: max(firstChanged, 0), To: rows})
+	return err
+}
+
+// extendOffsets 偏移表模式：用构建索引的扫描从 start 继续追加记录偏移
+func (l *CSVLoader) extendOffsets(start, size int64, quoted bool) error {
+	var offs, nl []int64
+	_, err := scanRecords(io.NewSectionReader(l.f, 0, size), start, quoted, func(o, n []int64) bool {
+		offs = append(offs, o...)
+		nl = append(nl, n...)
+		return true
+	})
+	l.Mu.Lock()
+	defer l.Mu.Unlock()
+	l.Offsets = append(l.Offsets, offs...)
+	l.addQuotedNL(nl)
+	l.tail = l.Offsets[len(l.Offsets)-1]
+	if size > l.tail {
+		l.Offsets = append(l.Offsets, size)
+		l.partial = true
+	}
+	l.rows = len(l.Offsets) - 1
+	return err
+}
+
+// extendInMemory 小文件模式：把新增的记录直接解析进缓存
+func (l *CSVLoader) extendInMemory(start, size int64) error {
+	br := bufio.NewReader(io.NewSectionReader(l.f, start, size-start))
+	for {
+		b, err := readRecord(br, l.quoted())
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rec := l.parseRecord(b)
+		l.Mu.Lock()
+		l.noteLineLocked(l.rows, l.nextLine)
+		l.nextLine += bytes.Count(b, []byte{'\n'})
+		l.Cache[l.rows] = rec
+		l.rows++
+		l.cols = max(l.cols, len(rec))
+		if l.header == nil {
+			l.header = rec
+		}
+		if bytes.HasSuffix(b, []byte{'\n'}) {
+			l.tail += int64(len(b))
+		} else {
+			l.partial = true
+		}
+		l.Mu.Unlock()
+	}
+}
+
+// findTailLocked 第一次扩展前确定最后一条完整记录的结束位置，调用方持有写锁
+func (l *CSVLoader) findTailLocked() error {
+	l.tailKnown = true
+	if !l.inMemory {
+		last := l.Offsets[len(l.Offsets)-1]
+		l.tail, l.partial = last, false
+		if last > l.Offsets[0] && !l.endsWithNewline(last) {
+			l.tail, l.partial = l.Offsets[len(l.Offsets)-2], true
+		}
+		return nil
+	}
+	l.tail = bomLen(l.f)
+	// nextLine 为 tail 处的物理行号，追加的记录从这里继续编号
+	var offs int
+	var nl []int64
+	_, err := scanRecords(io.NewSectionReader(l.f, 0, l.size), l.tail, l.quoted(), func(o, n []int64) bool {
+		if len(o) > 0 {
+			l.tail = o[len(o)-1]
+			offs += len(o)
+		}
+		nl = append(nl, n...)
+		return true
+	})
+	l.partial = l.size > l.tail
+	l.nextLine = 1 + offs + sort.Search(len(nl), func(i int) bool { return nl[i] >= l.tail })
+	return err
+}
+
+// endsWithNewline pos 之前的一个字节是否为换行
+func (l *CSVLoader) endsWithNewline(pos int64) bool {
+	b := make([]byte, 1)
+	_, err := l.f.ReadAt(b, pos-1)
+	return err == nil && b[0] == '\n'
+}
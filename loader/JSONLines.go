@@ -0,0 +1,161 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// JSONLinesLoader 以表格方式打开 JSON Lines（NDJSON）文件，每行一个 JSON 对象。
+// 复用 CSV 加载器的行偏移表和窗口缓存；列为采样行中出现过的键的并集，
+// 嵌套对象展开为点分路径，数组按紧凑 JSON 文本显示。
+type JSONLinesLoader struct {
+	*CSVLoader
+}
+
+// jsonLinesFormat 按采样得到的列把一行 JSON 映射为字段
+type jsonLinesFormat struct {
+	index map[string]int
+	cols  int
+}
+
+// NewJSONLinesLoader 打开 JSON Lines 文件，读取开头 DefaultInferSample 行确定列
+func NewJSONLinesLoader(path string, cacheCap int) (*JSONLinesLoader, error) {
+	lines, err := ReadSampleLines(path, DefaultInferSample)
+	if err != nil {
+		return nil, err
+	}
+	var header []string
+	jf := &jsonLinesFormat{index: make(map[string]int)}
+	for _, line := range lines {
+		// 无法解析的行不影响列的推断
+		_ = flattenJSON("", []byte(line), func(key, _ string) {
+			if _, ok := jf.index[key]; !ok {
+				jf.index[key] = len(header)
+				header = append(header, key)
+			}
+		})
+	}
+	if len(header) == 0 {
+		header = []string{jsonValueColumn}
+		jf.index[jsonValueColumn] = 0
+	}
+	jf.cols = len(header)
+
+	l, err := newLineLoader(path, jf, false, cacheCap)
+	if err != nil {
+		return nil, err
+	}
+	l.header = header
+	l.cols = len(header)
+	l.reopen = func() (RowSource, error) { return NewJSONLinesLoader(path, cacheCap) }
+	l.start()
+	return &JSONLinesLoader{CSVLoader: l}, nil
+}
+
+// jsonValueColumn 行不是对象时值所在的列名
+const jsonValueColumn = "value"
+
+func (jf *jsonLinesFormat) parseLine(b []byte) []string {
+	rec := make([]string, jf.cols)
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return rec
+	}
+	if err := flattenJSON("", b, func(key, val string) {
+		if i, ok := jf.index[key]; ok {
+			rec[i] = val
+		}
+	}); err != nil {
+		// 无法解析的行把原文放在第一列
+		rec[0] = string(b)
+	}
+	return rec
+}
+
+// flattenJSON 遍历一个 JSON 值的所有叶子，按出现顺序回调点分路径和文本值
+func flattenJSON(prefix string, raw []byte, fn func(key, val string)) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return io.ErrUnexpectedEOF
+	}
+	if raw[0] != '{' {
+		val, err := jsonScalarString(raw)
+		if err != nil {
+			return err
+		}
+		key := strings.TrimSuffix(prefix, ".")
+		if key == "" {
+			key = jsonValueColumn
+		}
+		fn(key, val)
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := t.(string)
+		if !ok {
+			return errors.New("json: object key is not a string")
+		}
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		if err := flattenJSON(prefix+key+".", v, fn); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// jsonScalarString 字符串去掉引号，null 为空，其他值（数字、布尔、数组）保留紧凑的 JSON 文本
+func jsonScalarString(raw []byte) (string, error) {
+	switch raw[0] {
+	case '"':
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	case 'n':
+		if string(raw) == "null" {
+			return "", nil
+		}
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// RawRecord 返回数据行的原始 JSON 文本
+func (j *JSONLinesLoader) RawRecord(row int) ([]byte, error) {
+	l := j.CSVLoader
+	rec := row + 1
+	l.Mu.RLock()
+	if row < 0 || rec+1 >= len(l.Offsets) {
+		l.Mu.RUnlock()
+		return nil, errors.New("row is not indexed yet")
+	}
+	start, end := l.Offsets[rec], l.Offsets[rec+1]
+	l.Mu.RUnlock()
+	b := make([]byte, end-start)
+	if _, err := l.f.ReadAt(b, start); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return bytes.TrimSpace(b), nil
+}
+
+var (
+	_ RowSource   = (*JSONLinesLoader)(nil)
+	_ RawRecorder = (*JSONLinesLoader)(nil)
+)
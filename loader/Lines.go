@@ -0,0 +1,200 @@
+package loader
+
+import (
+	"bytes"
+	"io"
+	"sort"
+)
+
+// LineLocator 可以在数据行和文件中的物理行号、字节偏移之间换算的数据源。
+// 引号内有换行的记录占多行，物理行号与行号不再一一对应
+type LineLocator interface {
+	// LineOf 数据行开始处的物理行号（从 1 开始），未知时返回 0
+	LineOf(row int) int
+	// RowAtLine 包含物理行 line 的数据行，还没有索引到或是表头时返回 false
+	RowAtLine(line int) (int, bool)
+	// RowAtOffset 包含字节偏移 off 的数据行
+	RowAtOffset(off int64) (int, bool)
+}
+
+// AsLineLocator 返回数据源的行号换算接口；视图只有底层支持时才支持
+func AsLineLocator(src RowSource) (LineLocator, bool) {
+	if v, ok := src.(*View); ok {
+		if _, ok := v.Base().(LineLocator); !ok {
+			return nil, false
+		}
+		return v, true
+	}
+	ll, ok := src.(LineLocator)
+	return ll, ok
+}
+
+// addQuotedNL 记录扫描到的引号内换行；首块扫描和后台构建有重叠，已记录过的位置跳过。调用方持有写锁
+func (l *CSVLoader) addQuotedNL(nl []int64) {
+	for _, p := range nl {
+		if n := len(l.quotedNL); n == 0 || p > l.quotedNL[n-1] {
+			l.quotedNL = append(l.quotedNL, p)
+		}
+	}
+}
+
+// noteLineLocked 小文件模式：记录 rec 从物理行 line 开始（按顺序逐条调用）
+func (l *CSVLoader) noteLineLocked(rec, line int) {
+	for expect := rec + 1 + len(l.lineJumps); expect < line; expect++ {
+		l.lineJumps = append(l.lineJumps, rec)
+	}
+}
+
+// dropLinesLocked 小文件模式：丢弃记录 rec（没写完的最后一条）的行号，追加时从它的起始行重新编号
+func (l *CSVLoader) dropLinesLocked(rec int) {
+	l.nextLine = l.lineLocked(rec)
+	l.lineJumps = l.lineJumps[:sort.SearchInts(l.lineJumps, rec)]
+}
+
+// lineLocked 记录 rec（含表头）开始处的物理行号，调用方持有读锁并保证 rec 已经索引到
+func (l *CSVLoader) lineLocked(rec int) int {
+	if l.inMemory {
+		return rec + 1 + sort.SearchInts(l.lineJumps, rec+1)
+	}
+	off := l.Offsets[rec]
+	line := rec + 1 + sort.Search(len(l.quotedNL), func(i int) bool { return l.quotedNL[i] >= off })
+	if l.noHeaderLine {
+		line--
+	}
+	return line
+}
+
+// LineOf 数据行开始处的物理行号
+func (l *CSVLoader) LineOf(row int) int {
+	l.Mu.RLock()
+	defer l.Mu.RUnlock()
+	rec := row + 1
+	if row < 0 || rec >= l.recordCountLocked() || l.invalid {
+		return 0
+	}
+	return l.lineLocked(rec)
+}
+
+// RowAtLine 在已索引的记录中二分查找包含物理行 line 的记录
+func (l *CSVLoader) RowAtLine(line int) (int, bool) {
+	l.Mu.RLock()
+	defer l.Mu.RUnlock()
+	n := l.recordCountLocked()
+	if line < 1 || n == 0 || l.invalid {
+		return 0, false
+	}
+	rec := sort.Search(n, func(r int) bool { return l.lineLocked(r) > line }) - 1
+	// 最后一条已索引的记录可能还没扫描完，行号更大的行也许属于后面的记录
+	if rec < 1 || rec == n-1 && !l.OffBuilt && !l.inMemory {
+		return 0, false
+	}
+	return rec - 1, true
+}
+
+// RowAtOffset 偏移表模式直接在偏移表中查找；小文件模式数出 off 之前的换行得到行号
+func (l *CSVLoader) RowAtOffset(off int64) (int, bool) {
+	l.Mu.RLock()
+	if off < 0 || off >= l.size || l.invalid {
+		l.Mu.RUnlock()
+		return 0, false
+	}
+	if l.inMemory {
+		l.Mu.RUnlock()
+		lines, err := countLines(io.NewSectionReader(l.f, 0, off))
+		if err != nil {
+			return 0, false
+		}
+		return l.RowAtLine(lines + 1)
+	}
+	defer l.Mu.RUnlock()
+	n := l.recordCountLocked()
+	rec := sort.Search(n, func(i int) bool { return l.Offsets[i] > off }) - 1
+	if rec < 1 || rec == n-1 && !l.OffBuilt && off >= l.Offsets[n] {
+		return 0, false
+	}
+	return rec - 1, true
+}
+
+// countLines 统计 r 中的换行数
+func countLines(r io.Reader) (int, error) {
+	buf := make([]byte, scanChunkSize)
+	n := 0
+	for {
+		k, err := r.Read(buf)
+		n += bytes.Count(buf[:k], []byte{'\n'})
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// LineOf 视图行对应的底层行的物理行号
+func (v *View) LineOf(row int) int {
+	ll, ok := v.Base().(LineLocator)
+	if !ok {
+		return 0
+	}
+	v.mu.RLock()
+	b := v.baseRow(row)
+	v.mu.RUnlock()
+	if b < 0 {
+		return 0
+	}
+	return ll.LineOf(b)
+}
+
+// RowAtLine 找到底层行后换算成视图行，被筛选掉的行返回 false
+func (v *View) RowAtLine(line int) (int, bool) {
+	ll, ok := v.Base().(LineLocator)
+	if !ok {
+		return 0, false
+	}
+	b, ok := ll.RowAtLine(line)
+	if !ok {
+		return 0, false
+	}
+	return v.viewRow(b)
+}
+
+// RowAtOffset 同 RowAtLine
+func (v *View) RowAtOffset(off int64) (int, bool) {
+	ll, ok := v.Base().(LineLocator)
+	if !ok {
+		return 0, false
+	}
+	b, ok := ll.RowAtOffset(off)
+	if !ok {
+		return 0, false
+	}
+	return v.viewRow(b)
+}
+
+// viewRow 底层行在视图中的位置，没有映射时相同
+func (v *View) viewRow(b int) (int, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if v.index == nil {
+		return b, true
+	}
+	for i, r := range v.index {
+		if r == b {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Mapped 是否有筛选或排序，视图行号与底层行号不再相同
+func (v *View) Mapped() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.index != nil
+}
+
+var (
+	_ LineLocator = (*CSVLoader)(nil)
+	_ LineLocator = (*View)(nil)
+)
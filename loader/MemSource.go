@@ -0,0 +1,70 @@
+package loader
+
+import "sync"
+
+// MemSource 完全在内存中的数据源，适用于查询结果、聚合结果等小数据
+type MemSource struct {
+	Notifier
+	mu     sync.RWMutex
+	header []string
+	rows   [][]string
+}
+
+// NewMemSource 用表头和行数据创建内存数据源，rows 不会被复制
+func NewMemSource(header []string, rows [][]string) *MemSource {
+	return &MemSource{header: header, rows: rows}
+}
+
+func (m *MemSource) Header() []string {
+	return m.header
+}
+
+func (m *MemSource) RowCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.rows)
+}
+
+func (m *MemSource) ColCount() int {
+	return len(m.header)
+}
+
+func (m *MemSource) Indexed() bool {
+	return true
+}
+
+func (m *MemSource) GetRow(row int) ([]string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if row < 0 || row >= len(m.rows) {
+		return nil, false
+	}
+	return m.rows[row], true
+}
+
+func (m *MemSource) ReadRows(from, to int, fn func(row int, rec []string) error) error {
+	m.mu.RLock()
+	rows := m.rows
+	m.mu.RUnlock()
+	if to < 0 || to > len(rows) {
+		to = len(rows)
+	}
+	for i := max(from, 0); i < to; i++ {
+		if err := fn(i, rows[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Append 追加行并通知视图刷新
+func (m *MemSource) Append(rows ...[]string) {
+	m.mu.Lock()
+	m.rows = append(m.rows, rows...)
+	n := len(m.rows)
+	m.mu.Unlock()
+	m.publish(Event{Kind: EventRowCountChanged, Rows: n})
+	m.publish(Event{Kind: EventRowsLoaded, From: n - len(rows), To: n})
+}
+
+func (m *MemSource) Close() {}
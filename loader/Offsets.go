@@ -0,0 +1,103 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+const scanChunkSize = 1 << 20
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// scanOffsets 从 start 开始扫描 r，每遇到一个记录结束的换行就记录下一条记录的起始偏移。
+// quoted 为 true 时按 CSV 规则处理引号，引号内的换行不结束记录。
+// 每扫描完一个块调用一次 emit，emit 返回 false 时提前停止。
+// 返回扫描结束时的位置（文件末尾或停止处）。
+func scanOffsets(r io.ReaderAt, start int64, quoted bool, emit func(offs []int64) bool) (int64, error) {
+	return scanRecords(r, start, quoted, func(offs, _ []int64) bool {
+		if len(offs) == 0 {
+			return true
+		}
+		return emit(offs)
+	})
+}
+
+// scanRecords 同 scanOffsets，另外把引号内换行的位置作为 nl 传给 emit，用于换算物理行号。
+// 块中没有记录结束但有引号内换行时也会调用 emit（offs 为空）
+func scanRecords(r io.ReaderAt, start int64, quoted bool, emit func(offs, nl []int64) bool) (int64, error) {
+	buf := make([]byte, scanChunkSize)
+	pos := start
+	inQuote := false
+	for {
+		n, err := r.ReadAt(buf, pos)
+		chunk := buf[:n]
+		var offs, nl []int64
+		if quoted {
+			for i, b := range chunk {
+				switch b {
+				case '"':
+					inQuote = !inQuote
+				case '\n':
+					if !inQuote {
+						offs = append(offs, pos+int64(i)+1)
+					} else {
+						nl = append(nl, pos+int64(i))
+					}
+				}
+			}
+		} else {
+			base := 0
+			for {
+				i := bytes.IndexByte(chunk[base:], '\n')
+				if i < 0 {
+					break
+				}
+				base += i + 1
+				offs = append(offs, pos+int64(base))
+			}
+		}
+		pos += int64(n)
+		if len(offs) > 0 || len(nl) > 0 || err != nil {
+			if !emit(offs, nl) {
+				return pos, nil
+			}
+		}
+		if err == io.EOF {
+			return pos, nil
+		}
+		if err != nil {
+			return pos, err
+		}
+	}
+}
+
+// bomLen 返回文件开头 UTF-8 BOM 的长度
+func bomLen(r io.ReaderAt) int64 {
+	head := make([]byte, len(utf8BOM))
+	if n, _ := r.ReadAt(head, 0); n == len(head) && bytes.Equal(head, utf8BOM) {
+		return int64(len(utf8BOM))
+	}
+	return 0
+}
+
+// readRecord 从 br 读取一条完整记录的原始字节（含结尾换行）。
+// quoted 为 true 时引号内的换行不结束记录。到达文件末尾且没有数据时返回 io.EOF。
+func readRecord(br *bufio.Reader, quoted bool) ([]byte, error) {
+	line, err := br.ReadBytes('\n')
+	if !quoted {
+		if err == io.EOF && len(line) > 0 {
+			err = nil
+		}
+		return line, err
+	}
+	for err == nil && bytes.Count(line, []byte{'"'})%2 == 1 {
+		var more []byte
+		more, err = br.ReadBytes('\n')
+		line = append(line, more...)
+	}
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	return line, err
+}
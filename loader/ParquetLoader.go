@@ -0,0 +1,463 @@
+package loader
+
+import (
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+)
+
+// ParquetLoader 只读打开 Parquet 文件。
+// 行按窗口从所在的行组中懒加载，只解码当前投影中的列；嵌套字段展开为点分列名。
+type ParquetLoader struct {
+	Notifier
+	Path string
+
+	f      *os.File
+	pf     *parquet.File
+	header []string
+	leaves []parquet.LeafColumn
+	starts []int64 // 每个行组的起始行号
+	rows   int
+
+	mu         sync.RWMutex
+	cache      map[int][]string
+	cacheProj  map[int]uint64 // 行缓存时使用的投影版本
+	cap        int
+	proj       []bool // 需要解码的列，nil 表示全部
+	projVer    uint64
+	requestCh  chan int
+	stopCh     chan struct{}
+	closeOnce  sync.Once
+	decodeLock sync.Mutex // parquet 文件的页读取不是并发安全的
+}
+
+// NewParquetLoader 打开 Parquet 文件，只读取元数据
+func NewParquetLoader(path string, cacheCap int) (*ParquetLoader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	// 保留页索引，按行定位时直接跳到所在的页，不用从行组开头扫描
+	pf, err := parquet.OpenFile(f, st.Size(), parquet.ReadBufferSize(1<<20))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	p := &ParquetLoader{
+		Path:      path,
+		f:         f,
+		pf:        pf,
+		rows:      int(pf.NumRows()),
+		cache:     make(map[int][]string),
+		cacheProj: make(map[int]uint64),
+		cap:       max(cacheCap, 64),
+		requestCh: make(chan int, 100),
+		stopCh:    make(chan struct{}),
+	}
+	for _, path := range pf.Schema().Columns() {
+		leaf, ok := pf.Schema().Lookup(path...)
+		if !ok {
+			continue
+		}
+		// LIST 的标准三层结构 a.list.element 直接显示为 a
+		name := strings.TrimSuffix(strings.Join(path, "."), ".list.element")
+		p.header = append(p.header, name)
+		p.leaves = append(p.leaves, leaf)
+	}
+	var start int64
+	for _, rg := range pf.RowGroups() {
+		p.starts = append(p.starts, start)
+		start += rg.NumRows()
+	}
+	go p.requestLoop()
+	return p, nil
+}
+
+func (p *ParquetLoader) Header() []string { return p.header }
+func (p *ParquetLoader) RowCount() int    { return p.rows }
+func (p *ParquetLoader) ColCount() int    { return len(p.header) }
+func (p *ParquetLoader) Indexed() bool    { return true }
+
+// SetProjection 设置需要解码的列；已缓存但缺少这些列的行会在下次访问时重新加载
+func (p *ParquetLoader) SetProjection(cols []int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cols == nil {
+		p.proj = nil
+	} else {
+		p.proj = make([]bool, len(p.header))
+		for _, c := range cols {
+			if c >= 0 && c < len(p.proj) {
+				p.proj[c] = true
+			}
+		}
+	}
+	p.projVer++
+}
+
+func (p *ParquetLoader) GetRow(row int) ([]string, bool) {
+	if row < 0 || row >= p.rows {
+		return nil, false
+	}
+	p.mu.RLock()
+	r, ok := p.cache[row]
+	fresh := ok && p.cacheProj[row] == p.projVer
+	p.mu.RUnlock()
+	if fresh {
+		return r, true
+	}
+	select {
+	case p.requestCh <- row:
+	default:
+	}
+	// 投影变化后旧数据缺少新列，和未命中一样返回 false，加载完成后表格才会刷新这些单元格
+	return nil, false
+}
+
+func (p *ParquetLoader) requestLoop() {
+	for {
+		select {
+		case row := <-p.requestCh:
+			rows := []int{row}
+		drain:
+			for {
+				select {
+				case r := <-p.requestCh:
+					rows = append(rows, r)
+				default:
+					break drain
+				}
+			}
+			for i := len(rows) - 1; i >= 0; i-- {
+				p.mu.RLock()
+				fresh := p.cache[rows[i]] != nil && p.cacheProj[rows[i]] == p.projVer
+				p.mu.RUnlock()
+				if fresh {
+					continue
+				}
+				from, to, err := p.loadWindow(rows[i])
+				if err != nil {
+					log.Println("parquet loadWindow error:", err)
+					p.publish(Event{Kind: EventError, Err: err})
+					continue
+				}
+				p.publish(Event{Kind: EventRowsLoaded, From: from, To: to})
+			}
+		case <-p.stopCh:
+			return
+		}
+	}
+}
+
+// loadWindow 解码 row 附近、同一行组内的一段行放入缓存
+func (p *ParquetLoader) loadWindow(row int) (from, to int, err error) {
+	g := p.rowGroupOf(row)
+	rgStart := int(p.starts[g])
+	rgEnd := rgStart + int(p.pf.RowGroups()[g].NumRows())
+	half := p.cap / 4
+	from = max(row-half, rgStart)
+	to = min(from+2*half, rgEnd)
+
+	p.mu.RLock()
+	proj := p.proj
+	ver := p.projVer
+	p.mu.RUnlock()
+
+	records, err := p.decode(g, from-rgStart, to-from, proj)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, r := range records {
+		p.cache[from+i] = r
+		p.cacheProj[from+i] = ver
+	}
+	if len(p.cache) > p.cap {
+		for k := range p.cache {
+			if k < row-p.cap/2 || k >= row+p.cap/2 {
+				delete(p.cache, k)
+				delete(p.cacheProj, k)
+			}
+		}
+	}
+	return from, to, nil
+}
+
+func (p *ParquetLoader) rowGroupOf(row int) int {
+	g := 0
+	for i, s := range p.starts {
+		if int64(row) >= s {
+			g = i
+		}
+	}
+	return g
+}
+
+// decode 从行组 g 的第 offset 行开始解码 n 行，proj 为 nil 时解码所有列
+func (p *ParquetLoader) decode(g, offset, n int, proj []bool) ([][]string, error) {
+	p.decodeLock.Lock()
+	defer p.decodeLock.Unlock()
+
+	records := make([][]string, n)
+	for i := range records {
+		records[i] = make([]string, len(p.leaves))
+	}
+	for c := range p.leaves {
+		if proj != nil && !proj[c] {
+			continue
+		}
+		cr, err := p.openColumn(g, c, offset)
+		if err != nil {
+			return nil, err
+		}
+		err = cr.read(records, c)
+		cr.close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// columnReader 顺序读取行组中的一列，按行返回格式化后的单元格
+type columnReader struct {
+	leaf  parquet.LeafColumn
+	typ   parquet.Type
+	pages parquet.Pages
+	page  parquet.Page
+	vr    parquet.ValueReader
+	buf   []parquet.Value
+	vals  []parquet.Value // buf 中还没有处理的值
+}
+
+// openColumn 打开行组 g 的第 c 列并定位到组内第 offset 行
+func (p *ParquetLoader) openColumn(g, c, offset int) (*columnReader, error) {
+	leaf := p.leaves[c]
+	cr := &columnReader{
+		leaf:  leaf,
+		typ:   leaf.Node.Type(),
+		pages: p.pf.RowGroups()[g].ColumnChunks()[leaf.ColumnIndex].Pages(),
+		buf:   make([]parquet.Value, 1024),
+	}
+	if err := cr.pages.SeekToRow(int64(offset)); err != nil {
+		cr.close()
+		return nil, err
+	}
+	// 重复级别为 0 表示新的一行开始，跳过定位点之前残留的列表元素
+	for {
+		if err := cr.fill(); err != nil {
+			if err == io.EOF {
+				return cr, nil
+			}
+			cr.close()
+			return nil, err
+		}
+		if cr.vals[0].RepetitionLevel() == 0 {
+			return cr, nil
+		}
+		cr.vals = cr.vals[1:]
+	}
+}
+
+// fill 当前没有待处理的值时读取下一批，列读完时返回 io.EOF
+func (cr *columnReader) fill() error {
+	for len(cr.vals) == 0 {
+		if cr.vr == nil {
+			if cr.page != nil {
+				parquet.Release(cr.page)
+				cr.page = nil
+			}
+			page, err := cr.pages.ReadPage()
+			if err != nil {
+				return err
+			}
+			cr.page, cr.vr = page, page.Values()
+		}
+		n, err := cr.vr.ReadValues(cr.buf)
+		cr.vals = cr.buf[:n]
+		if err == io.EOF {
+			cr.vr = nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// next 下一行的单元格；重复字段的各个元素合并为 [a, b] 显示
+func (cr *columnReader) next() (string, error) {
+	if err := cr.fill(); err != nil {
+		return "", err
+	}
+	v := cr.vals[0]
+	cr.vals = cr.vals[1:]
+	if cr.leaf.MaxRepetitionLevel == 0 {
+		return parquetValueString(v, cr.typ), nil
+	}
+	var list []string
+	for {
+		if !v.IsNull() {
+			list = append(list, parquetValueString(v, cr.typ))
+		}
+		if err := cr.fill(); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		if cr.vals[0].RepetitionLevel() == 0 {
+			break
+		}
+		v = cr.vals[0]
+		cr.vals = cr.vals[1:]
+	}
+	if list == nil {
+		return "", nil
+	}
+	return "[" + strings.Join(list, ", ") + "]", nil
+}
+
+// read 把接下来的 len(records) 行写入各行的第 c 列，列提前结束时其余行留空
+func (cr *columnReader) read(records [][]string, c int) error {
+	for _, r := range records {
+		s, err := cr.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		r[c] = s
+	}
+	return nil
+}
+
+func (cr *columnReader) close() {
+	if cr.page != nil {
+		parquet.Release(cr.page)
+		cr.page = nil
+	}
+	cr.pages.Close()
+}
+
+// parquetValueString 按逻辑类型格式化单元格
+func parquetValueString(v parquet.Value, t parquet.Type) string {
+	if v.IsNull() {
+		return ""
+	}
+	if lt := t.LogicalType(); lt != nil {
+		switch {
+		case lt.Date != nil:
+			return time.Unix(int64(v.Int32())*86400, 0).UTC().Format("2006-01-02")
+		case lt.Timestamp != nil:
+			return formatParquetTime(v.Int64(), lt.Timestamp.Unit).Format("2006-01-02 15:04:05.999")
+		}
+	}
+	switch v.Kind() {
+	case parquet.Double:
+		return strconv.FormatFloat(v.Double(), 'g', -1, 64)
+	case parquet.Float:
+		return strconv.FormatFloat(float64(v.Float()), 'g', -1, 32)
+	}
+	return v.String()
+}
+
+func formatParquetTime(n int64, unit format.TimeUnit) time.Time {
+	switch {
+	case unit.Nanos != nil:
+		return time.Unix(0, n).UTC()
+	case unit.Micros != nil:
+		return time.UnixMicro(n).UTC()
+	default:
+		return time.UnixMilli(n).UTC()
+	}
+}
+
+// ReadRows 每个行组的各列只打开一次，从起点顺序解码到结束，不按批重新定位
+func (p *ParquetLoader) ReadRows(from, to int, fn func(row int, rec []string) error) error {
+	if to < 0 || to > p.rows {
+		to = p.rows
+	}
+	for row := max(from, 0); row < to; {
+		g := p.rowGroupOf(row)
+		rgEnd := min(int(p.starts[g])+int(p.pf.RowGroups()[g].NumRows()), to)
+		if err := p.readGroup(g, row, rgEnd, fn); err != nil {
+			return err
+		}
+		row = rgEnd
+	}
+	return nil
+}
+
+// readGroup 顺序读取行组 g 中 [from, to) 的行；每批解码时持有 decodeLock，回调时释放
+func (p *ParquetLoader) readGroup(g, from, to int, fn func(row int, rec []string) error) error {
+	const batch = 4096
+	readers := make([]*columnReader, len(p.leaves))
+	defer func() {
+		for _, cr := range readers {
+			if cr != nil {
+				cr.close()
+			}
+		}
+	}()
+	p.decodeLock.Lock()
+	for c := range p.leaves {
+		cr, err := p.openColumn(g, c, from-int(p.starts[g]))
+		if err != nil {
+			p.decodeLock.Unlock()
+			return err
+		}
+		readers[c] = cr
+	}
+	p.decodeLock.Unlock()
+
+	for row := from; row < to; {
+		n := min(batch, to-row)
+		records := make([][]string, n)
+		for i := range records {
+			records[i] = make([]string, len(p.leaves))
+		}
+		p.decodeLock.Lock()
+		for c, cr := range readers {
+			if err := cr.read(records, c); err != nil {
+				p.decodeLock.Unlock()
+				return err
+			}
+		}
+		p.decodeLock.Unlock()
+		for i, r := range records {
+			if err := fn(row+i, r); err != nil {
+				return err
+			}
+		}
+		row += n
+	}
+	return nil
+}
+
+func (p *ParquetLoader) Close() {
+	p.closeOnce.Do(func() {
+		close(p.stopCh)
+		if err := p.f.Close(); err != nil {
+			log.Println("close error:", err)
+		}
+	})
+}
+
+var (
+	_ RowSource       = (*ParquetLoader)(nil)
+	_ ColumnProjector = (*ParquetLoader)(nil)
+)
@@ -0,0 +1,117 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+// RawFile 按原始字节访问 CSV/TSV 文件的记录，拆分文件时按记录偏移直接复制，不解析也不改变格式
+type RawFile struct {
+	f      *os.File
+	Comma  rune
+	Header []byte // 表头记录原文，总是以换行结尾
+	start  int64  // 第一条数据记录的偏移
+	size   int64
+}
+
+// OpenRawFile 打开 CSV/TSV 文件并读出表头记录
+func OpenRawFile(path string) (*RawFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if st.IsDir() {
+		f.Close()
+		return nil, errors.New(path + " is a directory")
+	}
+	r := &RawFile{f: f, Comma: detectComma(path, f), size: st.Size()}
+	bom := bomLen(f)
+	header, err := readRecord(bufio.NewReader(io.NewSectionReader(f, bom, r.size-bom)), true)
+	if err != nil && err != io.EOF {
+		f.Close()
+		return nil, err
+	}
+	r.start = bom + int64(len(header))
+	r.Header = withNewline(header)
+	return r, nil
+}
+
+// Columns 解析后的表头
+func (r *RawFile) Columns() []string {
+	return parseCSVRecord(r.Header, r.Comma)
+}
+
+// Start 第一条数据记录的偏移
+func (r *RawFile) Start() int64 { return r.start }
+
+// Size 文件大小
+func (r *RawFile) Size() int64 { return r.size }
+
+// ScanRecords 按构建偏移表的方式扫描数据记录边界，对每条记录的结束偏移调用 fn，
+// fn 返回 false 时停止。最后一条记录没有换行结尾时以文件末尾为结束偏移
+func (r *RawFile) ScanRecords(fn func(end int64) bool) error {
+	last := r.start
+	stopped := false
+	_, err := scanOffsets(r.f, r.start, true, func(offs []int64) bool {
+		for _, off := range offs {
+			last = off
+			if !fn(off) {
+				stopped = true
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil || stopped {
+		return err
+	}
+	if r.size > last {
+		fn(r.size)
+	}
+	return nil
+}
+
+// CopyRange 把 [from, to) 的字节原样写入 w
+func (r *RawFile) CopyRange(w io.Writer, from, to int64) error {
+	_, err := io.Copy(w, io.NewSectionReader(r.f, from, to-from))
+	return err
+}
+
+// ReadRecords 顺序读取数据记录，回调原始字节（总是以换行结尾）和解析后的字段
+func (r *RawFile) ReadRecords(fn func(raw []byte, rec []string) error) error {
+	br := bufio.NewReaderSize(io.NewSectionReader(r.f, r.start, r.size-r.start), 1<<20)
+	for {
+		b, err := readRecord(br, true)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		b = withNewline(b)
+		if err := fn(b, parseCSVRecord(b, r.Comma)); err != nil {
+			return err
+		}
+	}
+}
+
+// Close 关闭文件
+func (r *RawFile) Close() error {
+	return r.f.Close()
+}
+
+// withNewline 没有换行结尾的记录（文件最后一条）补上换行，便于拼接到其他记录前面
+func withNewline(b []byte) []byte {
+	if len(b) == 0 || bytes.HasSuffix(b, []byte{'\n'}) {
+		return b
+	}
+	return append(b, '\n')
+}
@@ -0,0 +1,213 @@
+package loader
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// changePoll 没有跟踪文件时检查文件是否被其他程序修改的间隔
+const changePoll = 2 * time.Second
+
+// ErrFileChanged 文件已被其他程序原地改写，旧的偏移表不再可信
+var ErrFileChanged = errors.New("the file was changed by another program; reload it to see the current contents")
+
+// CellEdit 一个单元格的未保存修改，Row 为数据行号（不含表头）
+type CellEdit struct {
+	Row, Col int
+	Old      string // 修改前文件中的值
+	New      string
+	// OldUnknown 编辑时读不到文件中的原值（行尚未索引或文件已被改写），Old 无意义
+	OldUnknown bool
+}
+
+// EditConflict 重新打开后文件中的值已经不是 Old，无法自动合并的修改
+type EditConflict struct {
+	CellEdit
+	Current string // 文件中现在的值
+	Missing bool   // 文件中已经没有这一行
+}
+
+// Reloadable 文件被其他程序修改后可以重新打开，并把未保存的修改转移到新数据源
+type Reloadable interface {
+	Reopen() (RowSource, error)
+	// Changed 打开后文件是否被其他程序修改过
+	Changed() bool
+	// PendingEdits 按行列排序的编辑
+	PendingEdits() []CellEdit
+	// DeletedRows 标记删除的数据行，升序
+	DeletedRows() []int
+}
+
+var _ Reloadable = (*CSVLoader)(nil)
+
+// watchChanges 定期比较文件的大小和修改时间。跟踪文件时由 followLoop 负责，这里跳过
+func (l *CSVLoader) watchChanges() {
+	tick := time.NewTicker(changePoll)
+	defer tick.Stop()
+	for {
+		select {
+		case <-l.stopCh:
+			return
+		case <-tick.C:
+		}
+		if l.Following() || l.Changed() {
+			continue
+		}
+		l.checkChanged()
+	}
+}
+
+// checkChanged 文件被修改时发布 EventFileChanged。原地改写的大文件偏移表已失效，
+// 清空缓存并停止读取；改名替换时旧句柄读到的仍是旧内容，一次性读入内存的小文件也不受影响
+func (l *CSVLoader) checkChanged() {
+	st, err := os.Stat(l.Path)
+	if err != nil {
+		return
+	}
+	fst, err := l.f.Stat()
+	if err != nil {
+		return
+	}
+	l.Mu.Lock()
+	if st.Size() == l.size && st.ModTime().Equal(l.modTime) && os.SameFile(st, fst) {
+		l.Mu.Unlock()
+		return
+	}
+	l.changed = true
+	if os.SameFile(st, fst) && !l.inMemory {
+		l.invalid = true
+		clear(l.Cache)
+	}
+	rows := max(l.recordCountLocked()-1, 0)
+	l.Mu.Unlock()
+	l.publish(Event{Kind: EventFileChanged})
+	l.publish(Event{Kind: EventRowsLoaded, From: 0, To: rows})
+}
+
+// Changed 打开后文件是否被其他程序修改过
+func (l *CSVLoader) Changed() bool {
+	l.Mu.RLock()
+	defer l.Mu.RUnlock()
+	return l.changed
+}
+
+// PendingEdits 返回所有编辑及其修改前的值
+func (l *CSVLoader) PendingEdits() []CellEdit {
+	l.Mu.RLock()
+	defer l.Mu.RUnlock()
+	var out []CellEdit
+	for rec, cols := range l.edits {
+		for c, v := range cols {
+			out = append(out, CellEdit{Row: rec - 1, Col: c, Old: l.editOrig[rec][c], New: v, OldUnknown: l.noOrig[rec][c]})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Row != out[j].Row {
+			return out[i].Row < out[j].Row
+		}
+		return out[i].Col < out[j].Col
+	})
+	return out
+}
+
+// DeletedRows 标记删除的数据行
+func (l *CSVLoader) DeletedRows() []int {
+	l.Mu.RLock()
+	defer l.Mu.RUnlock()
+	out := make([]int, 0, len(l.deleted))
+	for rec := range l.deleted {
+		out = append(out, rec-1)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// originalRecord 编辑前的记录（不含编辑），缓存未命中时按偏移从文件同步读取。
+// 行尚未索引、文件已被改写或读取失败时返回 false，调用方不能把空值当作原值
+func (l *CSVLoader) originalRecord(rec int) ([]string, bool) {
+	l.Mu.RLock()
+	r, ok := l.Cache[rec]
+	if ok || l.inMemory {
+		// 一次性读入内存的文件没有的行就是文件末尾之后的行，原值确实为空
+		l.Mu.RUnlock()
+		return r, true
+	}
+	if l.invalid || rec+1 >= len(l.Offsets) {
+		l.Mu.RUnlock()
+		return nil, false
+	}
+	start, end := l.Offsets[rec], l.Offsets[rec+1]
+	l.Mu.RUnlock()
+	b := make([]byte, end-start)
+	if _, err := l.f.ReadAt(b, start); err != nil && err != io.EOF {
+		return nil, false
+	}
+	return l.parseRecord(b), true
+}
+
+// ReapplyEdits 把 edits 和删除标记转移到重新打开的 dst 上。
+// 文件中的值仍为 Old 的修改直接应用，已经是 New 的忽略，其余作为冲突返回由用户决定；
+// 原值未知的修改无法判断文件是否改过，除非已经是 New，都作为冲突返回；
+// 删除标记只保留仍然存在的行
+func ReapplyEdits(dst RowSource, edits []CellEdit, deleted []int) ([]EditConflict, error) {
+	ed, ok := AsEditor(dst)
+	if !ok {
+		return nil, errors.New("the reopened table cannot be edited")
+	}
+	if len(edits) == 0 && len(deleted) == 0 {
+		return nil, nil
+	}
+	byRow := make(map[int][]CellEdit)
+	last := -1
+	for _, e := range edits {
+		byRow[e.Row] = append(byRow[e.Row], e)
+		last = max(last, e.Row)
+	}
+	if len(deleted) > 0 {
+		last = max(last, deleted[len(deleted)-1])
+	}
+
+	var conflicts []EditConflict
+	rows := 0
+	err := dst.ReadRows(0, last+1, func(row int, rec []string) error {
+		rows = row + 1
+		for _, e := range byRow[row] {
+			cur := field(rec, e.Col)
+			switch {
+			case cur == e.New:
+			case cur == e.Old && !e.OldUnknown:
+				ed.SetEdit(e.Row, e.Col, e.New)
+			default:
+				conflicts = append(conflicts, EditConflict{CellEdit: e, Current: cur})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range edits {
+		if e.Row >= rows {
+			conflicts = append(conflicts, EditConflict{CellEdit: e, Missing: true})
+		}
+	}
+	keep := deleted[:0:0]
+	for _, r := range deleted {
+		if r < rows {
+			keep = append(keep, r)
+		}
+	}
+	ed.SetDeleted(keep, true)
+	return conflicts, nil
+}
+
+// field 取记录中的第 i 个字段，不存在时返回空字符串
+func field(rec []string, i int) string {
+	if i < len(rec) {
+		return rec[i]
+	}
+	return ""
+}
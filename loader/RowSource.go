@@ -0,0 +1,102 @@
+package loader
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// RowSource 表格视图背后的数据源。
+// VirtualTable 只通过这个接口取数，CSV 加载器、内存数据或其他格式都可以作为实现。
+// 行号从 0 开始且不包含表头。
+type RowSource interface {
+	// Header 列名
+	Header() []string
+	// RowCount 当前已知的行数，Indexed 为 false 时可能继续增长
+	RowCount() int
+	// ColCount 列数
+	ColCount() int
+	// Indexed 行数是否已经确定
+	Indexed() bool
+	// GetRow 非阻塞取行：命中时返回 true；未命中时在后台加载，加载完成后发布 EventRowsLoaded
+	GetRow(row int) ([]string, bool)
+	// ReadRows 顺序阻塞读取 [from, to) 范围内的行（to < 0 表示读到末尾），用于导出等批量处理。
+	// fn 返回错误时停止读取并返回该错误；rec 在回调返回后可能被复用，需要保留时请复制
+	ReadRows(from, to int, fn func(row int, rec []string) error) error
+	// Subscribe 订阅数据源事件（回调可能在任意 goroutine 中调用），返回取消订阅函数
+	Subscribe(f func(Event)) func()
+	// Close 释放数据源占用的资源
+	Close()
+}
+
+// ColumnProjector 可以只解码部分列的数据源，视图把当前可见的列告诉它
+type ColumnProjector interface {
+	SetProjection(cols []int)
+}
+
+// Editor 支持编辑覆盖层的数据源，编辑只保存在内存中、合并到读取结果里，不写回文件
+type Editor interface {
+	SetEdit(row, col int, val string)
+	// SetDeleted 标记（或取消标记）删除行；标记的行仍然显示，导出时跳过
+	SetDeleted(rows []int, deleted bool)
+	Deleted(row int) bool
+}
+
+// Follower 按行偏移索引的文本文件，可以跟踪写入方在末尾追加的内容
+type Follower interface {
+	Follow(on bool) error
+	Following() bool
+	// Reopen 用相同的参数重新打开文件，用于文件被截断或替换之后
+	Reopen() (RowSource, error)
+}
+
+// RawRecorder 可以返回某一数据行原始文本的数据源（如 JSON Lines），用于详情面板
+type RawRecorder interface {
+	RawRecord(row int) ([]byte, error)
+}
+
+var (
+	_ Editor    = (*CSVLoader)(nil)
+	_ Follower  = (*CSVLoader)(nil)
+	_ RowSource = (*CSVLoader)(nil)
+	_ RowSource = (*MemSource)(nil)
+)
+
+// Open 按扩展名选择加载器打开文件；多表文件（XLSX）只返回第一张表
+func Open(path string) (RowSource, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".parquet":
+		return NewParquetLoader(path, 1024)
+	case ".jsonl", ".ndjson":
+		return NewJSONLinesLoader(path, 1024)
+	case ".xlsx", ".xlsm":
+		sheets, err := OpenXLSX(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(sheets) == 0 {
+			return nil, errors.New("workbook has no visible sheets")
+		}
+		for _, s := range sheets[1:] {
+			s.Source.Close()
+		}
+		return sheets[0].Source, nil
+	}
+	return NewCsvLoaderV2(path, 1024)
+}
+
+// OpenSheets 打开文件中的所有表，每张表在界面上对应一个标签页
+func OpenSheets(path string) ([]Sheet, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xlsx", ".xlsm":
+		return OpenXLSX(path)
+	}
+	src, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return []Sheet{{Name: filepath.Base(path), Source: src}}, nil
+}
+
+// OpenExtensions 打开对话框中可选的扩展名
+var OpenExtensions = []string{".csv", ".tsv", ".txt", ".parquet", ".xlsx", ".xlsm", ".jsonl", ".ndjson"}
@@ -0,0 +1,389 @@
+package loader
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteObject 数据库中可以打开的表或视图
+type SQLiteObject struct {
+	Name string
+	Type string // table 或 view
+}
+
+// openSQLite 以只读方式打开本地 SQLite 文件
+func openSQLite(path string) (*sql.DB, error) {
+	u := url.URL{Scheme: "file", OmitHost: true, Path: path, RawQuery: "mode=ro"}
+	db, err := sql.Open("sqlite", u.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// SQLiteTables 列出数据库中的表和视图（不含 sqlite_ 内部表）
+func SQLiteTables(path string) ([]SQLiteObject, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT name, type FROM sqlite_master
+		WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+		ORDER BY type, name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var objs []SQLiteObject
+	for rows.Next() {
+		var o SQLiteObject
+		if err := rows.Scan(&o.Name, &o.Type); err != nil {
+			return nil, err
+		}
+		objs = append(objs, o)
+	}
+	return objs, rows.Err()
+}
+
+// SQLiteLoader 按页读取 SQLite 表或视图。
+// 有 rowid 的表按 rowid 排序，并在相邻窗口之间用 rowid 续读，避免大 OFFSET 的全表扫描；
+// 视图和 WITHOUT ROWID 表退回 LIMIT/OFFSET。行数在后台统计。
+type SQLiteLoader struct {
+	Notifier
+	Path  string
+	Table string
+
+	db     *sql.DB
+	header []string
+	query  string // SELECT ... FROM table，不含排序和分页
+	rowid  bool
+
+	mu        sync.RWMutex
+	rows      int
+	counted   bool
+	cache     map[int][]string
+	rowids    map[int]int64 // 缓存行对应的 rowid，用于续读下一页
+	cap       int
+	requestCh chan int
+	stopCh    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewSQLiteLoader 打开数据库中的一张表或视图
+func NewSQLiteLoader(path, table string, cacheCap int) (*SQLiteLoader, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &SQLiteLoader{
+		Path:      path,
+		Table:     table,
+		db:        db,
+		cache:     make(map[int][]string),
+		rowids:    make(map[int]int64),
+		cap:       max(cacheCap, 64),
+		requestCh: make(chan int, 100),
+		stopCh:    make(chan struct{}),
+	}
+	from := " FROM " + quoteSQLiteIdent(table)
+	cols, err := db.Query("SELECT *" + from + " LIMIT 0")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.header, err = cols.Columns()
+	cols.Close()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	// 视图和 WITHOUT ROWID 表查询 rowid 会报错
+	if r, err := db.Query("SELECT rowid" + from + " LIMIT 0"); err == nil {
+		r.Close()
+		s.rowid = true
+	}
+	if s.rowid {
+		s.query = "SELECT rowid, *" + from
+	} else {
+		s.query = "SELECT *" + from
+	}
+	if _, _, err := s.loadWindow(0); err != nil {
+		db.Close()
+		return nil, err
+	}
+	go s.countRows()
+	go s.requestLoop()
+	return s, nil
+}
+
+func quoteSQLiteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// countRows 后台统计行数，大表的 count(*) 可能需要扫描整个表
+func (s *SQLiteLoader) countRows() {
+	var n int
+	err := s.db.QueryRow("SELECT count(*) FROM " + quoteSQLiteIdent(s.Table)).Scan(&n)
+	select {
+	case <-s.stopCh:
+		return
+	default:
+	}
+	if err != nil {
+		log.Println("sqlite count error:", err)
+		s.publish(Event{Kind: EventError, Err: err})
+	}
+	s.mu.Lock()
+	s.rows = max(s.rows, n)
+	s.counted = true
+	rows := s.rows
+	s.mu.Unlock()
+	s.publish(Event{Kind: EventIndexProgress, Progress: 1, Rows: rows})
+	s.publish(Event{Kind: EventRowCountChanged, Rows: rows})
+}
+
+func (s *SQLiteLoader) Header() []string { return s.header }
+func (s *SQLiteLoader) ColCount() int    { return len(s.header) }
+
+// RowCount 统计完成前为已经读到的行数
+func (s *SQLiteLoader) RowCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rows
+}
+
+func (s *SQLiteLoader) Indexed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.counted
+}
+
+func (s *SQLiteLoader) GetRow(row int) ([]string, bool) {
+	if row < 0 {
+		return nil, false
+	}
+	s.mu.RLock()
+	r, ok := s.cache[row]
+	s.mu.RUnlock()
+	if ok {
+		return r, true
+	}
+	select {
+	case s.requestCh <- row:
+	default:
+	}
+	return nil, false
+}
+
+func (s *SQLiteLoader) requestLoop() {
+	for {
+		select {
+		case row := <-s.requestCh:
+			rows := []int{row}
+		drain:
+			for {
+				select {
+				case r := <-s.requestCh:
+					rows = append(rows, r)
+				default:
+					break drain
+				}
+			}
+			for i := len(rows) - 1; i >= 0; i-- {
+				s.mu.RLock()
+				_, ok := s.cache[rows[i]]
+				s.mu.RUnlock()
+				if ok {
+					continue
+				}
+				from, to, err := s.loadWindow(rows[i])
+				if err != nil {
+					log.Println("sqlite loadWindow error:", err)
+					s.publish(Event{Kind: EventError, Err: err})
+					continue
+				}
+				if to > from {
+					s.publish(Event{Kind: EventRowsLoaded, From: from, To: to})
+				}
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// loadWindow 读取 row 附近的一页放入缓存，返回实际读到的行范围 [from, to)
+func (s *SQLiteLoader) loadWindow(row int) (from, to int, err error) {
+	half := s.cap / 4
+	from = max(row-half, 0)
+	limit := 2 * half
+
+	s.mu.RLock()
+	prev, keyed := s.rowids[from-1]
+	s.mu.RUnlock()
+	var records [][]string
+	var ids []int64
+	err = s.readPage(from, limit, prev, keyed, func(id int64, rec []string) error {
+		records = append(records, rec)
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	to = from + len(records)
+
+	s.mu.Lock()
+	for i, r := range records {
+		s.cache[from+i] = r
+		if s.rowid {
+			s.rowids[from+i] = ids[i]
+		}
+	}
+	if len(s.cache) > s.cap {
+		for k := range s.cache {
+			if k < row-s.cap/2 || k >= row+s.cap/2 {
+				delete(s.cache, k)
+				delete(s.rowids, k)
+			}
+		}
+	}
+	grew := !s.counted && to > s.rows
+	if grew {
+		s.rows = to
+	}
+	s.mu.Unlock()
+	if grew {
+		s.publish(Event{Kind: EventRowCountChanged, Rows: to})
+	}
+	return from, to, nil
+}
+
+// readPage 从第 offset 行开始读取最多 limit 行；keyed 为 true 时从 rowid 大于 after 的行续读
+func (s *SQLiteLoader) readPage(offset, limit int, after int64, keyed bool, fn func(id int64, rec []string) error) error {
+	q := s.query
+	var args []any
+	switch {
+	case s.rowid && keyed:
+		q += " WHERE rowid > ? ORDER BY rowid LIMIT ?"
+		args = []any{after, limit}
+	case s.rowid:
+		q += " ORDER BY rowid LIMIT ? OFFSET ?"
+		args = []any{limit, offset}
+	default:
+		q += " LIMIT ? OFFSET ?"
+		args = []any{limit, offset}
+	}
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	n := len(s.header)
+	if s.rowid {
+		n++
+	}
+	values := make([]any, n)
+	ptrs := make([]any, n)
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		var id int64
+		vals := values
+		if s.rowid {
+			id, _ = values[0].(int64)
+			vals = values[1:]
+		}
+		rec := make([]string, len(vals))
+		for i, v := range vals {
+			rec[i] = sqliteValueString(v)
+		}
+		if err := fn(id, rec); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// sqliteValueString 把驱动返回的值格式化为单元格文本
+func sqliteValueString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return fmt.Sprintf("x'%X'", v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999")
+	}
+	return fmt.Sprint(v)
+}
+
+// ReadRows 按页顺序读取，有 rowid 的表用 rowid 续读
+func (s *SQLiteLoader) ReadRows(from, to int, fn func(row int, rec []string) error) error {
+	const batch = 4096
+	from = max(from, 0)
+	row := from
+	var after int64
+	keyed := false
+	for to < 0 || row < to {
+		limit := batch
+		if to >= 0 {
+			limit = min(batch, to-row)
+		}
+		n := 0
+		err := s.readPage(row, limit, after, keyed, func(id int64, rec []string) error {
+			after = id
+			n++
+			return fn(row+n-1, rec)
+		})
+		if err != nil {
+			return err
+		}
+		if n < limit {
+			return nil
+		}
+		row += n
+		keyed = s.rowid
+	}
+	return nil
+}
+
+func (s *SQLiteLoader) Close() {
+	s.closeOnce.Do(func() {
+		close(s.stopCh)
+		if err := s.db.Close(); err != nil {
+			log.Println("close error:", err)
+		}
+	})
+}
+
+var _ RowSource = (*SQLiteLoader)(nil)
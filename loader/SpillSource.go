@@ -0,0 +1,250 @@
+package loader
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// SpillSource 追加写入临时文件的数据源。
+// 生产者在后台 goroutine 中 Append，视图可以边写边看；行内容只在缓存窗口中常驻内存，
+// 适用于 XLSX 工作表、连接结果等无法随机访问或超出内存的数据。
+type SpillSource struct {
+	Notifier
+
+	mu      sync.RWMutex
+	header  []string
+	cols    int
+	f       *os.File
+	out     *bufio.Writer
+	cw      *csv.Writer
+	count   *countingWriter
+	offsets []int64 // 每条记录的起始偏移，最后一个元素为已写入的末尾
+	flushed int64   // 已写入文件（可以 ReadAt）的字节数
+	done    bool
+	cache   map[int][]string
+	cap     int
+
+	lastProgress time.Time
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// NewSpillSource 创建空的数据源，数据写入系统临时目录，Close 时删除
+func NewSpillSource(header []string) (*SpillSource, error) {
+	f, err := os.CreateTemp("", "csvview-spill-*.csv")
+	if err != nil {
+		return nil, err
+	}
+	s := &SpillSource{
+		header:  header,
+		cols:    len(header),
+		f:       f,
+		out:     bufio.NewWriterSize(f, 1<<20),
+		offsets: []int64{0},
+		cache:   make(map[int][]string),
+		cap:     1024,
+	}
+	s.count = &countingWriter{w: s.out}
+	s.cw = csv.NewWriter(s.count)
+	return s, nil
+}
+
+// SetHeader 设置表头（表头来自数据第一行时在开始追加前调用）
+func (s *SpillSource) SetHeader(header []string) {
+	s.mu.Lock()
+	s.header = header
+	s.cols = max(s.cols, len(header))
+	s.mu.Unlock()
+}
+
+// Append 追加一行，rec 会被立即编码，调用方可以复用
+func (s *SpillSource) Append(rec []string) error {
+	s.mu.Lock()
+	if err := s.cw.Write(rec); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.cw.Flush()
+	if err := s.cw.Error(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.offsets = append(s.offsets, s.count.n)
+	s.cols = max(s.cols, len(rec))
+	rows := len(s.offsets) - 1
+	notify := time.Since(s.lastProgress) > 250*time.Millisecond
+	if notify {
+		s.lastProgress = time.Now()
+	}
+	s.mu.Unlock()
+	if notify {
+		s.publish(Event{Kind: EventRowCountChanged, Rows: rows})
+	}
+	return nil
+}
+
+// Finish 结束写入；err 不为空时发布 EventError
+func (s *SpillSource) Finish(err error) {
+	s.mu.Lock()
+	if ferr := s.out.Flush(); err == nil {
+		err = ferr
+	}
+	s.flushed = s.count.n
+	s.done = true
+	rows := len(s.offsets) - 1
+	s.mu.Unlock()
+	s.publish(Event{Kind: EventRowCountChanged, Rows: rows})
+	s.publish(Event{Kind: EventIndexProgress, Progress: 1, Rows: rows})
+	if err != nil {
+		s.publish(Event{Kind: EventError, Err: err})
+	}
+}
+
+func (s *SpillSource) Header() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.header
+}
+
+func (s *SpillSource) RowCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.offsets) - 1
+}
+
+func (s *SpillSource) ColCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cols
+}
+
+func (s *SpillSource) Indexed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.done
+}
+
+// GetRow 临时文件在本地磁盘上，未命中时直接同步读取一个窗口
+func (s *SpillSource) GetRow(row int) ([]string, bool) {
+	s.mu.RLock()
+	r, ok := s.cache[row]
+	count := len(s.offsets) - 1
+	s.mu.RUnlock()
+	if ok {
+		return r, true
+	}
+	if row < 0 || row >= count {
+		return nil, false
+	}
+	from := max(row-s.cap/4, 0)
+	to := min(from+s.cap/2, count)
+	records := make([][]string, 0, to-from)
+	if err := s.ReadRows(from, to, func(_ int, rec []string) error {
+		records = append(records, rec)
+		return nil
+	}); err != nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.cache)+len(records) > s.cap {
+		clear(s.cache)
+	}
+	for i, rec := range records {
+		s.cache[from+i] = rec
+	}
+	return s.cache[row], len(records) > row-from
+}
+
+func (s *SpillSource) ReadRows(from, to int, fn func(row int, rec []string) error) error {
+	s.mu.Lock()
+	if s.f == nil {
+		s.mu.Unlock()
+		return os.ErrClosed
+	}
+	count := len(s.offsets) - 1
+	if to < 0 || to > count {
+		to = count
+	}
+	from = max(from, 0)
+	if from >= to {
+		s.mu.Unlock()
+		return nil
+	}
+	// 需要读取尚在缓冲区中的数据时先落盘
+	if s.offsets[to] > s.flushed {
+		if err := s.out.Flush(); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		s.flushed = s.count.n
+	}
+	start, end := s.offsets[from], s.offsets[to]
+	offs := s.offsets[from : to+1]
+	f := s.f
+	s.mu.Unlock()
+
+	br := bufio.NewReaderSize(io.NewSectionReader(f, start, end-start), 256*1024)
+	for i := range to - from {
+		b := make([]byte, offs[i+1]-offs[i])
+		if _, err := io.ReadFull(br, b); err != nil {
+			return err
+		}
+		if err := fn(from+i, parseCSVRecord(b, ',')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Record 同步读取一行，不经过缓存，用于按任意顺序逐行读取
+func (s *SpillSource) Record(row int) ([]string, error) {
+	s.mu.Lock()
+	if s.f == nil {
+		s.mu.Unlock()
+		return nil, os.ErrClosed
+	}
+	if row < 0 || row >= len(s.offsets)-1 {
+		s.mu.Unlock()
+		return nil, io.EOF
+	}
+	if s.offsets[row+1] > s.flushed {
+		if err := s.out.Flush(); err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
+		s.flushed = s.count.n
+	}
+	start, end, f := s.offsets[row], s.offsets[row+1], s.f
+	s.mu.Unlock()
+	b := make([]byte, end-start)
+	if _, err := f.ReadAt(b, start); err != nil {
+		return nil, err
+	}
+	return parseCSVRecord(b, ','), nil
+}
+
+// Close 删除临时文件
+func (s *SpillSource) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return
+	}
+	s.f.Close()
+	os.Remove(s.f.Name())
+	s.f = nil
+}
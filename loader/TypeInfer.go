@@ -0,0 +1,178 @@
+package loader
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnType 推断出的列类型
+type ColumnType int
+
+const (
+	TypeString ColumnType = iota
+	TypeInt
+	TypeFloat
+	TypeBool
+	TypeDate
+	TypeTimestamp
+)
+
+func (t ColumnType) String() string {
+	switch t {
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeBool:
+		return "bool"
+	case TypeDate:
+		return "date"
+	case TypeTimestamp:
+		return "timestamp"
+	default:
+		return "string"
+	}
+}
+
+// DefaultInferSample 类型推断默认采样行数
+const DefaultInferSample = 1000
+
+var dateLayouts = []string{"2006-01-02", "2006/01/02", "2006.01.02", "02.01.2006", "01/02/2006"}
+
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006/01/02 15:04:05",
+}
+
+// ParseDate 按常见日期格式解析
+func ParseDate(s string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParseTimestamp 按常见时间戳格式解析
+func ParseTimestamp(s string) (time.Time, bool) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParseBool 只接受明确的布尔写法，避免把 0/1 列识别成布尔
+func ParseBool(s string) (bool, bool) {
+	switch strings.ToLower(s) {
+	case "true", "yes":
+		return true, true
+	case "false", "no":
+		return false, true
+	}
+	return false, false
+}
+
+// columnCandidates 记录一列仍然可能的类型
+type columnCandidates struct {
+	isInt, isFloat, isBool, isDate, isTimestamp bool
+	seen                                        bool
+}
+
+func (c *columnCandidates) observe(v string) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return
+	}
+	c.seen = true
+	if c.isInt {
+		_, err := strconv.ParseInt(v, 10, 64)
+		c.isInt = err == nil
+	}
+	if c.isFloat {
+		_, err := strconv.ParseFloat(v, 64)
+		c.isFloat = err == nil
+	}
+	if c.isBool {
+		_, c.isBool = ParseBool(v)
+	}
+	if c.isDate {
+		_, c.isDate = ParseDate(v)
+	}
+	if c.isTimestamp {
+		_, c.isTimestamp = ParseTimestamp(v)
+	}
+}
+
+func (c *columnCandidates) result() ColumnType {
+	switch {
+	case !c.seen:
+		return TypeString
+	case c.isInt:
+		return TypeInt
+	case c.isFloat:
+		return TypeFloat
+	case c.isBool:
+		return TypeBool
+	case c.isDate:
+		return TypeDate
+	case c.isTimestamp:
+		return TypeTimestamp
+	default:
+		return TypeString
+	}
+}
+
+// InferTypes 读取前 sample 行推断每列类型，空值不参与推断，全空的列视为字符串
+func InferTypes(src RowSource, sample int) ([]ColumnType, error) {
+	ti := NewTypeInferrer(src.ColCount())
+	err := src.ReadRows(0, sample, func(_ int, rec []string) error {
+		ti.Observe(rec)
+		return nil
+	})
+	return ti.Types(), err
+}
+
+// TypeInferrer 逐行累积的列类型推断，用于需要看完全部数据才能确定类型的场合
+type TypeInferrer struct {
+	cands []columnCandidates
+}
+
+// NewTypeInferrer 为 cols 列创建推断器
+func NewTypeInferrer(cols int) *TypeInferrer {
+	cands := make([]columnCandidates, cols)
+	for i := range cands {
+		cands[i] = columnCandidates{isInt: true, isFloat: true, isBool: true, isDate: true, isTimestamp: true}
+	}
+	return &TypeInferrer{cands: cands}
+}
+
+// Observe 用一行数据缩小各列可能的类型
+func (ti *TypeInferrer) Observe(rec []string) {
+	for i := range min(len(rec), len(ti.cands)) {
+		ti.cands[i].observe(rec[i])
+	}
+}
+
+// Types 目前为止所有行都符合的列类型
+func (ti *TypeInferrer) Types() []ColumnType {
+	types := make([]ColumnType, len(ti.cands))
+	for i := range ti.cands {
+		types[i] = ti.cands[i].result()
+	}
+	return types
+}
+
+// DetectType 单个值的类型，规则与列类型推断相同
+func DetectType(v string) ColumnType {
+	ti := NewTypeInferrer(1)
+	ti.Observe([]string{v})
+	return ti.Types()[0]
+}
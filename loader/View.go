@@ -0,0 +1,632 @@
+package loader
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Computed 由表达式等计算得到的虚拟列，读取时按行求值，不写回数据源
+type Computed struct {
+	Name string
+	// Eval 对一行求值；rec 包含底层所有列以及排在它前面的计算列
+	Eval func(rec []string) string
+	// Deps 求值用到的列（可以是前面的计算列），用于列投影
+	Deps []int
+}
+
+// SortKey 排序列
+type SortKey struct {
+	Col  int
+	Desc bool
+}
+
+// View 数据源之上的视图层：追加计算列、按条件筛选、按列排序。
+// 界面和导出都通过视图读取，筛选和排序只保存行号映射，数据仍由底层数据源按需读取。
+type View struct {
+	Notifier
+	// base 底层数据源，重新打开文件时整体替换，读取时不需要持锁
+	base        atomic.Pointer[baseRef]
+	unsubscribe func()
+
+	mu         sync.RWMutex
+	computed   []Computed
+	filter     func(rec []string) bool
+	filterText string
+	sortKeys   []SortKey
+	// index 视图行对应的底层行号；nil 表示没有筛选和排序，与底层一一对应
+	index    []int
+	building bool
+	gen      int // 每次重建加一，旧的后台扫描发现代数变化后退出
+	cache    map[int][]string
+}
+
+// NewView 在数据源之上创建视图，视图关闭时一并关闭底层数据源
+func NewView(base RowSource) *View {
+	v := &View{cache: make(map[int][]string)}
+	v.base.Store(&baseRef{base})
+	v.unsubscribe = base.Subscribe(v.handleBase)
+	return v
+}
+
+type baseRef struct{ RowSource }
+
+// Base 底层数据源
+func (v *View) Base() RowSource { return v.base.Load().RowSource }
+
+// Replace 换成新的底层数据源（如重新打开的文件）并关闭旧的，计算列、筛选和排序保留
+func (v *View) Replace(base RowSource) {
+	v.mu.Lock()
+	old, unsubscribe := v.Base(), v.unsubscribe
+	v.base.Store(&baseRef{base})
+	v.unsubscribe = base.Subscribe(v.handleBase)
+	clear(v.cache)
+	v.mu.Unlock()
+	unsubscribe()
+	old.Close()
+	v.rebuild()
+}
+
+// handleBase 转发底层事件；有行号映射时底层的行号没有意义，改为刷新整个视图
+func (v *View) handleBase(e Event) {
+	v.mu.Lock()
+	mapped := v.index != nil
+	if e.Kind == EventRowsLoaded {
+		for r := range v.cache {
+			if mapped || e.Contains(r) {
+				delete(v.cache, r)
+			}
+		}
+	}
+	rows := len(v.index)
+	v.mu.Unlock()
+	if !mapped || e.Kind == EventError || e.Kind == EventFileReplaced || e.Kind == EventFileChanged {
+		v.publish(e)
+		return
+	}
+	if e.Kind == EventRowsLoaded {
+		v.publish(Event{Kind: EventRowsLoaded, From: 0, To: rows})
+	}
+}
+
+func (v *View) Header() []string {
+	h := v.Base().Header()
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if len(v.computed) == 0 {
+		return h
+	}
+	out := make([]string, v.Base().ColCount(), v.Base().ColCount()+len(v.computed))
+	copy(out, h)
+	for _, c := range v.computed {
+		out = append(out, c.Name)
+	}
+	return out
+}
+
+func (v *View) ColCount() int {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.Base().ColCount() + len(v.computed)
+}
+
+func (v *View) RowCount() int {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if v.index != nil {
+		return len(v.index)
+	}
+	return v.Base().RowCount()
+}
+
+// Indexed 筛选或排序还在进行时为 false（排序完成前 index 仍为 nil）
+func (v *View) Indexed() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if v.building {
+		return false
+	}
+	return v.Base().Indexed()
+}
+
+// baseRow 视图行对应的底层行，调用方持有锁
+func (v *View) baseRow(row int) int {
+	if v.index == nil {
+		return row
+	}
+	if row < 0 || row >= len(v.index) {
+		return -1
+	}
+	return v.index[row]
+}
+
+// extend 在底层行后面追加计算列的值
+func (v *View) extend(rec []string, computed []Computed) []string {
+	if len(computed) == 0 {
+		return rec
+	}
+	n := v.Base().ColCount()
+	out := make([]string, max(n, len(rec)), max(n, len(rec))+len(computed))
+	copy(out, rec)
+	out = out[:n]
+	for _, c := range computed {
+		out = append(out, c.Eval(out))
+	}
+	return out
+}
+
+func (v *View) GetRow(row int) ([]string, bool) {
+	v.mu.RLock()
+	b := v.baseRow(row)
+	cached, ok := v.cache[b]
+	computed := v.computed
+	v.mu.RUnlock()
+	if b < 0 {
+		return nil, false
+	}
+	if ok {
+		return cached, true
+	}
+	rec, ok := v.Base().GetRow(b)
+	if !ok || len(computed) == 0 {
+		return rec, ok
+	}
+	rec = v.extend(rec, computed)
+	v.mu.Lock()
+	if len(v.cache) >= 4096 {
+		clear(v.cache)
+	}
+	v.cache[b] = rec
+	v.mu.Unlock()
+	return rec, true
+}
+
+// sortedReadBatch 排序视图一次读取的行数不超过这个数时在内存中重排，更多时借助临时文件
+const sortedReadBatch = 64 * 1024
+
+func (v *View) ReadRows(from, to int, fn func(row int, rec []string) error) error {
+	// 筛选或排序还在进行时，等到要读的行都已确定
+	v.mu.RLock()
+	for v.building && (to < 0 || to > len(v.index)) {
+		v.mu.RUnlock()
+		time.Sleep(50 * time.Millisecond)
+		v.mu.RLock()
+	}
+	index, computed, sorted := v.index, v.computed, len(v.sortKeys) > 0
+	v.mu.RUnlock()
+	if index == nil {
+		return v.Base().ReadRows(from, to, func(row int, rec []string) error {
+			return fn(row, v.extend(rec, computed))
+		})
+	}
+	from = max(from, 0)
+	if to < 0 || to > len(index) {
+		to = len(index)
+	}
+	if from >= to {
+		return nil
+	}
+	if !sorted {
+		// 只筛选时行号递增，顺序扫描一遍即可
+		i := from
+		err := v.Base().ReadRows(index[from], index[to-1]+1, func(row int, rec []string) error {
+			if row != index[i] {
+				return nil
+			}
+			i++
+			if err := fn(i-1, v.extend(rec, computed)); err != nil {
+				return err
+			}
+			if i == to {
+				return errStopRead
+			}
+			return nil
+		})
+		if errors.Is(err, errStopRead) {
+			return nil
+		}
+		return err
+	}
+	if to-from <= sortedReadBatch {
+		return v.readSortedBatch(index[from:to], from, computed, fn)
+	}
+	return v.readSortedSpill(index[from:to], from, computed, fn)
+}
+
+// readSortedBatch 行数不多时扫描一遍涉及的底层行，放在内存中按排序输出
+func (v *View) readSortedBatch(index []int, from int, computed []Computed, fn func(row int, rec []string) error) error {
+	want := make(map[int]int, len(index))
+	lo, hi := index[0], index[0]
+	for i, b := range index {
+		want[b] = i
+		lo, hi = min(lo, b), max(hi, b)
+	}
+	rows := make([][]string, len(index))
+	if err := v.Base().ReadRows(lo, hi+1, func(row int, rec []string) error {
+		if p, ok := want[row]; ok {
+			rows[p] = slices.Clone(rec)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for i, rec := range rows {
+		if err := fn(from+i, v.extend(rec, computed)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSortedSpill 行数多时只扫描底层一遍，按底层顺序把需要的行写入临时文件，
+// 再按排序逐行从临时文件读出，避免每批都扫描整个文件
+func (v *View) readSortedSpill(index []int, from int, computed []Computed, fn func(row int, rec []string) error) error {
+	// 临时文件中第 k 行是 bases 中的第 k 个底层行
+	bases := slices.Clone(index)
+	slices.Sort(bases)
+	spill, err := NewSpillSource(nil)
+	if err != nil {
+		return err
+	}
+	defer spill.Close()
+	k := 0
+	err = v.Base().ReadRows(bases[0], bases[len(bases)-1]+1, func(row int, rec []string) error {
+		if k < len(bases) && row == bases[k] {
+			k++
+			return spill.Append(rec)
+		}
+		return nil
+	})
+	spill.Finish(nil)
+	if err != nil {
+		return err
+	}
+	for i, b := range index {
+		pos, _ := slices.BinarySearch(bases, b)
+		rec, err := spill.Record(pos)
+		if err != nil {
+			return err
+		}
+		if err := fn(from+i, v.extend(rec, computed)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var errStopRead = errors.New("stop")
+
+// Computed 当前的计算列
+func (v *View) Computed() []Computed {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return slices.Clone(v.computed)
+}
+
+// AddComputed 追加计算列；已有的筛选和排序不受影响
+func (v *View) AddComputed(c Computed) {
+	v.mu.Lock()
+	v.computed = append(slices.Clone(v.computed), c)
+	clear(v.cache)
+	v.mu.Unlock()
+	rows := v.RowCount()
+	v.publish(Event{Kind: EventRowCountChanged, Rows: rows})
+	v.publish(Event{Kind: EventRowsLoaded, From: 0, To: rows})
+}
+
+// SetFilter 设置筛选条件，text 为条件的原文，f 为 nil 时取消筛选。
+// 筛选在后台扫描整个数据源，结果逐步出现
+func (v *View) SetFilter(text string, f func(rec []string) bool) {
+	v.mu.Lock()
+	v.filter, v.filterText = f, text
+	if f == nil {
+		v.filterText = ""
+	}
+	v.mu.Unlock()
+	v.rebuild()
+}
+
+// Filter 当前筛选条件的原文
+func (v *View) Filter() string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.filterText
+}
+
+// SetSort 按列排序（稳定排序），keys 为空时恢复原始顺序
+func (v *View) SetSort(keys []SortKey) {
+	v.mu.Lock()
+	v.sortKeys = slices.Clone(keys)
+	v.mu.Unlock()
+	v.rebuild()
+}
+
+// Sort 当前的排序列
+func (v *View) Sort() []SortKey {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return slices.Clone(v.sortKeys)
+}
+
+// rebuild 按当前的筛选和排序重新生成行号映射
+func (v *View) rebuild() {
+	v.mu.Lock()
+	v.gen++
+	gen, filter, keys, computed := v.gen, v.filter, v.sortKeys, v.computed
+	clear(v.cache)
+	if filter == nil && len(keys) == 0 {
+		v.index, v.building = nil, false
+		v.mu.Unlock()
+		rows := v.Base().RowCount()
+		v.publish(Event{Kind: EventRowCountChanged, Rows: rows})
+		v.publish(Event{Kind: EventRowsLoaded, From: 0, To: rows})
+		return
+	}
+	v.building = true
+	if len(keys) == 0 {
+		// 只筛选时结果逐步追加；排序要等扫描完成再替换
+		v.index = []int{}
+	}
+	v.mu.Unlock()
+	v.publish(Event{Kind: EventRowCountChanged, Rows: v.RowCount()})
+	go v.build(gen, filter, keys, computed)
+}
+
+// sortRow 排序时每行保留的排序列，数字预先解析
+type sortRow struct {
+	row  int
+	keys []sortValue
+}
+
+type sortValue struct {
+	s   string
+	f   float64
+	num bool
+}
+
+func (v *View) build(gen int, filter func([]string) bool, keys []SortKey, computed []Computed) {
+	// 仍在追加写入的临时表只能读到已写入的部分，先等它写完
+	if sp, ok := v.Base().(*SpillSource); ok {
+		for !sp.Indexed() {
+			if v.stale(gen) {
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	var rows []sortRow
+	var lastPublish time.Time
+	err := v.Base().ReadRows(0, -1, func(row int, rec []string) error {
+		full := v.extend(rec, computed)
+		if filter != nil && !filter(full) {
+			return nil
+		}
+		if len(keys) > 0 {
+			sr := sortRow{row: row, keys: make([]sortValue, len(keys))}
+			for i, k := range keys {
+				s := ""
+				if k.Col < len(full) {
+					s = full[k.Col]
+				}
+				f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+				sr.keys[i] = sortValue{s: s, f: f, num: err == nil}
+			}
+			rows = append(rows, sr)
+			if row%4096 == 0 && v.stale(gen) {
+				return errStopRead
+			}
+			return nil
+		}
+		v.mu.Lock()
+		if v.gen != gen {
+			v.mu.Unlock()
+			return errStopRead
+		}
+		v.index = append(v.index, row)
+		n := len(v.index)
+		v.mu.Unlock()
+		if time.Since(lastPublish) > 250*time.Millisecond {
+			lastPublish = time.Now()
+			v.publish(Event{Kind: EventRowCountChanged, Rows: n})
+		}
+		return nil
+	})
+	if errors.Is(err, errStopRead) {
+		return
+	}
+	var index []int
+	if len(keys) > 0 && err == nil {
+		slices.SortStableFunc(rows, func(a, b sortRow) int {
+			for i, k := range keys {
+				// 空值不论升序降序都排在最后
+				if ea, eb := a.keys[i].s == "", b.keys[i].s == ""; ea != eb {
+					if ea {
+						return 1
+					}
+					return -1
+				}
+				c := compareSortValues(a.keys[i], b.keys[i])
+				if k.Desc {
+					c = -c
+				}
+				if c != 0 {
+					return c
+				}
+			}
+			return 0
+		})
+		index = make([]int, len(rows))
+		for i, r := range rows {
+			index[i] = r.row
+		}
+	}
+
+	v.mu.Lock()
+	if v.gen != gen {
+		v.mu.Unlock()
+		return
+	}
+	if index != nil {
+		v.index = index
+	}
+	v.building = false
+	n := len(v.index)
+	v.mu.Unlock()
+	v.publish(Event{Kind: EventRowCountChanged, Rows: n})
+	v.publish(Event{Kind: EventRowsLoaded, From: 0, To: n})
+	v.publish(Event{Kind: EventIndexProgress, Progress: 1, Rows: n})
+	if err != nil {
+		v.publish(Event{Kind: EventError, Err: err})
+	}
+}
+
+func (v *View) stale(gen int) bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.gen != gen
+}
+
+// compareSortValues 数字按数值比较并排在文本前面
+func compareSortValues(a, b sortValue) int {
+	switch {
+	case a.num && b.num:
+		switch {
+		case a.f < b.f:
+			return -1
+		case a.f > b.f:
+			return 1
+		}
+		return 0
+	case a.num != b.num:
+		if a.num {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a.s, b.s)
+}
+
+// SetEdit 编辑映射到底层行，计算列只读
+func (v *View) SetEdit(row, col int, val string) {
+	ed, ok := v.Base().(Editor)
+	if !ok || col >= v.Base().ColCount() {
+		return
+	}
+	v.mu.RLock()
+	b := v.baseRow(row)
+	v.mu.RUnlock()
+	if b >= 0 {
+		ed.SetEdit(b, col, val)
+	}
+}
+
+// SetDeleted 把视图行号映射到底层行后标记删除
+func (v *View) SetDeleted(rows []int, deleted bool) {
+	ed, ok := v.Base().(Editor)
+	if !ok {
+		return
+	}
+	v.mu.RLock()
+	base := make([]int, 0, len(rows))
+	for _, r := range rows {
+		if b := v.baseRow(r); b >= 0 {
+			base = append(base, b)
+		}
+	}
+	v.mu.RUnlock()
+	ed.SetDeleted(base, deleted)
+}
+
+// Deleted 视图行对应的底层行是否被标记删除
+func (v *View) Deleted(row int) bool {
+	ed, ok := v.Base().(Editor)
+	if !ok {
+		return false
+	}
+	v.mu.RLock()
+	b := v.baseRow(row)
+	v.mu.RUnlock()
+	return b >= 0 && ed.Deleted(b)
+}
+
+// AsEditor 返回数据源的编辑接口；视图只有底层可编辑时才可编辑
+func AsEditor(src RowSource) (Editor, bool) {
+	if v, ok := src.(*View); ok {
+		if _, ok := v.Base().(Editor); !ok {
+			return nil, false
+		}
+		return v, true
+	}
+	ed, ok := src.(Editor)
+	return ed, ok
+}
+
+// RawRecord 返回视图行对应的底层原始记录
+func (v *View) RawRecord(row int) ([]byte, error) {
+	r, ok := v.Base().(RawRecorder)
+	if !ok {
+		return nil, errors.New("raw records are not available for this table")
+	}
+	v.mu.RLock()
+	b := v.baseRow(row)
+	v.mu.RUnlock()
+	if b < 0 {
+		return nil, errors.New("row out of range")
+	}
+	return r.RawRecord(b)
+}
+
+// SetProjection 把可见列告诉底层数据源，计算列换成它依赖的列
+func (v *View) SetProjection(cols []int) {
+	p, ok := v.Base().(ColumnProjector)
+	if !ok {
+		return
+	}
+	v.mu.RLock()
+	computed := v.computed
+	v.mu.RUnlock()
+	n := v.Base().ColCount()
+	seen := make(map[int]bool)
+	var out []int
+	var add func(c int)
+	add = func(c int) {
+		if seen[c] {
+			return
+		}
+		seen[c] = true
+		if c < n {
+			out = append(out, c)
+			return
+		}
+		if i := c - n; i < len(computed) {
+			for _, d := range computed[i].Deps {
+				add(d)
+			}
+		}
+	}
+	for _, c := range cols {
+		add(c)
+	}
+	p.SetProjection(out)
+}
+
+// Close 停止后台扫描并关闭底层数据源
+func (v *View) Close() {
+	v.mu.Lock()
+	v.gen++
+	v.building = false
+	v.mu.Unlock()
+	v.unsubscribe()
+	v.Base().Close()
+}
+
+var (
+	_ RowSource       = (*View)(nil)
+	_ Editor          = (*View)(nil)
+	_ RawRecorder     = (*View)(nil)
+	_ ColumnProjector = (*View)(nil)
+)
@@ -0,0 +1,77 @@
+package loader
+
+import (
+	"log"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Sheet 文件中可以单独打开的一张表，例如 XLSX 的一个工作表
+type Sheet struct {
+	Name   string
+	Source RowSource
+}
+
+// OpenXLSX 打开工作簿，每个可见工作表对应一个数据源。
+// 工作表 XML 在后台按行流式解析并写入临时文件，打开大工作表时不会整体读入内存。
+func OpenXLSX(path string) ([]Sheet, error) {
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sheets []Sheet
+	var spills []*SpillSource
+	for _, name := range f.GetSheetList() {
+		if visible, err := f.GetSheetVisible(name); err == nil && !visible {
+			continue
+		}
+		s, err := NewSpillSource(nil)
+		if err != nil {
+			for _, s := range spills {
+				s.Close()
+			}
+			f.Close()
+			return nil, err
+		}
+		sheets = append(sheets, Sheet{Name: name, Source: s})
+		spills = append(spills, s)
+	}
+
+	// excelize 的 File 不支持并发读取，按顺序解析各个工作表
+	go func() {
+		defer f.Close()
+		for i, sheet := range sheets {
+			err := streamSheet(f, sheet.Name, spills[i])
+			if err != nil {
+				log.Println("xlsx sheet", sheet.Name, "error:", err)
+			}
+			spills[i].Finish(err)
+		}
+	}()
+	return sheets, nil
+}
+
+// streamSheet 第一行作为表头，其余行追加到 s
+func streamSheet(f *excelize.File, name string, s *SpillSource) error {
+	rows, err := f.Rows(name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	first := true
+	for rows.Next() {
+		rec, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+		if first {
+			s.SetHeader(rec)
+			first = false
+			continue
+		}
+		if err := s.Append(rec); err != nil {
+			return err
+		}
+	}
+	return rows.Error()
+}
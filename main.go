@@ -1,17 +1,26 @@
 package main
 
 import (
-	"errors"
+	"fmt"
 	"log"
-	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/export"
+	"github.com/devbiu/CsvView/ipc"
 	"github.com/devbiu/CsvView/loader"
+	"github.com/devbiu/CsvView/ops"
 	"github.com/devbiu/CsvView/shower"
 )
 
@@ -19,28 +28,256 @@ var topWindow fyne.Window
 
 var tabs *container.DocTabs
 
+// cliOptions 命令行参数
+type cliOptions struct {
+	files       []string
+	row         int
+	col         int
+	debug       bool
+	newInstance bool
+}
+
+// parseArgs 解析命令行参数，允许文件与 --row/--col 任意顺序出现
+//
+//	csvview file1.csv file2.tsv --row 100 --col 3
+func parseArgs(args []string) (cliOptions, error) {
+	var opts cliOptions
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			opts.files = append(opts.files, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			opts.files = append(opts.files, argPath(arg))
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		switch name {
+		case "debug":
+			opts.debug = true
+		case "new-instance":
+			opts.newInstance = true
+		case "row", "col":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return opts, fmt.Errorf("flag --%s needs a value", name)
+				}
+				i++
+				value = args[i]
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return opts, fmt.Errorf("flag --%s: invalid position %q", name, value)
+			}
+			if name == "row" {
+				opts.row = n
+			} else {
+				opts.col = n
+			}
+		default:
+			return opts, fmt.Errorf("unknown flag %s", arg)
+		}
+	}
+	return opts, nil
+}
+
+// argPath 把文件管理器以 file:// URI 传入的参数（桌面文件中的 %U）转换为本地路径
+func argPath(arg string) string {
+	u, err := url.Parse(arg)
+	if err != nil || u.Scheme != "file" || u.Path == "" {
+		return arg
+	}
+	p := filepath.FromSlash(u.Path)
+	// Windows 的 file:///C:/a.csv 解析出的路径带前导斜杠
+	if v := filepath.VolumeName(p[1:]); v != "" {
+		p = p[1:]
+	}
+	return p
+}
+
 func main() {
+	if runSubcommand(os.Args[1:]) {
+		return
+	}
+	opts, err := parseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, "usage: csvview [--row N] [--col M] [--debug] [--new-instance] [file ...]")
+		os.Exit(2)
+	}
+
+	var reqs []ipc.Request
+	for _, p := range opts.files {
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+		reqs = append(reqs, ipc.Request{Path: p, Row: opts.row, Col: opts.col})
+	}
+	// 已有实例在运行时把文件交给它打开，避免再起一个占内存的加载器
+	if !opts.newInstance && !opts.debug {
+		if err := ipc.Forward(reqs); err == nil {
+			return
+		}
+	}
 
 	a := app.NewWithID("devbiu.csvView")
 	//a := app.New()
 	w := a.NewWindow("CsvView")
 	topWindow = w
 	w.SetMainMenu(makeMenu(a, w))
-	l, _ := loader.NewCsvLoaderV2("./data/test_student.csv", 1024)
-	table := shower.NewVirtualTable(l)
-	w.SetContent(table.Scroll)
+	tabs = container.NewDocTabs()
+	tabs.OnClosed = func(item *container.TabItem) {
+		if vt, ok := tabTables[item]; ok {
+			vt.Close()
+			delete(tabTables, item)
+			delete(tableInspectors, vt)
+		}
+	}
+	w.SetContent(tabs)
 	w.Resize(fyne.NewSize(1000, 700))
 
-	d := fyne.CurrentApp().NewWindow("debugWindow")
-	d.SetContent(shower.DebugTable(l))
-	d.Resize(fyne.NewSize(500, 100))
-	d.Show()
+	// 从文件管理器拖入的文件直接在新标签页中打开
+	w.SetOnDropped(func(_ fyne.Position, uris []fyne.URI) {
+		for _, u := range uris {
+			openFile(w, ipc.Request{Path: u.Path()})
+		}
+	})
+
+	for _, r := range reqs {
+		openFile(w, r)
+	}
+
+	if !opts.newInstance {
+		srv, err := ipc.Listen(func(r ipc.Request) {
+			fyne.Do(func() {
+				if r.Path != "" {
+					openFile(w, r)
+				}
+				w.RequestFocus()
+			})
+		})
+		if err != nil {
+			log.Println("single instance disabled:", err)
+		} else {
+			defer srv.Close()
+		}
+	}
+
+	if opts.debug {
+		if l, ok := baseSource(currentSource()).(*loader.CSVLoader); ok {
+			d := fyne.CurrentApp().NewWindow("debugWindow")
+			d.SetContent(shower.DebugTable(l))
+			d.Resize(fyne.NewSize(500, 100))
+			d.Show()
+		}
+	}
 
 	w.ShowAndRun()
 }
 
+// openFile 使用流式加载器在新的 DocTab 中打开文件，并按需跳转到指定位置。
+// XLSX 等多表文件每张表一个标签页，跳转只作用于第一张表。
+func openFile(w fyne.Window, req ipc.Request) {
+	path, err := filepath.Abs(req.Path)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	sheets, err := loader.OpenSheets(path)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	for i, sheet := range sheets {
+		title := filepath.Base(path)
+		if len(sheets) > 1 || sheet.Name != title {
+			title += " [" + sheet.Name + "]"
+		}
+		vt := openTab(w, title, sheet.Source)
+		// 列布局按文件（多表文件按表）保存
+		key := path
+		if len(sheets) > 1 {
+			key += " [" + sheet.Name + "]"
+		}
+		vt.SetLayoutKey(key)
+		if i == 0 && (req.Row > 0 || req.Col > 0) {
+			vt.GoTo(max(req.Row-1, 0), max(req.Col-1, 0))
+		}
+	}
+}
+
+// openTab 为数据源创建视图和虚拟表格，并作为新标签页选中
+func openTab(w fyne.Window, title string, l loader.RowSource) *shower.VirtualTable {
+	view := loader.NewView(l)
+	vt := shower.NewVirtualTable(view)
+	var errOnce sync.Once
+	view.Subscribe(func(e loader.Event) {
+		if e.Kind == loader.EventError {
+			errOnce.Do(func() {
+				fyne.Do(func() { dialog.ShowError(e.Err, w) })
+			})
+		}
+	})
+	var content fyne.CanvasObject = vt.Scroll
+	switch src := l.(type) {
+	case *ops.DiffResult:
+		content = shower.NewDiffView(vt, src)
+	case loader.RawRecorder:
+		// 原始记录按视图行号读取，筛选和排序后仍对应选中的行
+		content = shower.NewRawDetail(vt, view)
+	}
+	inspector := shower.NewCellInspector(vt)
+	tableInspectors[vt] = inspector
+	content = container.NewBorder(nil, shower.NewStatusBar(vt), nil, nil, inspector.Wrap(content))
+	if _, ok := l.(loader.Follower); ok {
+		content = shower.NewFileBar(w, vt, view, content)
+	}
+	item := container.NewTabItem(title, content)
+	tabTables[item] = vt
+	tabs.Append(item)
+	tabs.Select(item)
+	return vt
+}
+
+// tabTables 记录 DocTab 与其虚拟表格的对应关系
+var tabTables = map[*container.TabItem]*shower.VirtualTable{}
+
+// tableInspectors 每个表格旁边的单元格详情面板
+var tableInspectors = map[*shower.VirtualTable]*shower.CellInspector{}
+
+// currentSource 返回当前选中标签页的数据源
+func currentSource() loader.RowSource {
+	if vt := currentTable(); vt != nil {
+		return vt.Source()
+	}
+	return nil
+}
+
+// baseSource 返回视图背后的数据源
+func baseSource(src loader.RowSource) loader.RowSource {
+	if v, ok := src.(*loader.View); ok {
+		return v.Base()
+	}
+	return src
+}
+
+// currentView 返回当前选中标签页的视图
+func currentView() *loader.View {
+	v, _ := currentSource().(*loader.View)
+	return v
+}
+
+// currentTable 返回当前选中标签页的虚拟表格
+func currentTable() *shower.VirtualTable {
+	if tabs == nil || tabs.Selected() == nil {
+		return nil
+	}
+	return tabTables[tabs.Selected()]
+}
+
 func makeMenu(a fyne.App, w fyne.Window) *fyne.MainMenu {
-	file := fyne.NewMenu("File", fyne.NewMenuItem("Open CSV", func() {
+	file := fyne.NewMenu("File", fyne.NewMenuItem("Open...", func() {
 		fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
 			if err != nil {
 				dialog.ShowError(err, w)
@@ -50,21 +287,98 @@ func makeMenu(a fyne.App, w fyne.Window) *fyne.MainMenu {
 				log.Println("Cancelled")
 				return
 			}
-			csvData, err := loader.CsvOpened(reader)
+			// 只取路径，内容交给流式加载器按需读取
+			path := reader.URI().Path()
+			reader.Close()
+			openFile(w, ipc.Request{Path: path})
+		}, w)
+		fd.SetFilter(storage.NewExtensionFileFilter(loader.OpenExtensions))
+		fd.Show()
+	}))
+
+	// 定长文本先在边界编辑器中确定列布局再打开
+	fixedItem := fyne.NewMenuItem("Open Fixed-Width...", func() {
+		fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
 			if err != nil {
 				dialog.ShowError(err, w)
+				return
 			}
-			if csvData == nil {
-				dialog.ShowError(errors.New("csv file empty"), w)
-			} else if len(csvData) == 0 || len(csvData[0]) == 0 {
-				dialog.ShowError(errors.New("file format is incorrect "), w)
-			} else {
-				showCsvWindow(a, w, reader.URI().Name(), csvData)
+			if reader == nil {
+				return
 			}
+			path := reader.URI().Path()
+			reader.Close()
+			shower.ShowFixedWidthDialog(w, path, func(spec loader.FixedWidthSpec) {
+				l, err := loader.NewFixedWidthLoader(path, spec, 1024)
+				if err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				openTab(w, filepath.Base(path), l)
+			})
 		}, w)
-		fd.SetFilter(storage.NewExtensionFileFilter([]string{".csv"}))
 		fd.Show()
-	}))
+	})
+	file.Items = append(file.Items, fixedItem)
+
+	sqliteItem := fyne.NewMenuItem("Open SQLite...", func() {
+		fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			path := reader.URI().Path()
+			reader.Close()
+			shower.ShowSQLiteTables(w, path, func(table string) {
+				l, err := loader.NewSQLiteLoader(path, table, 1024)
+				if err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				openTab(w, filepath.Base(path)+" ["+table+"]", l)
+			})
+		}, w)
+		fd.SetFilter(storage.NewExtensionFileFilter([]string{".db", ".sqlite", ".sqlite3"}))
+		fd.Show()
+	})
+	file.Items = append(file.Items, sqliteItem)
+
+	// 拆分和合并直接处理文件，不需要先打开
+	splitItem := fyne.NewMenuItem("Split File...", func() {
+		fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			path := reader.URI().Path()
+			reader.Close()
+			shower.ShowSplitDialog(w, path)
+		}, w)
+		fd.SetFilter(storage.NewExtensionFileFilter([]string{".csv", ".tsv", ".tab", ".txt"}))
+		fd.Show()
+	})
+	mergeItem := fyne.NewMenuItem("Merge Files...", func() {
+		shower.ShowMergeDialog(w, func(title string, res *loader.SpillSource) {
+			openTab(w, title, res)
+		})
+	})
+	file.Items = append(file.Items, splitItem, mergeItem)
+
+	exportItem := fyne.NewMenuItem("Export...", func() {
+		vt := currentTable()
+		if vt == nil {
+			dialog.ShowInformation("Export", "Open a file first", w)
+			return
+		}
+		shower.ShowExportDialog(w, vt, tabs.Selected().Text)
+	})
+	file.Items = append(file.Items, exportItem)
 
 	showAbout := func() {
 		w := a.NewWindow("About")
@@ -77,47 +391,231 @@ func makeMenu(a fyne.App, w fyne.Window) *fyne.MainMenu {
 
 	main := fyne.NewMainMenu(
 		file,
+		makeEditMenu(w),
+		makeViewMenu(w),
+		makeDataMenu(w),
 	)
 	return main
 }
 
-func showCsvWindow(a fyne.App, w fyne.Window, lebName string, csvData [][]string) {
-	if tabs == nil {
-		tabs = container.NewDocTabs(container.NewTabItem(lebName, ShowCsvTab(w, csvData)))
-	} else {
-		tabs.Append(container.NewTabItem(lebName, ShowCsvTab(w, csvData)))
+// makeEditMenu 复制、粘贴和全选作用于当前标签页的表格
+func makeEditMenu(w fyne.Window) *fyne.Menu {
+	copyItem := fyne.NewMenuItem("Copy", func() { copySelection(w, export.FormatTSV) })
+	copyItem.Shortcut = &fyne.ShortcutCopy{}
+	edit := fyne.NewMenu("Edit", copyItem,
+		fyne.NewMenuItem("Copy as CSV", func() { copySelection(w, export.FormatCSV) }),
+		fyne.NewMenuItem("Copy as Markdown", func() { copySelection(w, export.FormatMarkdown) }),
+		fyne.NewMenuItem("Copy as JSON", func() { copySelection(w, export.FormatJSON) }),
+	)
+	pasteItem := fyne.NewMenuItem("Paste", func() { pasteClipboard(w) })
+	pasteItem.Shortcut = &fyne.ShortcutPaste{}
+	selectAllItem := fyne.NewMenuItem("Select All", func() {
+		if vt := currentTable(); vt != nil {
+			vt.SelectAll()
+		}
+	})
+	selectAllItem.Shortcut = &fyne.ShortcutSelectAll{}
+	edit.Items = append(edit.Items, fyne.NewMenuItemSeparator(), pasteItem, selectAllItem)
+
+	// 标记删除只记录在编辑中，导出时跳过这些行
+	markDeleted := func(deleted bool) func() {
+		return func() {
+			vt := currentTable()
+			if vt == nil {
+				return
+			}
+			if err := vt.SetDeleted(nil, deleted); err != nil {
+				dialog.ShowError(err, w)
+			}
+		}
 	}
+	edit.Items = append(edit.Items, fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Mark Rows Deleted", markDeleted(true)),
+		fyne.NewMenuItem("Unmark Deleted Rows", markDeleted(false)),
+	)
 
-	w.SetContent(tabs)
-	//max := container.NewMax(tabs)
-	//w.SetContent(max)
-}
-
-func ShowCsvTab(w fyne.Window, data [][]string) fyne.CanvasObject {
-	table := widget.NewTableWithHeaders(
-		func() (int, int) { return len(data), len(data[0]) + 1 },
-		func() fyne.CanvasObject {
-			label := widget.NewLabel("Cell 000, 000")
-			label.Truncation = fyne.TextTruncateEllipsis
-			label.Selectable = true
-			return label
-		},
-		func(id widget.TableCellID, cell fyne.CanvasObject) {
-			label := cell.(*widget.Label)
-			//label := cell.(*widget.Entry)
-			switch id.Col {
-			case len(data[id.Col]):
-				label.SetText("")
-			default:
-				label.SetText(data[id.Row][id.Col])
+	for _, item := range []*fyne.MenuItem{copyItem, pasteItem, selectAllItem} {
+		action := item.Action
+		w.Canvas().AddShortcut(item.Shortcut, func(fyne.Shortcut) { action() })
+	}
+	return edit
+}
+
+// copySelection 把当前选区按格式复制到剪贴板
+func copySelection(w fyne.Window, f export.Format) {
+	vt := currentTable()
+	if vt == nil {
+		return
+	}
+	text, err := vt.SelectionText(f)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	fyne.CurrentApp().Clipboard().SetContent(text)
+}
+
+// pasteClipboard 把剪贴板中的 TSV 粘贴到活动单元格开始的区域
+func pasteClipboard(w fyne.Window) {
+	vt := currentTable()
+	if vt == nil {
+		return
+	}
+	if err := vt.Paste(fyne.CurrentApp().Clipboard().Content()); err != nil {
+		dialog.ShowError(err, w)
+	}
+}
+
+// makeViewMenu 跳转、单元格详情面板，以及列的显示、顺序、冻结和宽度（按文件保存）
+func makeViewMenu(w fyne.Window) *fyne.Menu {
+	withTable := func(fn func(vt *shower.VirtualTable) error) func() {
+		return func() {
+			vt := currentTable()
+			if vt == nil {
+				return
+			}
+			if err := fn(vt); err != nil {
+				dialog.ShowError(err, w)
 			}
+		}
+	}
+	goTo := fyne.NewMenuItem("Go To...", withTable(func(vt *shower.VirtualTable) error {
+		shower.ShowGoToDialog(w, vt)
+		return nil
+	}))
+	goTo.Shortcut = &desktop.CustomShortcut{KeyName: fyne.KeyG, Modifier: fyne.KeyModifierShortcutDefault}
+	inspect := fyne.NewMenuItem("Cell Inspector", withTable(func(vt *shower.VirtualTable) error {
+		tableInspectors[vt].Toggle()
+		return nil
+	}))
+	inspect.Shortcut = &desktop.CustomShortcut{KeyName: fyne.KeyI, Modifier: fyne.KeyModifierShortcutDefault}
+	for _, item := range []*fyne.MenuItem{goTo, inspect} {
+		action := item.Action
+		w.Canvas().AddShortcut(item.Shortcut, func(fyne.Shortcut) { action() })
+	}
+	return fyne.NewMenu("View",
+		goTo,
+		inspect,
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Columns...", withTable(func(vt *shower.VirtualTable) error {
+			shower.ShowColumnsDialog(w, vt)
+			return nil
+		})),
+		fyne.NewMenuItem("Hide Selected Columns", withTable(shower.HideSelectedColumns)),
+		fyne.NewMenuItem("Auto-fit Column Widths", withTable(func(vt *shower.VirtualTable) error {
+			vt.AutoFitColumns()
+			return nil
+		})),
+		fyne.NewMenuItem("Freeze Up To Selected Column", withTable(func(vt *shower.VirtualTable) error {
+			shower.FreezeToSelection(vt)
+			return nil
+		})),
+		fyne.NewMenuItem("Unfreeze Columns", withTable(func(vt *shower.VirtualTable) error {
+			vt.SetFrozen(0)
+			return nil
+		})),
+	)
+}
+
+// makeDataMenu 比较、连接、透视等生成新标签页的数据处理
+func makeDataMenu(w fyne.Window) *fyne.Menu {
+	// 比较两个已打开的标签页，结果在新标签页中打开
+	compareItem := fyne.NewMenuItem("Compare...", func() {
+		var sources []shower.CompareSource
+		for _, item := range tabs.Items {
+			if vt, ok := tabTables[item]; ok {
+				sources = append(sources, shower.CompareSource{Title: item.Text, Source: vt.Source()})
+			}
+		}
+		shower.ShowCompareDialog(w, sources, func(title string, res *ops.DiffResult) {
+			openTab(w, title, res)
 		})
+	})
 
-	setWidthInd := int(math.Min(float64(len(data)), 2))
-	for i := range len(data[setWidthInd]) {
-		table.SetColumnWidth(i, float32(len(data[setWidthInd][i])*8)+50)
-	}
+	// 把另一个文件按键连接到当前标签页，结果在新标签页中打开
+	joinItem := fyne.NewMenuItem("Join with File...", func() {
+		vt := currentTable()
+		if vt == nil {
+			dialog.ShowInformation("Join", "Open a file first", w)
+			return
+		}
+		title := tabs.Selected().Text
+		fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			path := reader.URI().Path()
+			reader.Close()
+			right, err := loader.Open(path)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			shower.ShowJoinDialog(w, vt.Source(), right, func(res *loader.SpillSource) {
+				openTab(w, title+" + "+filepath.Base(path), res)
+			})
+		}, w)
+		fd.SetFilter(storage.NewExtensionFileFilter(loader.OpenExtensions))
+		fd.Show()
+	})
+
+	// 对当前标签页做分组聚合
+	pivotItem := fyne.NewMenuItem("Pivot...", func() {
+		vt := currentTable()
+		if vt == nil {
+			dialog.ShowInformation("Pivot", "Open a file first", w)
+			return
+		}
+		title := tabs.Selected().Text
+		shower.ShowPivotDialog(w, vt.Source(), func(res *loader.MemSource) {
+			openTab(w, title+" (pivot)", res)
+		})
+	})
 
-	return table
+	// 找出当前标签页中的重复行
+	dedupItem := fyne.NewMenuItem("Deduplicate...", func() {
+		vt := currentTable()
+		if vt == nil {
+			dialog.ShowInformation("Deduplicate", "Open a file first", w)
+			return
+		}
+		shower.ShowDedupDialog(w, vt, tabs.Selected().Text)
+	})
 
+	// 计算列、筛选和排序作用于当前标签页的视图，底层文件不变
+	withView := func(title string, fn func(view *loader.View)) func() {
+		return func() {
+			view := currentView()
+			if view == nil {
+				dialog.ShowInformation(title, "Open a file first", w)
+				return
+			}
+			fn(view)
+		}
+	}
+	addColumnItem := fyne.NewMenuItem("Add Column...", withView("Add Column", func(view *loader.View) {
+		shower.ShowComputedDialog(w, view)
+	}))
+	filterItem := fyne.NewMenuItem("Filter...", withView("Filter", func(view *loader.View) {
+		shower.ShowFilterDialog(w, view)
+	}))
+	sortBy := func(desc bool) func(*loader.View) {
+		return func(*loader.View) {
+			if err := shower.SortBy(currentTable(), desc); err != nil {
+				dialog.ShowError(err, w)
+			}
+		}
+	}
+	sortAscItem := fyne.NewMenuItem("Sort Ascending", withView("Sort", sortBy(false)))
+	sortDescItem := fyne.NewMenuItem("Sort Descending", withView("Sort", sortBy(true)))
+	clearItem := fyne.NewMenuItem("Clear Filter and Sort", withView("Sort", func(view *loader.View) {
+		view.SetFilter("", nil)
+		view.SetSort(nil)
+	}))
+	return fyne.NewMenu("Data", compareItem, joinItem, pivotItem, dedupItem, fyne.NewMenuItemSeparator(),
+		addColumnItem, filterItem, sortAscItem, sortDescItem, clearItem)
 }
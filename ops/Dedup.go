@@ -0,0 +1,228 @@
+package ops
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/devbiu/CsvView/loader"
+)
+
+// Keep 重复的行中保留哪一行
+type Keep string
+
+const (
+	KeepFirst Keep = "first"
+	KeepLast  Keep = "last"
+	// KeepNone 有重复的键一行都不保留
+	KeepNone Keep = "none"
+)
+
+// Keeps 界面上可选的保留方式
+var Keeps = []Keep{KeepFirst, KeepLast, KeepNone}
+
+// DedupOptions 去重参数
+type DedupOptions struct {
+	// Keys 判断重复的列，为空时比较整行
+	Keys []string
+	// Trim 比较前去掉首尾空白；FoldCase 比较时不区分大小写
+	Trim, FoldCase bool
+	Keep           Keep
+	// MemRows 行数超过它时键先按哈希分区写入临时文件，默认 DefaultMemRows
+	MemRows  int
+	Progress func(done int)
+	Cancel   <-chan struct{}
+}
+
+// DedupResult 去重结果：按行号记录哪些行是要去掉的重复行
+type DedupResult struct {
+	Rows       int // 扫描的行数
+	Duplicates int // 要去掉的行数
+	dup        []uint64
+}
+
+// IsDuplicate 第 row 行是否是要去掉的重复行
+func (r *DedupResult) IsDuplicate(row int) bool {
+	i := row / 64
+	return row >= 0 && i < len(r.dup) && r.dup[i]&(1<<(row%64)) != 0
+}
+
+// DuplicateRows 所有要去掉的行号，按升序
+func (r *DedupResult) DuplicateRows() []int {
+	rows := make([]int, 0, r.Duplicates)
+	for row := range r.Rows {
+		if r.IsDuplicate(row) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func (r *DedupResult) mark(row int) {
+	i := row / 64
+	for len(r.dup) <= i {
+		r.dup = append(r.dup, 0)
+	}
+	if r.dup[i]&(1<<(row%64)) == 0 {
+		r.dup[i] |= 1 << (row % 64)
+		r.Duplicates++
+	}
+}
+
+// Preview 读出前 n 行重复行，第一列为行号（从 1 开始），用于确认前预览
+func (r *DedupResult) Preview(src loader.RowSource, n int) (*loader.MemSource, error) {
+	header := append([]string{"row"}, src.Header()...)
+	var rows [][]string
+	if r.Duplicates == 0 {
+		return loader.NewMemSource(header, rows), nil
+	}
+	err := src.ReadRows(0, -1, func(row int, rec []string) error {
+		if !r.IsDuplicate(row) {
+			return nil
+		}
+		rows = append(rows, append([]string{strconv.Itoa(row + 1)}, rec...))
+		if len(rows) >= n {
+			return errStopPreview
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopPreview) {
+		return nil, err
+	}
+	return loader.NewMemSource(header, rows), nil
+}
+
+var errStopPreview = errors.New("stop")
+
+// dedupRow 参与比较的行：规范化后的键和行号
+type dedupRow struct {
+	key string
+	row int
+}
+
+// Dedup 顺序扫描 src 找出重复行。行数在 MemRows 以内时键的集合放在内存中，
+// 否则键和行号按哈希分区写入临时文件，同一个键落在同一分区，再逐个分区判断
+func Dedup(src loader.RowSource, opts DedupOptions) (*DedupResult, error) {
+	if opts.MemRows <= 0 {
+		opts.MemRows = DefaultMemRows
+	}
+	if opts.Keep == "" {
+		opts.Keep = KeepFirst
+	}
+	s := &scanner{cancel: opts.Cancel, progress: opts.Progress}
+	idx := columnIndex(src.Header())
+	var cols []int
+	for _, k := range opts.Keys {
+		c := lookup(idx, k)
+		if c < 0 {
+			return nil, fmt.Errorf("column %q not found", k)
+		}
+		cols = append(cols, c)
+	}
+	if cols == nil {
+		cols = make([]int, src.ColCount())
+		for i := range cols {
+			cols[i] = i
+		}
+	}
+	parts := make([]string, len(cols))
+	normalize := func(rec []string) string {
+		for i, c := range cols {
+			v := field(rec, c)
+			if opts.Trim {
+				v = strings.TrimSpace(v)
+			}
+			if opts.FoldCase {
+				v = strings.ToLower(v)
+			}
+			parts[i] = v
+		}
+		return strings.Join(parts, "\x00")
+	}
+
+	res := &DedupResult{}
+	if src.Indexed() && src.RowCount() <= opts.MemRows {
+		rows := make([]dedupRow, 0, src.RowCount())
+		if err := s.scan(src, func(rec []string) error {
+			rows = append(rows, dedupRow{normalize(rec), len(rows)})
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		res.Rows = len(rows)
+		res.markGroup(rows, opts.Keep)
+		return res, nil
+	}
+
+	n := 16
+	if src.Indexed() {
+		n = min(max(2*src.RowCount()/opts.MemRows+1, 2), maxPartitions)
+	}
+	spills := make([]*loader.SpillSource, 0, n)
+	defer func() { closeAll(spills) }()
+	for range n {
+		p, err := loader.NewSpillSource([]string{"key", "row"})
+		if err != nil {
+			return nil, err
+		}
+		spills = append(spills, p)
+	}
+	row := 0
+	if err := s.scan(src, func(rec []string) error {
+		k := normalize(rec)
+		h := fnv.New32a()
+		h.Write([]byte(k))
+		err := spills[h.Sum32()%uint32(n)].Append([]string{k, strconv.Itoa(row)})
+		row++
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	res.Rows = row
+	for _, p := range spills {
+		if err := s.canceled(); err != nil {
+			return nil, err
+		}
+		var rows []dedupRow
+		if err := p.ReadRows(0, -1, func(_ int, rec []string) error {
+			r, err := strconv.Atoi(rec[1])
+			if err != nil {
+				return err
+			}
+			rows = append(rows, dedupRow{rec[0], r})
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		res.markGroup(rows, opts.Keep)
+	}
+	return res, nil
+}
+
+// markGroup 在一组按行号升序排列的行中标记重复行
+func (r *DedupResult) markGroup(rows []dedupRow, keep Keep) {
+	type seen struct{ first, last, count int }
+	groups := make(map[string]*seen, len(rows))
+	for _, dr := range rows {
+		if g, ok := groups[dr.key]; ok {
+			g.last = dr.row
+			g.count++
+		} else {
+			groups[dr.key] = &seen{dr.row, dr.row, 1}
+		}
+	}
+	for _, dr := range rows {
+		g := groups[dr.key]
+		if g.count == 1 {
+			continue
+		}
+		switch {
+		case keep == KeepFirst && dr.row != g.first,
+			keep == KeepLast && dr.row != g.last,
+			keep == KeepNone:
+			r.mark(dr.row)
+		}
+	}
+}
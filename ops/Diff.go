@@ -0,0 +1,199 @@
+package ops
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/devbiu/CsvView/loader"
+)
+
+// 比较结果每一行的状态
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// DiffOptions 比较选项
+type DiffOptions struct {
+	// Keys 键列名，两边都必须存在；键相同的行视为同一条记录
+	Keys []string
+	// MemRows 每次在内存中建立索引的最大行数，0 为 DefaultMemRows
+	MemRows int
+	// Progress 每读取一批行回调一次，done 为两边已读取的总行数
+	Progress func(done int)
+	// Cancel 关闭后比较以 ErrCanceled 结束
+	Cancel <-chan struct{}
+}
+
+// DiffResult 比较结果。每行为 status、键列，以及每个比较列的旧值和新值（相邻两列）；
+// 没有变化的行不出现在结果中
+type DiffResult struct {
+	*loader.SpillSource
+	Keys    []string
+	Columns []string // 比较的非键列，按旧文件列顺序、再接新文件独有的列
+
+	Added, Removed, Changed int
+}
+
+// Cell 返回结果列 col 对应的比较列序号和是否为新值；status 和键列返回 -1
+func (r *DiffResult) Cell(col int) (column int, isNew bool) {
+	col -= 1 + len(r.Keys)
+	// 状态列、键列以及结果之后追加的计算列都不是比较列
+	if col < 0 || col/2 >= len(r.Columns) {
+		return -1, false
+	}
+	return col / 2, col%2 == 1
+}
+
+// CellChanged 结果行中第 column 个比较列的旧值和新值是否不同
+func (r *DiffResult) CellChanged(rec []string, column int) bool {
+	i := 1 + len(r.Keys) + 2*column
+	return len(rec) > i+1 && rec[0] == DiffChanged && rec[i] != rec[i+1]
+}
+
+// diffPlan 两边列的对应关系
+type diffPlan struct {
+	oldKeys, newKeys []int
+	oldCols, newCols []int // 比较列在两边的下标，-1 表示这一边没有该列
+}
+
+// Diff 按键比较 oldSrc 和 newSrc，找出新增、删除和修改的行。
+// 同一个键出现多次时按出现顺序一一配对，多出的行为新增或删除。
+func Diff(oldSrc, newSrc loader.RowSource, opts DiffOptions) (*DiffResult, error) {
+	if len(opts.Keys) == 0 {
+		return nil, errors.New("no key columns")
+	}
+	if opts.MemRows <= 0 {
+		opts.MemRows = DefaultMemRows
+	}
+	var p diffPlan
+	oldIdx, newIdx := columnIndex(oldSrc.Header()), columnIndex(newSrc.Header())
+	for _, k := range opts.Keys {
+		oi, ok1 := oldIdx[k]
+		ni, ok2 := newIdx[k]
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("key column %q must exist in both files", k)
+		}
+		p.oldKeys = append(p.oldKeys, oi)
+		p.newKeys = append(p.newKeys, ni)
+	}
+	res := &DiffResult{Keys: opts.Keys}
+	isKey := make(map[string]bool)
+	for _, k := range opts.Keys {
+		isKey[k] = true
+	}
+	addColumn := func(name string) {
+		if isKey[name] {
+			return
+		}
+		isKey[name] = true
+		res.Columns = append(res.Columns, name)
+		p.oldCols = append(p.oldCols, lookup(oldIdx, name))
+		p.newCols = append(p.newCols, lookup(newIdx, name))
+	}
+	for _, h := range oldSrc.Header() {
+		addColumn(h)
+	}
+	for _, h := range newSrc.Header() {
+		addColumn(h)
+	}
+
+	header := append([]string{"status"}, opts.Keys...)
+	for _, c := range res.Columns {
+		header = append(header, "old."+c, "new."+c)
+	}
+	spill, err := loader.NewSpillSource(header)
+	if err != nil {
+		return nil, err
+	}
+	res.SpillSource = spill
+
+	d := &differ{scanner: scanner{cancel: opts.Cancel, progress: opts.Progress}, plan: p, res: res}
+	err = d.hashJoin(oldSrc, newSrc, p.oldKeys, p.newKeys, opts.MemRows, d.match)
+	spill.Finish(err)
+	if err != nil {
+		spill.Close()
+		return nil, err
+	}
+	return res, nil
+}
+
+type differ struct {
+	scanner
+	plan diffPlan
+	res  *DiffResult
+}
+
+// match 用旧行建立索引，按新行的顺序输出修改和新增的行，最后按旧行顺序输出删除的行
+func (d *differ) match(oldRows [][]string, eachNew func(fn func(rec []string) error) error) error {
+	index := make(map[string][]int, len(oldRows))
+	for i, rec := range oldRows {
+		k := key(rec, d.plan.oldKeys)
+		index[k] = append(index[k], i)
+	}
+	matched := make([]bool, len(oldRows))
+	if err := eachNew(func(rec []string) error {
+		k := key(rec, d.plan.newKeys)
+		if rows := index[k]; len(rows) > 0 {
+			index[k] = rows[1:]
+			matched[rows[0]] = true
+			return d.emit(oldRows[rows[0]], rec)
+		}
+		return d.emit(nil, rec)
+	}); err != nil {
+		return err
+	}
+	for i, rec := range oldRows {
+		if !matched[i] {
+			if err := d.emit(rec, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// emit 比较一对行并写出结果；oldRec 为 nil 表示新增，newRec 为 nil 表示删除，没有变化时不写出
+func (d *differ) emit(oldRec, newRec []string) error {
+	p := d.plan
+	out := make([]string, 0, 1+len(p.oldKeys)+2*len(p.oldCols))
+	switch {
+	case oldRec == nil:
+		out = append(out, DiffAdded)
+	case newRec == nil:
+		out = append(out, DiffRemoved)
+	default:
+		out = append(out, DiffChanged)
+	}
+	for i := range p.oldKeys {
+		if oldRec != nil {
+			out = append(out, field(oldRec, p.oldKeys[i]))
+		} else {
+			out = append(out, field(newRec, p.newKeys[i]))
+		}
+	}
+	changed := false
+	for i := range p.oldCols {
+		var o, n string
+		if oldRec != nil {
+			o = field(oldRec, p.oldCols[i])
+		}
+		if newRec != nil {
+			n = field(newRec, p.newCols[i])
+		}
+		changed = changed || o != n
+		out = append(out, o, n)
+	}
+	switch {
+	case oldRec == nil:
+		d.res.Added++
+	case newRec == nil:
+		d.res.Removed++
+	case changed:
+		d.res.Changed++
+	default:
+		return nil
+	}
+	return d.res.Append(out)
+}
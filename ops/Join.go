@@ -0,0 +1,160 @@
+package ops
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/devbiu/CsvView/loader"
+)
+
+// JoinType 连接方式
+type JoinType string
+
+const (
+	// JoinInner 只保留两边都能匹配上的行
+	JoinInner JoinType = "inner"
+	// JoinLeft 保留左表所有行，没有匹配的行右表列为空
+	JoinLeft JoinType = "left"
+	// JoinAnti 只保留左表中没有匹配的行，不带入右表的列
+	JoinAnti JoinType = "anti"
+)
+
+// JoinTypes 界面上可选的连接方式
+var JoinTypes = []JoinType{JoinLeft, JoinInner, JoinAnti}
+
+// JoinOptions 连接选项
+type JoinOptions struct {
+	Type JoinType
+	// LeftKeys、RightKeys 两边的键列名，按位置一一对应
+	LeftKeys, RightKeys []string
+	// Columns 从右表带入的列，nil 表示右表除键列以外的所有列
+	Columns []string
+	// MemRows 右表在内存中建立索引的最大行数，0 为 DefaultMemRows
+	MemRows int
+	// Progress 每读取一批行回调一次，done 为两边已读取的总行数
+	Progress func(done int)
+	// Cancel 关闭后连接以 ErrCanceled 结束
+	Cancel <-chan struct{}
+}
+
+// Join 按键把 right 中的列带入 left，结果写入临时文件。
+// 右表按键建立哈希索引（过大时两边先分区落盘），一个左表行匹配多个右表行时输出多行；
+// 右表能放入内存时结果保持左表的行顺序，分区后按分区输出。
+func Join(left, right loader.RowSource, opts JoinOptions) (*loader.SpillSource, error) {
+	if len(opts.LeftKeys) == 0 || len(opts.LeftKeys) != len(opts.RightKeys) {
+		return nil, errors.New("join needs the same number of key columns on both sides")
+	}
+	switch opts.Type {
+	case "":
+		opts.Type = JoinLeft
+	case JoinInner, JoinLeft, JoinAnti:
+	default:
+		return nil, fmt.Errorf("unknown join type %q", opts.Type)
+	}
+	if opts.MemRows <= 0 {
+		opts.MemRows = DefaultMemRows
+	}
+	leftIdx, rightIdx := columnIndex(left.Header()), columnIndex(right.Header())
+	var leftKeys, rightKeys []int
+	for i := range opts.LeftKeys {
+		l, r := lookup(leftIdx, opts.LeftKeys[i]), lookup(rightIdx, opts.RightKeys[i])
+		if l < 0 {
+			return nil, fmt.Errorf("left table has no column %q", opts.LeftKeys[i])
+		}
+		if r < 0 {
+			return nil, fmt.Errorf("right table has no column %q", opts.RightKeys[i])
+		}
+		leftKeys = append(leftKeys, l)
+		rightKeys = append(rightKeys, r)
+	}
+
+	names := opts.Columns
+	if names == nil {
+		isKey := make(map[int]bool)
+		for _, k := range rightKeys {
+			isKey[k] = true
+		}
+		for i, h := range right.Header() {
+			if !isKey[i] {
+				names = append(names, h)
+			}
+		}
+	}
+	if opts.Type == JoinAnti {
+		names = nil
+	}
+	header := append([]string(nil), left.Header()...)
+	// 左表已有的列名加上 _right 后缀
+	taken := make(map[string]bool)
+	for _, h := range header {
+		taken[h] = true
+	}
+	var cols []int
+	for _, name := range names {
+		c := lookup(rightIdx, name)
+		if c < 0 {
+			return nil, fmt.Errorf("right table has no column %q", name)
+		}
+		cols = append(cols, c)
+		for taken[name] {
+			name += "_right"
+		}
+		taken[name] = true
+		header = append(header, name)
+	}
+
+	out, err := loader.NewSpillSource(header)
+	if err != nil {
+		return nil, err
+	}
+	width := len(left.Header())
+	emit := func(l, r []string) error {
+		rec := make([]string, width, width+len(cols))
+		copy(rec, l)
+		for _, c := range cols {
+			if r == nil {
+				rec = append(rec, "")
+			} else {
+				rec = append(rec, field(r, c))
+			}
+		}
+		return out.Append(rec)
+	}
+
+	s := &scanner{cancel: opts.Cancel, progress: opts.Progress}
+	err = s.hashJoin(right, left, rightKeys, leftKeys, opts.MemRows,
+		func(rightRows [][]string, eachLeft func(func(rec []string) error) error) error {
+			index := make(map[string][]int, len(rightRows))
+			for i, rec := range rightRows {
+				k := key(rec, rightKeys)
+				index[k] = append(index[k], i)
+			}
+			return eachLeft(func(rec []string) error {
+				matches := index[key(rec, leftKeys)]
+				switch {
+				case opts.Type == JoinAnti:
+					if len(matches) == 0 {
+						return emit(rec, nil)
+					}
+					return nil
+				case len(matches) == 0:
+					if opts.Type == JoinLeft {
+						return emit(rec, nil)
+					}
+					return nil
+				}
+				for _, m := range matches {
+					if err := emit(rec, rightRows[m]); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+	out.Finish(err)
+	if err != nil {
+		out.Close()
+		return nil, err
+	}
+	return out, nil
+}
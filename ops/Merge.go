@@ -0,0 +1,58 @@
+package ops
+
+import (
+	"strconv"
+
+	"github.com/devbiu/CsvView/loader"
+)
+
+// MergeOptions 合并参数
+type MergeOptions struct {
+	Progress func(done int)
+	Cancel   <-chan struct{}
+}
+
+// Merge 把多个数据源上下拼接成一个。列按名称对应，结果的列为所有输入列的并集，
+// 按第一次出现的顺序排列，某个输入没有的列填空；同一输入中重名的列按出现次序分别对应
+func Merge(srcs []loader.RowSource, opts MergeOptions) (*loader.SpillSource, error) {
+	var header []string
+	pos := make(map[string]int) // 列名#次序 -> 结果中的列
+	plans := make([][]int, len(srcs))
+	for i, src := range srcs {
+		seen := make(map[string]int)
+		for _, h := range src.Header() {
+			seen[h]++
+			k := h + "#" + strconv.Itoa(seen[h])
+			c, ok := pos[k]
+			if !ok {
+				c = len(header)
+				pos[k] = c
+				header = append(header, h)
+			}
+			plans[i] = append(plans[i], c)
+		}
+	}
+
+	out, err := loader.NewSpillSource(header)
+	if err != nil {
+		return nil, err
+	}
+	s := &scanner{cancel: opts.Cancel, progress: opts.Progress}
+	row := make([]string, len(header))
+	for i := 0; i < len(srcs) && err == nil; i++ {
+		plan := plans[i]
+		err = s.scan(srcs[i], func(rec []string) error {
+			clear(row)
+			for j, c := range plan {
+				row[c] = field(rec, j)
+			}
+			return out.Append(row)
+		})
+	}
+	out.Finish(err)
+	if err != nil {
+		out.Close()
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,183 @@
+// Package ops 两个或多个数据源之间的处理操作（比较、连接等）。
+// 输入通过 RowSource.ReadRows 顺序读取，结果写入 loader.SpillSource，
+// 可以直接在标签页中打开或导出；超出内存的输入先按键的哈希分区落盘。
+package ops
+
+import (
+	"errors"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/devbiu/CsvView/loader"
+)
+
+// ErrCanceled 操作被取消
+var ErrCanceled = errors.New("canceled")
+
+// DefaultMemRows 建立哈希索引的一侧行数不超过它时直接在内存中处理，否则先分区落盘
+const DefaultMemRows = 500_000
+
+// maxPartitions 每个分区有自己的写缓冲，分区数不超过它
+const maxPartitions = 64
+
+func columnIndex(header []string) map[string]int {
+	idx := make(map[string]int, len(header))
+	for i, h := range header {
+		if _, ok := idx[h]; !ok {
+			idx[h] = i
+		}
+	}
+	return idx
+}
+
+func lookup(idx map[string]int, name string) int {
+	if i, ok := idx[name]; ok {
+		return i
+	}
+	return -1
+}
+
+func field(rec []string, i int) string {
+	if i < 0 || i >= len(rec) {
+		return ""
+	}
+	return rec[i]
+}
+
+// key 多个键列用 \x00 连接
+func key(rec []string, cols []int) string {
+	if len(cols) == 1 {
+		return field(rec, cols[0])
+	}
+	parts := make([]string, len(cols))
+	for i, c := range cols {
+		parts[i] = field(rec, c)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// scanner 顺序读取输入，定期检查取消并报告进度
+type scanner struct {
+	cancel   <-chan struct{}
+	progress func(done int)
+	read     int
+}
+
+func (s *scanner) canceled() error {
+	select {
+	case <-s.cancel:
+		return ErrCanceled
+	default:
+		return nil
+	}
+}
+
+// scan 读取数据源的所有行；rec 在回调返回后可能被复用
+func (s *scanner) scan(src loader.RowSource, fn func(rec []string) error) error {
+	// 仍在追加写入的临时表（如 XLSX 工作表）只能读到已写入的部分，先等它写完
+	base := src
+	if v, ok := src.(*loader.View); ok {
+		base = v.Base()
+	}
+	if sp, ok := base.(*loader.SpillSource); ok {
+		for !sp.Indexed() {
+			if err := s.canceled(); err != nil {
+				return err
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	return src.ReadRows(0, -1, func(_ int, rec []string) error {
+		s.read++
+		if s.read%4096 == 0 {
+			if err := s.canceled(); err != nil {
+				return err
+			}
+			if s.progress != nil {
+				s.progress(s.read)
+			}
+		}
+		return fn(rec)
+	})
+}
+
+// hashJoin 把 build 一侧读入内存，再让 fn 顺序处理 probe 一侧。
+// build 一侧超过 memRows 行（或行数尚未确定）时两边先按键的哈希分区写入临时文件，
+// 同一个键一定落在同一分区，再对每个分区分别调用 fn，每个分区约 memRows/2 行
+func (s *scanner) hashJoin(build, probe loader.RowSource, buildKeys, probeKeys []int, memRows int,
+	fn func(buildRows [][]string, eachProbe func(func(rec []string) error) error) error) error {
+	if build.Indexed() && build.RowCount() <= memRows {
+		var rows [][]string
+		if err := s.scan(build, func(rec []string) error {
+			rows = append(rows, append([]string(nil), rec...))
+			return nil
+		}); err != nil {
+			return err
+		}
+		return fn(rows, func(f func(rec []string) error) error { return s.scan(probe, f) })
+	}
+
+	n := 16
+	if build.Indexed() {
+		n = min(max(2*build.RowCount()/memRows+1, 2), maxPartitions)
+	}
+	buildParts, err := s.partition(build, buildKeys, n)
+	if err != nil {
+		return err
+	}
+	defer closeAll(buildParts)
+	probeParts, err := s.partition(probe, probeKeys, n)
+	if err != nil {
+		return err
+	}
+	defer closeAll(probeParts)
+
+	for i := range n {
+		if err := s.canceled(); err != nil {
+			return err
+		}
+		var rows [][]string
+		if err := buildParts[i].ReadRows(0, -1, func(_ int, rec []string) error {
+			rows = append(rows, rec)
+			return nil
+		}); err != nil {
+			return err
+		}
+		if err := fn(rows, func(f func(rec []string) error) error {
+			return probeParts[i].ReadRows(0, -1, func(_ int, rec []string) error { return f(rec) })
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// partition 按键的哈希把数据源拆分到 n 个临时文件
+func (s *scanner) partition(src loader.RowSource, keys []int, n int) ([]*loader.SpillSource, error) {
+	parts := make([]*loader.SpillSource, 0, n)
+	for range n {
+		p, err := loader.NewSpillSource(src.Header())
+		if err != nil {
+			closeAll(parts)
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+	err := s.scan(src, func(rec []string) error {
+		h := fnv.New32a()
+		h.Write([]byte(key(rec, keys)))
+		return parts[h.Sum32()%uint32(n)].Append(rec)
+	})
+	if err != nil {
+		closeAll(parts)
+		return nil, err
+	}
+	return parts, nil
+}
+
+func closeAll(parts []*loader.SpillSource) {
+	for _, p := range parts {
+		p.Close()
+	}
+}
@@ -0,0 +1,338 @@
+package ops
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/devbiu/CsvView/loader"
+)
+
+// Aggregate 透视表的聚合函数
+type Aggregate string
+
+const (
+	AggCount    Aggregate = "count"
+	AggSum      Aggregate = "sum"
+	AggAvg      Aggregate = "avg"
+	AggMin      Aggregate = "min"
+	AggMax      Aggregate = "max"
+	AggDistinct Aggregate = "distinct"
+	AggMedian   Aggregate = "median"
+)
+
+// Aggregates 界面上可选的聚合函数
+var Aggregates = []Aggregate{AggCount, AggSum, AggAvg, AggMin, AggMax, AggDistinct, AggMedian}
+
+// numeric 聚合是否需要数值列
+func (a Aggregate) numeric() bool {
+	return a == AggSum || a == AggAvg || a == AggMedian
+}
+
+// PivotValue 一个值字段：对 Column 做 Agg 聚合；Column 为空时 count 统计行数
+type PivotValue struct {
+	Column string
+	Agg    Aggregate
+}
+
+// Name 结果中的列名，如 sum(price)
+func (v PivotValue) Name() string {
+	col := v.Column
+	if col == "" {
+		col = "*"
+	}
+	return string(v.Agg) + "(" + col + ")"
+}
+
+// PivotOptions 透视选项
+type PivotOptions struct {
+	// Rows 行分组字段，每个不同的取值组合是结果中的一行
+	Rows []string
+	// Columns 列分组字段，每个不同的取值组合与每个值字段组成结果中的一列
+	Columns []string
+	Values  []PivotValue
+	// Progress 每读取一批行回调一次
+	Progress func(done int)
+	// Cancel 关闭后以 ErrCanceled 结束
+	Cancel <-chan struct{}
+}
+
+// ErrNoPivotFields 没有选择任何分组或值字段
+var ErrNoPivotFields = errors.New("drag at least one field to rows, columns or values")
+
+// pivotCell 一个分组中一个值字段的累加器，只用到聚合函数需要的部分
+type pivotCell struct {
+	count    int
+	sum      float64
+	min, max string
+	distinct map[string]struct{}
+	nums     []float64
+}
+
+// Pivot 顺序读取一遍数据源完成分组聚合，结果按分组取值排序。
+// sum、avg、median 要求数值列（按推断的类型判断，无法解析的值被跳过），
+// min、max 对数值和日期按值比较，其余按文本比较；count 和 distinct 不计空值。
+func Pivot(src loader.RowSource, opts PivotOptions) (*loader.MemSource, error) {
+	if len(opts.Rows) == 0 && len(opts.Columns) == 0 && len(opts.Values) == 0 {
+		return nil, ErrNoPivotFields
+	}
+	if len(opts.Values) == 0 {
+		opts.Values = []PivotValue{{Agg: AggCount}}
+	}
+	idx := columnIndex(src.Header())
+	resolve := func(names []string) ([]int, error) {
+		cols := make([]int, len(names))
+		for i, n := range names {
+			if cols[i] = lookup(idx, n); cols[i] < 0 {
+				return nil, fmt.Errorf("no column %q", n)
+			}
+		}
+		return cols, nil
+	}
+	rowCols, err := resolve(opts.Rows)
+	if err != nil {
+		return nil, err
+	}
+	colCols, err := resolve(opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+	types, err := loader.InferTypes(src, loader.DefaultInferSample)
+	if err != nil {
+		return nil, err
+	}
+	valCols := make([]int, len(opts.Values))
+	compare := make([]func(a, b string) int, len(opts.Values))
+	for i, v := range opts.Values {
+		valCols[i] = -1
+		if v.Column == "" {
+			if v.Agg != AggCount {
+				return nil, fmt.Errorf("%s needs a column", v.Agg)
+			}
+			continue
+		}
+		if valCols[i] = lookup(idx, v.Column); valCols[i] < 0 {
+			return nil, fmt.Errorf("no column %q", v.Column)
+		}
+		t := types[valCols[i]]
+		if v.Agg.numeric() && t != loader.TypeInt && t != loader.TypeFloat {
+			return nil, fmt.Errorf("%s needs a numeric column, %q looks like %s", v.Agg, v.Column, t)
+		}
+		compare[i] = valueCompare(t)
+	}
+
+	rowKeys := make(map[string][]string)
+	colKeys := make(map[string][]string)
+	cells := make(map[[2]string][]*pivotCell)
+	s := &scanner{cancel: opts.Cancel, progress: opts.Progress}
+	err = s.scan(src, func(rec []string) error {
+		rk, ck := key(rec, rowCols), key(rec, colCols)
+		if _, ok := rowKeys[rk]; !ok {
+			rowKeys[rk] = fields(rec, rowCols)
+		}
+		if _, ok := colKeys[ck]; !ok {
+			colKeys[ck] = fields(rec, colCols)
+		}
+		group, ok := cells[[2]string{rk, ck}]
+		if !ok {
+			group = make([]*pivotCell, len(opts.Values))
+			for i := range group {
+				group[i] = &pivotCell{}
+			}
+			cells[[2]string{rk, ck}] = group
+		}
+		for i, v := range opts.Values {
+			group[i].add(v.Agg, valCols[i], rec, compare[i])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows := sortedKeys(rowKeys)
+	cols := sortedKeys(colKeys)
+	header := append([]string(nil), opts.Rows...)
+	for _, ck := range cols {
+		for _, v := range opts.Values {
+			if len(opts.Columns) > 0 {
+				header = append(header, strings.Join(colKeys[ck], " / ")+" | "+v.Name())
+			} else {
+				header = append(header, v.Name())
+			}
+		}
+	}
+	out := make([][]string, 0, len(rows))
+	for _, rk := range rows {
+		rec := append([]string(nil), rowKeys[rk]...)
+		for _, ck := range cols {
+			group := cells[[2]string{rk, ck}]
+			for i, v := range opts.Values {
+				if group == nil {
+					rec = append(rec, "")
+				} else {
+					rec = append(rec, group[i].result(v.Agg))
+				}
+			}
+		}
+		out = append(out, rec)
+	}
+	return loader.NewMemSource(header, out), nil
+}
+
+func fields(rec []string, cols []int) []string {
+	out := make([]string, len(cols))
+	for i, c := range cols {
+		out[i] = field(rec, c)
+	}
+	return out
+}
+
+// add 把一行的值计入累加器；col < 0 表示 count(*)
+func (c *pivotCell) add(agg Aggregate, col int, rec []string, compare func(a, b string) int) {
+	if col < 0 {
+		c.count++
+		return
+	}
+	v := strings.TrimSpace(field(rec, col))
+	if v == "" {
+		return
+	}
+	switch agg {
+	case AggCount:
+		c.count++
+	case AggSum, AggAvg, AggMedian:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return
+		}
+		c.count++
+		c.sum += f
+		if agg == AggMedian {
+			c.nums = append(c.nums, f)
+		}
+	case AggMin:
+		if c.count == 0 || compare(v, c.min) < 0 {
+			c.min = v
+		}
+		c.count++
+	case AggMax:
+		if c.count == 0 || compare(v, c.max) > 0 {
+			c.max = v
+		}
+		c.count++
+	case AggDistinct:
+		if c.distinct == nil {
+			c.distinct = make(map[string]struct{})
+		}
+		c.distinct[v] = struct{}{}
+	}
+}
+
+func (c *pivotCell) result(agg Aggregate) string {
+	switch agg {
+	case AggCount:
+		return strconv.Itoa(c.count)
+	case AggDistinct:
+		return strconv.Itoa(len(c.distinct))
+	case AggMin:
+		return c.min
+	case AggMax:
+		return c.max
+	}
+	if c.count == 0 {
+		return ""
+	}
+	switch agg {
+	case AggSum:
+		return formatNumber(c.sum)
+	case AggAvg:
+		return formatNumber(c.sum / float64(c.count))
+	case AggMedian:
+		slices.Sort(c.nums)
+		n := len(c.nums)
+		if n%2 == 1 {
+			return formatNumber(c.nums[n/2])
+		}
+		return formatNumber((c.nums[n/2-1] + c.nums[n/2]) / 2)
+	}
+	return ""
+}
+
+func formatNumber(f float64) string {
+	if math.Abs(f) < 1e15 && f == math.Trunc(f) {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// valueCompare 按列类型比较两个值：数值和日期按值，其他按文本
+func valueCompare(t loader.ColumnType) func(a, b string) int {
+	switch t {
+	case loader.TypeInt, loader.TypeFloat:
+		return func(a, b string) int {
+			x, err1 := strconv.ParseFloat(a, 64)
+			y, err2 := strconv.ParseFloat(b, 64)
+			if err1 != nil || err2 != nil {
+				return strings.Compare(a, b)
+			}
+			return cmpFloat(x, y)
+		}
+	case loader.TypeDate, loader.TypeTimestamp:
+		parse := loader.ParseDate
+		if t == loader.TypeTimestamp {
+			parse = loader.ParseTimestamp
+		}
+		return func(a, b string) int {
+			x, ok1 := parse(a)
+			y, ok2 := parse(b)
+			if !ok1 || !ok2 {
+				return strings.Compare(a, b)
+			}
+			return x.Compare(y)
+		}
+	}
+	return strings.Compare
+}
+
+func cmpFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// sortedKeys 分组按取值排序，逐个字段比较，两边都是数字时按数值比较
+func sortedKeys(groups map[string][]string) []string {
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		x, y := groups[a], groups[b]
+		for i := range x {
+			if c := naturalCompare(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	return keys
+}
+
+func naturalCompare(a, b string) int {
+	x, err1 := strconv.ParseFloat(a, 64)
+	y, err2 := strconv.ParseFloat(b, 64)
+	if err1 == nil && err2 == nil {
+		if c := cmpFloat(x, y); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,254 @@
+package ops
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/devbiu/CsvView/loader"
+)
+
+// SplitBy 拆分方式
+type SplitBy string
+
+const (
+	SplitRows   SplitBy = "rows"
+	SplitBytes  SplitBy = "bytes"
+	SplitColumn SplitBy = "column"
+)
+
+// SplitBys 界面上可选的拆分方式
+var SplitBys = []SplitBy{SplitRows, SplitBytes, SplitColumn}
+
+// maxSplitFiles 按列值拆分时同时打开的文件数上限
+const maxSplitFiles = 500
+
+// SplitOptions 拆分参数
+type SplitOptions struct {
+	By SplitBy
+	// Rows 每个文件的数据行数；Bytes 每个文件的大约字节数（不含表头，不会截断记录）
+	Rows  int
+	Bytes int64
+	// Column 按这一列的值拆分，每个值一个文件
+	Column string
+	// Dir 输出目录，默认与输入文件相同；文件名为 输入名-序号 或 输入名-列值
+	Dir      string
+	Progress func(done int)
+	Cancel   <-chan struct{}
+}
+
+// SplitFile 把 CSV/TSV 文件拆分成多个文件，每个文件都带表头，返回写出的文件。
+// 按行数和字节数拆分时只扫描记录边界、按偏移复制原始字节；按列值拆分时逐条解析
+func SplitFile(path string, opts SplitOptions) ([]string, error) {
+	rf, err := loader.OpenRawFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer rf.Close()
+	if opts.Dir == "" {
+		opts.Dir = filepath.Dir(path)
+	}
+	ext := filepath.Ext(path)
+	base := filepath.Join(opts.Dir, strings.TrimSuffix(filepath.Base(path), ext))
+	s := &scanner{cancel: opts.Cancel, progress: opts.Progress}
+
+	switch opts.By {
+	case SplitRows:
+		if opts.Rows <= 0 {
+			return nil, errors.New("rows per file must be positive")
+		}
+		rows := 0
+		return splitRanges(rf, base, ext, s, func(start, end int64) bool {
+			rows++
+			if rows == opts.Rows {
+				rows = 0
+				return true
+			}
+			return false
+		})
+	case SplitBytes:
+		if opts.Bytes <= 0 {
+			return nil, errors.New("bytes per file must be positive")
+		}
+		return splitRanges(rf, base, ext, s, func(start, end int64) bool {
+			return end-start >= opts.Bytes
+		})
+	case SplitColumn:
+		return splitColumn(rf, base, ext, opts.Column, s)
+	}
+	return nil, fmt.Errorf("unknown split mode %q", opts.By)
+}
+
+// splitRanges 扫描记录边界，cut 返回 true 时在这条记录之后切开，每段原样复制到一个文件
+func splitRanges(rf *loader.RawFile, base, ext string, s *scanner, cut func(start, end int64) bool) ([]string, error) {
+	var files []string
+	start := rf.Start()
+	write := func(end int64) error {
+		name := fmt.Sprintf("%s-%04d%s", base, len(files)+1, ext)
+		if err := writeChunk(name, rf, start, end); err != nil {
+			return err
+		}
+		files = append(files, name)
+		start = end
+		return nil
+	}
+	var werr error
+	last := start
+	err := rf.ScanRecords(func(end int64) bool {
+		s.read++
+		if s.read%4096 == 0 {
+			if werr = s.canceled(); werr != nil {
+				return false
+			}
+			if s.progress != nil {
+				s.progress(s.read)
+			}
+		}
+		last = end
+		if cut(start, end) {
+			werr = write(end)
+		}
+		return werr == nil
+	})
+	if err == nil {
+		err = werr
+	}
+	if err == nil && last > start {
+		err = write(last)
+	}
+	if err != nil {
+		removeAll(files)
+		return nil, err
+	}
+	return files, nil
+}
+
+func writeChunk(name string, rf *loader.RawFile, from, to int64) error {
+	f, err := createNew(name)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(rf.Header); err != nil {
+		f.Close()
+		return err
+	}
+	if err := rf.CopyRange(f, from, to); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// splitColumn 每个不同的列值写到一个文件，记录保持原始字节
+func splitColumn(rf *loader.RawFile, base, ext, column string, s *scanner) ([]string, error) {
+	col := lookup(columnIndex(rf.Columns()), column)
+	if col < 0 {
+		return nil, fmt.Errorf("column %q not found", column)
+	}
+	type output struct {
+		f *os.File
+		w *bufio.Writer
+	}
+	outputs := make(map[string]*output)
+	used := make(map[string]bool)
+	var files []string
+	closeOutputs := func() error {
+		var first error
+		for _, o := range outputs {
+			if err := o.w.Flush(); err != nil && first == nil {
+				first = err
+			}
+			if err := o.f.Close(); err != nil && first == nil {
+				first = err
+			}
+		}
+		return first
+	}
+	err := rf.ReadRecords(func(raw []byte, rec []string) error {
+		s.read++
+		if s.read%4096 == 0 {
+			if err := s.canceled(); err != nil {
+				return err
+			}
+			if s.progress != nil {
+				s.progress(s.read)
+			}
+		}
+		v := field(rec, col)
+		o, ok := outputs[v]
+		if !ok {
+			if len(outputs) >= maxSplitFiles {
+				return fmt.Errorf("column %q has more than %d distinct values", column, maxSplitFiles)
+			}
+			name := uniqueName(base+"-"+fileNamePart(v), ext, used)
+			f, err := createNew(name)
+			if err != nil {
+				return err
+			}
+			o = &output{f, bufio.NewWriterSize(f, 64*1024)}
+			outputs[v] = o
+			files = append(files, name)
+			if _, err := o.w.Write(rf.Header); err != nil {
+				return err
+			}
+		}
+		_, err := o.w.Write(raw)
+		return err
+	})
+	if cerr := closeOutputs(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		removeAll(files)
+		return nil, err
+	}
+	return files, nil
+}
+
+// fileNamePart 把列值变成可以用在文件名中的文本
+func fileNamePart(v string) string {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return "(empty)"
+	}
+	v = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?*`, r) || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, v)
+	if r := []rune(v); len(r) > 80 {
+		v = string(r[:80])
+	}
+	return v
+}
+
+// uniqueName 列值规范化后可能重名（如大小写不同的文件系统），重名时加序号
+func uniqueName(base, ext string, used map[string]bool) string {
+	name := base + ext
+	for i := 2; used[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
+
+// createNew 创建输出文件；同名文件已经存在（如上次拆分的结果）时报错，不覆盖
+func createNew(name string) (*os.File, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o666)
+	if errors.Is(err, fs.ErrExist) {
+		return nil, fmt.Errorf("%s already exists; remove it or choose another output folder", name)
+	}
+	return f, err
+}
+
+// removeAll 出错时删除本次已经写出的文件，不会删除原有的文件
+func removeAll(files []string) {
+	for _, f := range files {
+		os.Remove(f)
+	}
+}
@@ -0,0 +1,271 @@
+package shower
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/loader"
+)
+
+const (
+	// maxInspectRunes 面板中最多显示的字符数，更长的值只显示开头
+	maxInspectRunes = 100000
+	// maxHexBytes 十六进制视图最多显示的字节数
+	maxHexBytes = 4096
+)
+
+// CellInfo 单元格值的分析结果
+type CellInfo struct {
+	Type      string // 推断的类型（int、float、date 等），JSON/XML 为 json、xml，空值为 empty
+	Pretty    string // JSON/XML 格式化后的文本，其他类型为原值
+	Chars     int    // 字符数
+	Bytes     int    // UTF-8 字节数
+	Invisible int    // 不可见字符数：换行和制表符以外的控制字符、零宽字符、BOM、不换行空格和非法字节
+}
+
+// InspectValue 分析单元格的值：统计长度和不可见字符，识别类型，JSON 和 XML 缩进显示
+func InspectValue(s string) CellInfo {
+	info := CellInfo{Pretty: s, Chars: utf8.RuneCountInString(s), Bytes: len(s)}
+	for i, r := range s {
+		if invisible(r) || r == utf8.RuneError && !strings.HasPrefix(s[i:], string(utf8.RuneError)) {
+			info.Invisible++
+		}
+	}
+	trimmed := strings.TrimSpace(s)
+	switch {
+	case trimmed == "":
+		info.Type = "empty"
+		return info
+	case trimmed[0] == '{' || trimmed[0] == '[':
+		var buf bytes.Buffer
+		if json.Indent(&buf, []byte(trimmed), "", "  ") == nil {
+			info.Type, info.Pretty = "json", buf.String()
+			return info
+		}
+	case trimmed[0] == '<':
+		if pretty, ok := indentXML(trimmed); ok {
+			info.Type, info.Pretty = "xml", pretty
+			return info
+		}
+	}
+	info.Type = loader.DetectType(s).String()
+	return info
+}
+
+// invisible 显示时看不出来的字符
+func invisible(r rune) bool {
+	if r == '\n' || r == '\t' {
+		return false
+	}
+	return unicode.IsControl(r) || unicode.Is(unicode.Cf, r) || r == '\u00a0'
+}
+
+// indentXML 按元素缩进重新输出 XML，元素之间的空白丢弃；不是完整的 XML 时返回 false
+func indentXML(s string) (string, bool) {
+	d := xml.NewDecoder(strings.NewReader(s))
+	var buf bytes.Buffer
+	e := xml.NewEncoder(&buf)
+	e.Indent("", "  ")
+	elements := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", false
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			elements++
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+		}
+		if err := e.EncodeToken(xml.CopyToken(tok)); err != nil {
+			return "", false
+		}
+	}
+	if elements == 0 || e.Flush() != nil {
+		return "", false
+	}
+	return buf.String(), true
+}
+
+// hexView 值的十六进制和 ASCII 对照，用于查看不可见字符和编码问题
+func hexView(s string) string {
+	b := []byte(s)
+	if len(b) <= maxHexBytes {
+		return hex.Dump(b)
+	}
+	return hex.Dump(b[:maxHexBytes]) + fmt.Sprintf("... %d more bytes", len(b)-maxHexBytes)
+}
+
+// CellInspector 表格右侧的单元格详情面板：完整显示活动单元格的值、长度、类型和十六进制，
+// 以及当前行所有列的“列名: 值”列表。面板隐藏时不更新
+type CellInspector struct {
+	vt    *VirtualTable
+	panel *fyne.Container
+
+	title, info *widget.Label
+	value       *widget.Label
+	hex         *widget.Label
+	fields      *widget.List
+	cols        []int // 当前行按显示顺序的源列
+	rec         []string
+	row         int
+}
+
+// NewCellInspector 创建跟随活动单元格更新的详情面板，用 Wrap 放到表格旁边
+func NewCellInspector(vt *VirtualTable) *CellInspector {
+	ci := &CellInspector{vt: vt, row: -1}
+	ci.title = widget.NewLabel("")
+	ci.title.TextStyle = fyne.TextStyle{Bold: true}
+	ci.title.Truncation = fyne.TextTruncateEllipsis
+	ci.info = widget.NewLabel("")
+	ci.value = widget.NewLabel("")
+	ci.value.Wrapping = fyne.TextWrapWord
+	ci.value.Selectable = true
+	ci.hex = widget.NewLabel("")
+	ci.hex.TextStyle = fyne.TextStyle{Monospace: true}
+	ci.hex.Selectable = true
+
+	ci.fields = widget.NewList(
+		func() int { return len(ci.cols) },
+		func() fyne.CanvasObject {
+			key := widget.NewLabel("")
+			key.TextStyle = fyne.TextStyle{Bold: true}
+			key.Truncation = fyne.TextTruncateEllipsis
+			val := widget.NewLabel("")
+			val.Truncation = fyne.TextTruncateEllipsis
+			return container.NewGridWithColumns(2, key, val)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			labels := obj.(*fyne.Container).Objects
+			c := ci.cols[id]
+			labels[0].(*widget.Label).SetText(columnName(vt.source.Header(), c))
+			val := ""
+			if c < len(ci.rec) {
+				val, _, _ = strings.Cut(ci.rec[c], "\n")
+			}
+			labels[1].(*widget.Label).SetText(val)
+		},
+	)
+	// 点击列表中的列跳到该单元格，值显示在上方
+	ci.fields.OnSelected = func(id widget.ListItemID) {
+		ci.fields.UnselectAll()
+		if ci.row >= 0 {
+			vt.GoTo(ci.row, id)
+		}
+	}
+
+	tabs := container.NewAppTabs(
+		container.NewTabItem("Value", container.NewVScroll(ci.value)),
+		container.NewTabItem("Hex", container.NewScroll(ci.hex)),
+		container.NewTabItem("Record", ci.fields),
+	)
+	ci.panel = container.NewBorder(container.NewVBox(ci.title, ci.info), nil, nil, nil, tabs)
+	ci.panel.Hide()
+
+	vt.OnSelectionChanged(ci.update)
+	vt.source.Subscribe(func(e loader.Event) {
+		if e.Kind == loader.EventRowsLoaded {
+			fyne.Do(func() {
+				if ci.row >= 0 && ci.rec == nil && e.Contains(ci.row) {
+					ci.update()
+				}
+			})
+		}
+	})
+	return ci
+}
+
+// columnName 列名，表头为空时显示列序号
+func columnName(header []string, c int) string {
+	if c < len(header) && header[c] != "" {
+		return header[c]
+	}
+	return "Column " + strconv.Itoa(c+1)
+}
+
+// Wrap 把面板放到 content 右侧，面板隐藏时 content 占满
+func (ci *CellInspector) Wrap(content fyne.CanvasObject) fyne.CanvasObject {
+	split := container.NewHSplit(content, ci.panel)
+	split.Offset = 0.7
+	return split
+}
+
+// Visible 面板是否显示
+func (ci *CellInspector) Visible() bool {
+	return ci.panel.Visible()
+}
+
+// Toggle 显示或隐藏面板
+func (ci *CellInspector) Toggle() {
+	if ci.panel.Visible() {
+		ci.panel.Hide()
+		return
+	}
+	ci.panel.Show()
+	ci.update()
+}
+
+// update 按活动单元格刷新面板内容
+func (ci *CellInspector) update() {
+	if !ci.panel.Visible() {
+		return
+	}
+	sel := ci.vt.selected
+	if sel == nil {
+		ci.row, ci.rec, ci.cols = -1, nil, nil
+		ci.title.SetText("No cell selected")
+		ci.info.SetText("")
+		ci.value.SetText("")
+		ci.hex.SetText("")
+		ci.fields.Refresh()
+		return
+	}
+	col := ci.vt.SourceCol(sel.Col)
+	ci.row, ci.cols = sel.Row, ci.vt.VisibleColumns()
+	ci.title.SetText(fmt.Sprintf("%s · Row %s", columnName(ci.vt.source.Header(), col), groupDigits(sel.Row+1)))
+	rec, ok := ci.vt.source.GetRow(sel.Row)
+	ci.rec = nil
+	if ok {
+		ci.rec = rec
+	}
+	ci.fields.Refresh()
+	if !ok {
+		ci.info.SetText("loading...")
+		ci.value.SetText("")
+		ci.hex.SetText("")
+		return
+	}
+	s := ""
+	if col >= 0 && col < len(rec) {
+		s = rec[col]
+	}
+	info := InspectValue(s)
+	text := fmt.Sprintf("%s chars · %s bytes · %s", groupDigits(info.Chars), groupDigits(info.Bytes), info.Type)
+	if info.Invisible > 0 {
+		text += fmt.Sprintf(" · %d invisible", info.Invisible)
+	}
+	ci.info.SetText(text)
+	pretty := info.Pretty
+	if utf8.RuneCountInString(pretty) > maxInspectRunes {
+		pretty = string([]rune(pretty)[:maxInspectRunes]) + "\n..."
+	}
+	ci.value.SetText(pretty)
+	ci.hex.SetText(hexView(s))
+}
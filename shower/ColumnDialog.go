@@ -0,0 +1,146 @@
+package shower
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ShowColumnsDialog 选择显示哪些列、调整列顺序和冻结的列数；列很多时可以按名称过滤
+func ShowColumnsDialog(w fyne.Window, vt *VirtualTable) {
+	header := vt.source.Header()
+	name := func(c int) string {
+		if c < len(header) && header[c] != "" {
+			return header[c]
+		}
+		return "Column " + strconv.Itoa(c+1)
+	}
+	order := vt.fullOrder()
+	hidden := make(map[int]bool, len(vt.hidden))
+	for c := range vt.hidden {
+		hidden[c] = true
+	}
+
+	// shown 过滤后显示在列表中的项，值为 order 中的位置
+	var shown []int
+	query := widget.NewEntry()
+	query.SetPlaceHolder("Filter columns")
+	var list *widget.List
+	refilter := func() {
+		q := strings.ToLower(strings.TrimSpace(query.Text))
+		shown = shown[:0]
+		for i, c := range order {
+			if q == "" || strings.Contains(strings.ToLower(name(c)), q) {
+				shown = append(shown, i)
+			}
+		}
+		list.UnselectAll()
+		list.Refresh()
+	}
+	// move 把列与完整顺序中相邻的列交换
+	move := func(i, delta int) {
+		j := i + delta
+		if j < 0 || j >= len(order) {
+			return
+		}
+		order[i], order[j] = order[j], order[i]
+		refilter()
+	}
+
+	list = widget.NewList(
+		func() int { return len(shown) },
+		func() fyne.CanvasObject {
+			check := widget.NewCheck("", nil)
+			up := widget.NewButtonWithIcon("", theme.MoveUpIcon(), nil)
+			down := widget.NewButtonWithIcon("", theme.MoveDownIcon(), nil)
+			return container.NewBorder(nil, nil, nil, container.NewHBox(up, down), check)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			i := shown[id]
+			c := order[i]
+			row := obj.(*fyne.Container)
+			check := row.Objects[0].(*widget.Check)
+			buttons := row.Objects[1].(*fyne.Container).Objects
+			check.OnChanged = nil
+			check.SetText(name(c))
+			check.SetChecked(!hidden[c])
+			check.OnChanged = func(on bool) { hidden[c] = !on }
+			buttons[0].(*widget.Button).OnTapped = func() { move(i, -1) }
+			buttons[1].(*widget.Button).OnTapped = func() { move(i, 1) }
+		},
+	)
+	query.OnChanged = func(string) { refilter() }
+	refilter()
+
+	setAll := func(show bool) func() {
+		return func() {
+			for _, i := range shown {
+				hidden[order[i]] = !show
+			}
+			list.Refresh()
+		}
+	}
+	frozen := widget.NewEntry()
+	frozen.SetText(strconv.Itoa(vt.Frozen()))
+
+	top := container.NewVBox(query, container.NewHBox(
+		widget.NewButton("Show All", setAll(true)),
+		widget.NewButton("Hide All", setAll(false)),
+		layout.NewSpacer(),
+	))
+	bottom := widget.NewForm(widget.NewFormItem("Freeze first columns", frozen))
+	content := container.NewBorder(top, bottom, nil, nil, list)
+	d := dialog.NewCustomConfirm("Columns", "Apply", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		visible := slices.ContainsFunc(order, func(c int) bool { return !hidden[c] })
+		if !visible {
+			dialog.ShowError(errors.New("at least one column must stay visible"), w)
+			return
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(frozen.Text))
+		if err != nil || n < 0 {
+			dialog.ShowError(fmt.Errorf("invalid number of frozen columns %q", frozen.Text), w)
+			return
+		}
+		vt.SetColumns(order, hidden, n)
+	}, w)
+	d.Resize(fyne.NewSize(480, 560))
+	d.Show()
+}
+
+// FreezeToSelection 冻结到活动单元格所在的列（含），没有选中时取消冻结
+func FreezeToSelection(vt *VirtualTable) {
+	n := 0
+	if vt.selected != nil {
+		n = vt.selected.Col + 1
+	}
+	vt.SetFrozen(n)
+}
+
+// HideSelectedColumns 隐藏选区中的列
+func HideSelectedColumns(vt *VirtualTable) error {
+	sel, ok := vt.Selection()
+	if !ok {
+		return errors.New("select cells in the columns to hide first")
+	}
+	if sel.Cols() >= vt.colCount() {
+		return errors.New("at least one column must stay visible")
+	}
+	var cols []int
+	for c := sel.Left; c <= sel.Right; c++ {
+		cols = append(cols, c)
+	}
+	vt.HideColumns(cols)
+	return nil
+}
@@ -0,0 +1,115 @@
+package shower
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+const (
+	minColumnWidth = 40
+	// maxColumnWidth 自动列宽的上限，更长的内容以省略号截断，拖动列边界可以再加宽
+	maxColumnWidth = 400
+	// autoFitSample 自动列宽参考开头和当前位置附近各多少行（只用已加载的行）
+	autoFitSample = 100
+	// measureRunes 测量时每行最多取的字符数，再长也会超过上限
+	measureRunes = 200
+)
+
+// cellTextWidth 单元格显示文本需要的宽度（含标签内边距），多行文本取最长的一行
+func cellTextWidth(s string, style fyne.TextStyle) float32 {
+	var w float32
+	for line := range strings.SplitSeq(s, "\n") {
+		if utf8.RuneCountInString(line) > measureRunes {
+			line = string([]rune(line)[:measureRunes])
+		}
+		w = max(w, fyne.MeasureText(line, theme.TextSize(), style).Width)
+	}
+	return w + 2*theme.InnerPadding() + theme.Padding()
+}
+
+// sampleRows 自动列宽参考的行：开头的若干行和最近显示过的行附近，未加载的行跳过
+func (vt *VirtualTable) sampleRows() [][]string {
+	n := vt.source.RowCount()
+	var rows [][]string
+	add := func(from, to int) {
+		for r := max(from, 0); r < min(to, n); r++ {
+			if rec, ok := vt.source.GetRow(r); ok {
+				rows = append(rows, rec)
+			}
+		}
+	}
+	add(0, autoFitSample)
+	if vt.lastRow >= autoFitSample {
+		add(vt.lastRow-autoFitSample/2, vt.lastRow+autoFitSample/2)
+	}
+	return rows
+}
+
+// fitWidth 按表头和样本行计算源列的宽度
+func fitWidth(col int, header []string, rows [][]string) float32 {
+	var w float32
+	if col < len(header) {
+		w = cellTextWidth(header[col], fyne.TextStyle{Bold: true})
+	}
+	for _, r := range rows {
+		if col < len(r) && r[col] != "" {
+			w = max(w, cellTextWidth(r[col], fyne.TextStyle{}))
+			if w >= maxColumnWidth {
+				break
+			}
+		}
+	}
+	return min(max(w, minColumnWidth), maxColumnWidth)
+}
+
+// ColumnWidth 显示列的宽度
+func (vt *VirtualTable) ColumnWidth(col int) float32 {
+	return vt.widths[vt.SourceCol(col)]
+}
+
+// AutoFitColumns 按内容调整显示列的宽度，cols 为空时调整所有可见列，结果保存到文件的列布局
+func (vt *VirtualTable) AutoFitColumns(cols ...int) {
+	if len(cols) == 0 {
+		for c := range vt.colCount() {
+			cols = append(cols, c)
+		}
+	}
+	header, rows := vt.source.Header(), vt.sampleRows()
+	for _, c := range cols {
+		if s := vt.SourceCol(c); s >= 0 {
+			vt.widths[s] = fitWidth(s, header, rows)
+			vt.userWidths[s] = true
+		}
+	}
+	vt.applyWidths()
+	vt.saveLayout()
+}
+
+// noteHeaderWidth 表格自带拖动列边界调整列宽但没有回调，
+// 从重新布局后的列头尺寸发现宽度变化并记录下来
+func (vt *VirtualTable) noteHeaderWidth(col int, w float32) {
+	old, ok := vt.widths[col]
+	if vt.applying || !ok || w <= 0 || w-old < 0.5 && old-w < 0.5 {
+		return
+	}
+	vt.widths[col] = w
+	vt.userWidths[col] = true
+	vt.saveSoon()
+}
+
+// saveSoon 拖动列边界时宽度连续变化，停下来之后再保存
+func (vt *VirtualTable) saveSoon() {
+	if vt.layoutKey == "" {
+		return
+	}
+	if vt.saveTimer != nil {
+		vt.saveTimer.Stop()
+	}
+	vt.saveTimer = time.AfterFunc(500*time.Millisecond, func() {
+		fyne.Do(vt.saveLayout)
+	})
+}
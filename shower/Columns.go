@@ -0,0 +1,305 @@
+package shower
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+// ColumnLayout 列的显示顺序、隐藏、冻结和宽度。按列名保存，文件增删列后其余列的设置仍然有效；
+// 同名的列按出现次序区分
+type ColumnLayout struct {
+	Order  []string `json:"order,omitempty"`  // 按显示顺序排列的列，未列出的列排在后面
+	Hidden []string `json:"hidden,omitempty"` // 隐藏的列
+	Frozen int      `json:"frozen,omitempty"` // 横向滚动时固定显示的前几列
+	// Widths 用户拖动或自动适配过的列宽，其余列打开时按内容测量
+	Widths map[string]float32 `json:"widths,omitempty"`
+}
+
+// columnLayoutDir 保存各文件列布局的目录
+func columnLayoutDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "csvview", "layouts"), nil
+}
+
+// columnLayoutPath 按文件路径的哈希命名，避免路径中的字符不能作为文件名
+func columnLayoutPath(key string) (string, error) {
+	dir, err := columnLayoutDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// LoadColumnLayout 读取文件的列布局，没有保存过时返回 false
+func LoadColumnLayout(key string) (ColumnLayout, bool) {
+	var l ColumnLayout
+	path, err := columnLayoutPath(key)
+	if err != nil {
+		return l, false
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return l, false
+	}
+	return l, json.Unmarshal(b, &l) == nil
+}
+
+// SaveColumnLayout 保存文件的列布局
+func SaveColumnLayout(key string, l ColumnLayout) error {
+	path, err := columnLayoutPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+// columnKeys 列名加出现次序，用于在保存的布局中区分同名列
+func columnKeys(header []string) []string {
+	seen := make(map[string]int)
+	keys := make([]string, len(header))
+	for i, h := range header {
+		seen[h]++
+		keys[i] = h
+		if n := seen[h]; n > 1 {
+			keys[i] = h + "#" + strconv.Itoa(n)
+		}
+	}
+	return keys
+}
+
+// columns 显示列对应的源列；源列数变化（如新增计算列）时重新计算，新列显示在最后
+func (vt *VirtualTable) columns() []int {
+	n := vt.source.ColCount()
+	if vt.cols != nil && vt.colsFor == n {
+		return vt.cols
+	}
+	cols := make([]int, 0, n)
+	for _, c := range vt.fullOrder() {
+		if !vt.hidden[c] {
+			cols = append(cols, c)
+		}
+	}
+	vt.cols, vt.colsFor = cols, n
+	return cols
+}
+
+// fullOrder 所有源列按显示顺序排列，包括隐藏的列
+func (vt *VirtualTable) fullOrder() []int {
+	n := vt.source.ColCount()
+	seen := make([]bool, n)
+	out := make([]int, 0, n)
+	for _, c := range vt.order {
+		if c < n && !seen[c] {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+	for c := range n {
+		if !seen[c] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// colCount 显示的列数
+func (vt *VirtualTable) colCount() int {
+	return len(vt.columns())
+}
+
+// SourceCol 显示列对应的数据源列，超出范围时返回 -1
+func (vt *VirtualTable) SourceCol(col int) int {
+	cols := vt.columns()
+	if col < 0 || col >= len(cols) {
+		return -1
+	}
+	return cols[col]
+}
+
+// VisibleColumns 按显示顺序排列的可见源列
+func (vt *VirtualTable) VisibleColumns() []int {
+	return slices.Clone(vt.columns())
+}
+
+// CustomColumns 是否隐藏或调整过列顺序
+func (vt *VirtualTable) CustomColumns() bool {
+	for i, c := range vt.columns() {
+		if i != c {
+			return true
+		}
+	}
+	return vt.colCount() != vt.source.ColCount()
+}
+
+// Frozen 固定显示的列数
+func (vt *VirtualTable) Frozen() int {
+	return vt.Table.StickyColumnCount
+}
+
+// SetColumns 设置列顺序（源列号，包括隐藏的列）、隐藏的列和冻结的列数，并保存到文件的列布局
+func (vt *VirtualTable) SetColumns(order []int, hidden map[int]bool, frozen int) {
+	vt.order = slices.Clone(order)
+	vt.hidden = make(map[int]bool, len(hidden))
+	for c, h := range hidden {
+		if h {
+			vt.hidden[c] = true
+		}
+	}
+	vt.cols = nil
+	vt.Table.StickyColumnCount = max(min(frozen, vt.colCount()), 0)
+	// 选区按显示列记录，列变化后不再对应原来的单元格
+	vt.anchor, vt.selected = nil, nil
+	vt.applyWidths()
+	vt.Table.Refresh()
+	vt.selectionChanged()
+	vt.saveLayout()
+}
+
+// SetFrozen 横向滚动时固定显示前 n 列，表头行始终固定
+func (vt *VirtualTable) SetFrozen(n int) {
+	vt.SetColumns(vt.fullOrder(), vt.hidden, n)
+}
+
+// HideColumns 隐藏显示列，至少保留一列
+func (vt *VirtualTable) HideColumns(cols []int) {
+	hidden := make(map[int]bool, len(vt.hidden)+len(cols))
+	for c := range vt.hidden {
+		hidden[c] = true
+	}
+	for _, c := range cols {
+		if s := vt.SourceCol(c); s >= 0 {
+			hidden[s] = true
+		}
+	}
+	if len(hidden) >= vt.source.ColCount() {
+		return
+	}
+	frozen := vt.Frozen()
+	for _, c := range cols {
+		if c < vt.Frozen() {
+			frozen--
+		}
+	}
+	vt.SetColumns(vt.fullOrder(), hidden, frozen)
+}
+
+// MoveColumn 把显示列 from 移到 to 的位置
+func (vt *VirtualTable) MoveColumn(from, to int) {
+	src, dst := vt.SourceCol(from), vt.SourceCol(to)
+	if src < 0 || dst < 0 || src == dst {
+		return
+	}
+	order := vt.fullOrder()
+	i := slices.Index(order, src)
+	order = slices.Delete(order, i, i+1)
+	j := slices.Index(order, dst)
+	if from < to {
+		j++
+	}
+	order = slices.Insert(order, j, src)
+	vt.SetColumns(order, vt.hidden, vt.Frozen())
+}
+
+// SetLayoutKey 读取并应用 key（文件路径）保存的列布局，之后的修改都保存到这个 key
+func (vt *VirtualTable) SetLayoutKey(key string) {
+	vt.layoutKey = key
+	if l, ok := LoadColumnLayout(key); ok {
+		vt.pendingLayout = &l
+		vt.applyPendingLayout()
+	}
+}
+
+// applyPendingLayout 表头可用后把按列名保存的布局换算成列号
+func (vt *VirtualTable) applyPendingLayout() {
+	l := vt.pendingLayout
+	header := vt.source.Header()
+	if l == nil || len(header) == 0 {
+		return
+	}
+	vt.pendingLayout = nil
+	index := make(map[string]int)
+	for i, k := range columnKeys(header) {
+		index[k] = i
+	}
+	var order []int
+	for _, k := range l.Order {
+		if c, ok := index[k]; ok {
+			order = append(order, c)
+		}
+	}
+	hidden := make(map[int]bool)
+	for _, k := range l.Hidden {
+		if c, ok := index[k]; ok {
+			hidden[c] = true
+		}
+	}
+	if len(hidden) >= vt.source.ColCount() {
+		hidden = nil
+	}
+	for k, w := range l.Widths {
+		if c, ok := index[k]; ok && w > 0 {
+			vt.widths[c] = w
+			vt.userWidths[c] = true
+		}
+	}
+	key := vt.layoutKey
+	// 应用读到的布局不需要再写回
+	vt.layoutKey = ""
+	vt.SetColumns(order, hidden, l.Frozen)
+	vt.layoutKey = key
+}
+
+// Layout 当前的列布局
+func (vt *VirtualTable) Layout() ColumnLayout {
+	keys := columnKeys(vt.source.Header())
+	name := func(c int) string {
+		if c < len(keys) {
+			return keys[c]
+		}
+		return ""
+	}
+	var l ColumnLayout
+	for _, c := range vt.fullOrder() {
+		if k := name(c); k != "" {
+			l.Order = append(l.Order, k)
+			if vt.hidden[c] {
+				l.Hidden = append(l.Hidden, k)
+			}
+			if vt.userWidths[c] {
+				if l.Widths == nil {
+					l.Widths = make(map[string]float32)
+				}
+				l.Widths[k] = vt.widths[c]
+			}
+		}
+	}
+	l.Frozen = vt.Frozen()
+	return l
+}
+
+// saveLayout 保存列布局；没有对应文件的标签页（比较结果等）不保存
+func (vt *VirtualTable) saveLayout() {
+	if vt.layoutKey == "" {
+		return
+	}
+	if err := SaveColumnLayout(vt.layoutKey, vt.Layout()); err != nil {
+		log.Println("save column layout:", err)
+	}
+}
@@ -0,0 +1,131 @@
+package shower
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/export"
+	"github.com/devbiu/CsvView/loader"
+	"github.com/devbiu/CsvView/ops"
+)
+
+// dedupPreviewRows 结果对话框中预览的重复行数
+const dedupPreviewRows = 200
+
+// dedupFound 去重扫描的结果和前几行重复行
+type dedupFound struct {
+	res     *ops.DedupResult
+	preview *loader.MemSource
+}
+
+// ShowDedupDialog 选择判断重复的列、规范化方式和保留哪一行，扫描后预览重复行，
+// 再把去重后的结果写到新文件，或者在编辑中把重复行标记为删除
+func ShowDedupDialog(w fyne.Window, vt *VirtualTable, name string) {
+	src := vt.Source()
+	keys := widget.NewCheckGroup(src.Header(), nil)
+	keysScroll := container.NewVScroll(keys)
+	keysScroll.SetMinSize(fyne.NewSize(0, 180))
+	wholeRow := widget.NewCheck("Compare whole row", func(on bool) {
+		if on {
+			keys.Disable()
+		} else {
+			keys.Enable()
+		}
+	})
+	wholeRow.SetChecked(true)
+	trim := widget.NewCheck("Ignore leading/trailing spaces", nil)
+	fold := widget.NewCheck("Ignore case", nil)
+	keeps := make([]string, len(ops.Keeps))
+	for i, k := range ops.Keeps {
+		keeps[i] = string(k)
+	}
+	keep := widget.NewSelect(keeps, nil)
+	keep.SetSelected(string(ops.KeepFirst))
+
+	form := widget.NewForm(widget.NewFormItem("Keep", keep))
+	content := container.NewBorder(container.NewVBox(wholeRow, trim, fold, form), nil, nil, nil,
+		container.NewBorder(widget.NewLabel("Key columns"), nil, nil, nil, keysScroll))
+	d := dialog.NewCustomConfirm("Deduplicate", "Find Duplicates", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		opts := ops.DedupOptions{Trim: trim.Checked, FoldCase: fold.Checked, Keep: ops.Keep(keep.Selected)}
+		if !wholeRow.Checked {
+			// 按表头顺序传递键列
+			for _, h := range keys.Options {
+				if slices.Contains(keys.Selected, h) {
+					opts.Keys = append(opts.Keys, h)
+				}
+			}
+			if len(opts.Keys) == 0 {
+				dialog.ShowInformation("Deduplicate", "Choose at least one key column", w)
+				return
+			}
+		}
+		runOperation(w, "Deduplicate", func(cancel <-chan struct{}, progress func(int)) (dedupFound, error) {
+			opts.Cancel, opts.Progress = cancel, progress
+			res, err := ops.Dedup(src, opts)
+			if err != nil {
+				return dedupFound{}, err
+			}
+			preview, err := res.Preview(src, dedupPreviewRows)
+			return dedupFound{res, preview}, err
+		}, func(f dedupFound) { showDedupResult(w, vt, name, f) })
+	}, w)
+	d.Resize(fyne.NewSize(450, 480))
+	d.Show()
+}
+
+// showDedupResult 显示找到的重复行，选择写出新文件或标记删除
+func showDedupResult(w fyne.Window, vt *VirtualTable, name string, f dedupFound) {
+	if f.res.Duplicates == 0 {
+		dialog.ShowInformation("Deduplicate", fmt.Sprintf("No duplicates in %d rows", f.res.Rows), w)
+		return
+	}
+	summary := fmt.Sprintf("%d duplicate rows in %d rows", f.res.Duplicates, f.res.Rows)
+	if f.res.Duplicates > f.preview.RowCount() {
+		summary += fmt.Sprintf(" (showing the first %d)", f.preview.RowCount())
+	}
+	preview := NewVirtualTable(f.preview)
+
+	var d *dialog.CustomDialog
+	saveButton := widget.NewButton("Save Deduplicated As...", func() {
+		d.Hide()
+		save := dialog.NewFileSave(func(wc fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if wc == nil {
+				return
+			}
+			// 格式按扩展名推断
+			path := wc.URI().Path()
+			wc.Close()
+			runExport(w, vt, path, export.Options{Skip: f.res.IsDuplicate})
+		}, w)
+		base := strings.TrimSuffix(name, filepath.Ext(name))
+		save.SetFileName(base + "-dedup.csv")
+		save.Show()
+	})
+	markButton := widget.NewButton("Mark for Deletion", func() {
+		d.Hide()
+		if err := vt.SetDeleted(f.res.DuplicateRows(), true); err != nil {
+			dialog.ShowError(err, w)
+		}
+	})
+	if _, ok := loader.AsEditor(vt.Source()); !ok {
+		markButton.Disable()
+	}
+	content := container.NewBorder(widget.NewLabel(summary), container.NewHBox(saveButton, markButton), nil, nil, preview.Scroll)
+	d = dialog.NewCustom("Duplicates", "Close", content, w)
+	d.SetOnClosed(preview.Close)
+	d.Resize(fyne.NewSize(760, 520))
+	d.Show()
+}
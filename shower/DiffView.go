@@ -0,0 +1,136 @@
+package shower
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/loader"
+	"github.com/devbiu/CsvView/ops"
+)
+
+// NewDiffView 显示比较结果：新增行标绿、删除行标红，修改的行中旧值和新值不同的单元格标黄
+func NewDiffView(vt *VirtualTable, res *ops.DiffResult) fyne.CanvasObject {
+	vt.SetCellColor(func(row, col int) fyne.ThemeColorName {
+		// 通过视图读取，排序、筛选后的行号仍然对应正确的记录
+		rec, ok := vt.Source().GetRow(row)
+		if !ok || len(rec) == 0 {
+			return ""
+		}
+		switch rec[0] {
+		case ops.DiffAdded:
+			return theme.ColorNameSuccess
+		case ops.DiffRemoved:
+			return theme.ColorNameError
+		}
+		if c, _ := res.Cell(col); c >= 0 && res.CellChanged(rec, c) {
+			return theme.ColorNameWarning
+		}
+		return ""
+	})
+	summary := widget.NewLabel(fmt.Sprintf("%d added, %d removed, %d changed (key: %v)",
+		res.Added, res.Removed, res.Changed, res.Keys))
+	return container.NewBorder(summary, nil, nil, nil, vt.Scroll)
+}
+
+// CompareSource 可以参与比较的已打开表格
+type CompareSource struct {
+	Title  string
+	Source loader.RowSource
+}
+
+// ShowCompareDialog 选择旧、新两个已打开的表格和键列，在后台比较后通过 onDone 返回结果
+func ShowCompareDialog(w fyne.Window, sources []CompareSource, onDone func(title string, res *ops.DiffResult)) {
+	if len(sources) < 2 {
+		dialog.ShowInformation("Compare", "Open the two files to compare first", w)
+		return
+	}
+	titles := make([]string, len(sources))
+	for i, s := range sources {
+		titles[i] = s.Title
+	}
+	find := func(title string) loader.RowSource {
+		for _, s := range sources {
+			if s.Title == title {
+				return s.Source
+			}
+		}
+		return nil
+	}
+
+	keys := widget.NewCheckGroup(nil, nil)
+	oldSelect := widget.NewSelect(titles, nil)
+	newSelect := widget.NewSelect(titles, nil)
+	// 键列只能从两边都有的列中选
+	updateKeys := func(string) {
+		o, n := find(oldSelect.Selected), find(newSelect.Selected)
+		if o == nil || n == nil {
+			return
+		}
+		inNew := make(map[string]bool)
+		for _, h := range n.Header() {
+			inNew[h] = true
+		}
+		var common []string
+		for _, h := range o.Header() {
+			if inNew[h] {
+				common = append(common, h)
+			}
+		}
+		keys.Options = common
+		var selected []string
+		for _, k := range keys.Selected {
+			if inNew[k] {
+				selected = append(selected, k)
+			}
+		}
+		if len(selected) == 0 && len(common) > 0 {
+			selected = common[:1]
+		}
+		keys.Selected = selected
+		keys.Refresh()
+	}
+	oldSelect.OnChanged = updateKeys
+	newSelect.OnChanged = updateKeys
+	oldSelect.SetSelected(titles[0])
+	newSelect.SetSelected(titles[1])
+
+	form := widget.NewForm(
+		widget.NewFormItem("Old", oldSelect),
+		widget.NewFormItem("New", newSelect),
+	)
+	content := container.NewBorder(form, nil, nil, nil,
+		container.NewBorder(widget.NewLabel("Key columns"), nil, nil, nil, container.NewVScroll(keys)))
+	d := dialog.NewCustomConfirm("Compare", "Compare", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		o, n := find(oldSelect.Selected), find(newSelect.Selected)
+		if o == nil || n == nil || o == n {
+			dialog.ShowInformation("Compare", "Choose two different tables", w)
+			return
+		}
+		if len(keys.Selected) == 0 {
+			dialog.ShowInformation("Compare", "Choose at least one key column", w)
+			return
+		}
+		// 按表头顺序传递键列
+		var ordered []string
+		for _, k := range keys.Options {
+			for _, s := range keys.Selected {
+				if s == k {
+					ordered = append(ordered, k)
+				}
+			}
+		}
+		title := oldSelect.Selected + " vs " + newSelect.Selected
+		runOperation(w, "Compare", func(cancel <-chan struct{}, progress func(int)) (*ops.DiffResult, error) {
+			return ops.Diff(o, n, ops.DiffOptions{Keys: ordered, Cancel: cancel, Progress: progress})
+		}, func(res *ops.DiffResult) { onDone(title, res) })
+	}, w)
+	d.Resize(fyne.NewSize(450, 450))
+	d.Show()
+}
@@ -0,0 +1,199 @@
+package shower
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/export"
+)
+
+// ShowExportDialog 选择格式后把当前视图（或选区）导出到文件
+func ShowExportDialog(w fyne.Window, vt *VirtualTable, name string) {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+
+	formats := make([]string, len(export.Formats))
+	for i, f := range export.Formats {
+		formats[i] = string(f)
+	}
+	dialects := make([]string, len(export.Dialects))
+	for i, d := range export.Dialects {
+		dialects[i] = string(d)
+	}
+
+	dialectSelect := widget.NewSelect(dialects, nil)
+	dialectSelect.SetSelected(string(export.DialectSQLite))
+	tableEntry := widget.NewEntry()
+	tableEntry.SetText(sqlTableName(base))
+	batchEntry := widget.NewEntry()
+	batchEntry.SetText("500")
+	sqlForm := widget.NewForm(
+		widget.NewFormItem("Dialect", dialectSelect),
+		widget.NewFormItem("Table", tableEntry),
+		widget.NewFormItem("Rows per INSERT", batchEntry),
+	)
+	sqlForm.Hide()
+
+	compressionSelect := widget.NewSelect(export.Compressions, nil)
+	compressionSelect.SetSelected(export.Compressions[0])
+	rowGroupEntry := widget.NewEntry()
+	rowGroupEntry.SetText("131072")
+	parquetForm := widget.NewForm(
+		widget.NewFormItem("Compression", compressionSelect),
+		widget.NewFormItem("Rows per row group", rowGroupEntry),
+	)
+	parquetForm.Hide()
+
+	dbTableEntry := widget.NewEntry()
+	dbTableEntry.SetText(sqlTableName(base))
+	sqliteForm := widget.NewForm(widget.NewFormItem("Table", dbTableEntry))
+	sqliteForm.Hide()
+
+	formatSelect := widget.NewSelect(formats, func(s string) {
+		sqlForm.Hide()
+		parquetForm.Hide()
+		sqliteForm.Hide()
+		switch export.Format(s) {
+		case export.FormatSQL:
+			sqlForm.Show()
+		case export.FormatParquet:
+			parquetForm.Show()
+		case export.FormatSQLiteDB:
+			sqliteForm.Show()
+		}
+	})
+	formatSelect.SetSelected(string(export.FormatJSON))
+
+	sel, hasSel := vt.Selection()
+	selOnly := widget.NewCheck("Selected cells only", nil)
+	if !hasSel {
+		selOnly.Disable()
+	}
+	// 隐藏或调整过列顺序时可以按界面上的列导出
+	visibleOnly := widget.NewCheck("Visible columns only, in displayed order", nil)
+	if !vt.CustomColumns() {
+		visibleOnly.Hide()
+	}
+
+	content := container.NewVBox(
+		widget.NewForm(widget.NewFormItem("Format", formatSelect)),
+		sqlForm,
+		parquetForm,
+		sqliteForm,
+		selOnly,
+		visibleOnly,
+	)
+	dialog.ShowCustomConfirm("Export", "Export", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		opts := export.Options{
+			Format:  export.Format(formatSelect.Selected),
+			Dialect: export.Dialect(dialectSelect.Selected),
+			Table:   tableEntry.Text,
+
+			Compression: compressionSelect.Selected,
+		}
+		if opts.Format == export.FormatSQLiteDB {
+			opts.Table = dbTableEntry.Text
+		}
+		if n, err := strconv.Atoi(batchEntry.Text); err == nil {
+			opts.BatchSize = n
+		}
+		if n, err := strconv.Atoi(rowGroupEntry.Text); err == nil {
+			opts.RowGroupSize = n
+		}
+		switch {
+		case selOnly.Checked:
+			opts.Rows = &export.Range{From: sel.Top, To: sel.Bottom + 1}
+			for c := sel.Left; c <= sel.Right; c++ {
+				opts.Cols = append(opts.Cols, vt.SourceCol(c))
+			}
+		case visibleOnly.Checked:
+			opts.Cols = vt.VisibleColumns()
+		}
+		save := dialog.NewFileSave(func(wc fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if wc == nil {
+				return
+			}
+			path := wc.URI().Path()
+			wc.Close()
+			runExport(w, vt, path, opts)
+		}, w)
+		save.SetFileName(base + opts.Format.Ext())
+		save.SetFilter(storage.NewExtensionFileFilter([]string{opts.Format.Ext()}))
+		save.Show()
+	}, w)
+}
+
+// runExport 在后台执行导出，期间显示进度并允许取消
+func runExport(w fyne.Window, vt *VirtualTable, path string, opts export.Options) {
+	cancel := make(chan struct{})
+	opts.Cancel = cancel
+	status := widget.NewLabel("Exporting...")
+	bar := widget.NewProgressBarInfinite()
+	progress := dialog.NewCustom("Export", "Cancel", container.NewVBox(status, bar), w)
+	progress.SetOnClosed(func() {
+		select {
+		case <-cancel:
+		default:
+			close(cancel)
+		}
+	})
+	opts.Progress = func(done, total int) {
+		fyne.Do(func() {
+			if total > 0 {
+				status.SetText(fmt.Sprintf("Exported %d of %d rows", done, total))
+			} else {
+				status.SetText(fmt.Sprintf("Exported %d rows", done))
+			}
+		})
+	}
+	progress.Show()
+
+	go func() {
+		err := export.ExportFile(path, vt.Source(), opts)
+		fyne.Do(func() {
+			select {
+			case <-cancel:
+			default:
+				close(cancel)
+				progress.Hide()
+			}
+			switch {
+			case errors.Is(err, export.ErrCanceled):
+			case err != nil:
+				dialog.ShowError(err, w)
+			default:
+				dialog.ShowInformation("Export", "Saved "+path, w)
+			}
+		})
+	}()
+}
+
+// sqlTableName 把文件名转换成可用作表名的标识符
+func sqlTableName(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		if r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	if sb.Len() == 0 {
+		return "data"
+	}
+	return sb.String()
+}
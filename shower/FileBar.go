@@ -0,0 +1,153 @@
+package shower
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/loader"
+)
+
+// maxConflictLines 冲突对话框中最多列出的修改
+const maxConflictLines = 200
+
+// NewFileBar 在文本文件标签页顶部加上跟踪文件追加（Follow）和自动滚动的开关。
+// 跟踪时文件被截断或替换（日志轮转）会自动重新打开；没有跟踪时文件被其他程序修改，
+// 显示提示条由用户决定是否重新打开。重新打开时计算列、筛选、排序和未保存的修改都会保留
+func NewFileBar(w fyne.Window, vt *VirtualTable, view *loader.View, content fyne.CanvasObject) fyne.CanvasObject {
+	status := widget.NewLabel("")
+	follower := func() (loader.Follower, bool) {
+		f, ok := view.Base().(loader.Follower)
+		return f, ok
+	}
+
+	var follow *widget.Check
+	follow = widget.NewCheck("Follow", func(on bool) {
+		f, ok := follower()
+		if !ok {
+			return
+		}
+		if err := f.Follow(on); err != nil {
+			dialog.ShowError(err, w)
+			follow.SetChecked(false)
+			return
+		}
+		if on {
+			status.SetText("Following file changes")
+		} else {
+			status.SetText("")
+		}
+	})
+	autoScroll := widget.NewCheck("Auto-scroll", vt.SetAutoScroll)
+
+	var banner *fyne.Container
+	// reload 重新打开文件并把未保存的修改转移过去，无法合并的修改交给用户决定
+	reload := func(done string) {
+		r, ok := view.Base().(loader.Reloadable)
+		if !ok {
+			return
+		}
+		banner.Hide()
+		status.SetText("Reloading...")
+		edits, deleted := r.PendingEdits(), r.DeletedRows()
+		go func() {
+			src, err := r.Reopen()
+			var conflicts []loader.EditConflict
+			if err == nil {
+				if conflicts, err = loader.ReapplyEdits(src, edits, deleted); err != nil {
+					src.Close()
+				}
+			}
+			fyne.Do(func() {
+				if err != nil {
+					status.SetText("Could not reopen the file: " + err.Error())
+					follow.SetChecked(false)
+					return
+				}
+				view.Replace(src)
+				if nf, ok := src.(loader.Follower); ok && follow.Checked {
+					if err := nf.Follow(true); err != nil {
+						dialog.ShowError(err, w)
+					}
+				}
+				status.SetText(done + " at " + time.Now().Format("15:04:05"))
+				if len(conflicts) > 0 {
+					showEditConflicts(w, src, conflicts)
+				}
+			})
+		}()
+	}
+
+	message := widget.NewLabelWithStyle("This file was changed by another program.", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	banner = container.NewHBox(
+		widget.NewIcon(theme.WarningIcon()), message,
+		widget.NewButton("Reload", func() { reload("Reloaded") }),
+		widget.NewButton("Dismiss", func() { banner.Hide() }),
+	)
+	banner.Hide()
+
+	view.Subscribe(func(e loader.Event) {
+		switch e.Kind {
+		case loader.EventFileReplaced:
+			fyne.Do(func() { reload("Reloaded after the file was truncated or replaced") })
+		case loader.EventFileChanged:
+			fyne.Do(func() {
+				text := "This file was changed by another program."
+				if r, ok := view.Base().(loader.Reloadable); ok && len(r.PendingEdits()) > 0 {
+					text += " Reloading keeps your edits where the file did not change the same cells."
+				}
+				message.SetText(text)
+				banner.Show()
+			})
+		}
+	})
+
+	bar := container.NewHBox(follow, autoScroll, status)
+	return container.NewBorder(container.NewVBox(bar, banner), nil, nil, nil, content)
+}
+
+// showEditConflicts 列出重新打开后无法自动合并的修改，用户选择保留自己的值或采用文件中的值
+func showEditConflicts(w fyne.Window, src loader.RowSource, conflicts []loader.EditConflict) {
+	header := src.Header()
+	var sb strings.Builder
+	for i, c := range conflicts {
+		if i == maxConflictLines {
+			fmt.Fprintf(&sb, "... and %d more\n", len(conflicts)-i)
+			break
+		}
+		name := fmt.Sprintf("column %d", c.Col+1)
+		if c.Col < len(header) {
+			name = header[c.Col]
+		}
+		if c.Missing {
+			fmt.Fprintf(&sb, "Row %d, %s: the row no longer exists (your value %q)\n", c.Row+1, name, c.New)
+		} else if c.OldUnknown {
+			fmt.Fprintf(&sb, "Row %d, %s: yours %q, file now %q (value before your edit unknown)\n", c.Row+1, name, c.New, c.Current)
+		} else {
+			fmt.Fprintf(&sb, "Row %d, %s: yours %q, file now %q (was %q)\n", c.Row+1, name, c.New, c.Current, c.Old)
+		}
+	}
+	list := widget.NewLabel(sb.String())
+	list.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(
+		widget.NewLabel(fmt.Sprintf("%d edits could not be merged because the file changed the same cells:", len(conflicts))),
+		nil, nil, nil, container.NewVScroll(list))
+	d := dialog.NewCustomConfirm("Edit Conflicts", "Keep Mine", "Use File", content, func(mine bool) {
+		ed, ok := loader.AsEditor(src)
+		if !mine || !ok {
+			return
+		}
+		for _, c := range conflicts {
+			if !c.Missing {
+				ed.SetEdit(c.Row, c.Col, c.New)
+			}
+		}
+	}, w)
+	d.Resize(fyne.NewSize(600, 400))
+	d.Show()
+}
@@ -0,0 +1,235 @@
+package shower
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/loader"
+)
+
+// fixedWidthSampleLines 边界编辑器中预览的行数
+const fixedWidthSampleLines = 40
+
+// ShowFixedWidthDialog 在样例行上点击设置列边界，确认后用得到的列布局打开文件
+func ShowFixedWidthDialog(w fyne.Window, path string, onOpen func(spec loader.FixedWidthSpec)) {
+	lines, err := loader.ReadSampleLines(path, fixedWidthSampleLines)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+
+	ruler := newRulerView(lines)
+	headerCheck := widget.NewCheck("First line is header", nil)
+	trimCheck := widget.NewCheck("Trim spaces", nil)
+	trimCheck.SetChecked(true)
+	summary := widget.NewLabel("")
+	summary.Wrapping = fyne.TextWrapWord
+	ruler.onChanged = func() {
+		summary.SetText(describeBoundaries(ruler.boundaries))
+	}
+	ruler.onChanged()
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("layout name")
+	specs := widget.NewSelect(loader.ListFixedWidthSpecs(), func(name string) {
+		spec, err := loader.LoadFixedWidthSpec(name)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		nameEntry.SetText(spec.Name)
+		headerCheck.SetChecked(spec.HeaderLine)
+		trimCheck.SetChecked(spec.Trim)
+		ruler.setBoundaries(spec.Boundaries)
+	})
+	specs.PlaceHolder = "(saved layouts)"
+
+	current := func() loader.FixedWidthSpec {
+		spec := loader.FixedWidthSpec{
+			Name:       strings.TrimSpace(nameEntry.Text),
+			Boundaries: slices.Clone(ruler.boundaries),
+			HeaderLine: headerCheck.Checked,
+			Trim:       trimCheck.Checked,
+		}
+		spec.Normalize()
+		return spec
+	}
+	save := widget.NewButton("Save layout", func() {
+		if err := loader.SaveFixedWidthSpec(current()); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		specs.SetOptions(loader.ListFixedWidthSpecs())
+	})
+	clearButton := widget.NewButton("Clear", func() {
+		ruler.setBoundaries(nil)
+	})
+
+	scroll := container.NewScroll(ruler)
+	scroll.SetMinSize(fyne.NewSize(800, 360))
+	content := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("Click between characters to add or remove a column boundary."),
+			container.NewHBox(specs, headerCheck, trimCheck, clearButton),
+		),
+		container.NewVBox(summary, container.NewBorder(nil, nil, nil, save, nameEntry)),
+		nil, nil,
+		scroll,
+	)
+	dialog.ShowCustomConfirm("Fixed-width columns", "Open", "Cancel", content, func(ok bool) {
+		if ok {
+			onOpen(current())
+		}
+	}, w)
+}
+
+// describeBoundaries 列出每一列的字符范围
+func describeBoundaries(bounds []int) string {
+	var sb strings.Builder
+	start := 0
+	for i, b := range bounds {
+		fmt.Fprintf(&sb, "%d: %d-%d   ", i+1, start, b-1)
+		start = b
+	}
+	fmt.Fprintf(&sb, "%d: %d-", len(bounds)+1, start)
+	return sb.String()
+}
+
+// rulerView 等宽显示样例行，顶部是字符位置标尺，点击切换该位置的列边界
+type rulerView struct {
+	widget.BaseWidget
+	lines      []string
+	boundaries []int
+	onChanged  func()
+}
+
+func newRulerView(lines []string) *rulerView {
+	r := &rulerView{lines: lines}
+	r.ExtendBaseWidget(r)
+	return r
+}
+
+// cellSize 单个等宽字符的尺寸
+func cellSize() fyne.Size {
+	return fyne.MeasureText("M", theme.TextSize(), fyne.TextStyle{Monospace: true})
+}
+
+func (r *rulerView) setBoundaries(b []int) {
+	r.boundaries = slices.Clone(b)
+	slices.Sort(r.boundaries)
+	r.Refresh()
+	if r.onChanged != nil {
+		r.onChanged()
+	}
+}
+
+// Tapped 边界取最近的字符间隙
+func (r *rulerView) Tapped(e *fyne.PointEvent) {
+	pos := int(e.Position.X/cellSize().Width + 0.5)
+	if pos <= 0 {
+		return
+	}
+	if i, found := slices.BinarySearch(r.boundaries, pos); found {
+		r.boundaries = slices.Delete(r.boundaries, i, i+1)
+	} else {
+		r.boundaries = slices.Insert(r.boundaries, i, pos)
+	}
+	r.Refresh()
+	if r.onChanged != nil {
+		r.onChanged()
+	}
+}
+
+// width 最长样例行的字符数
+func (r *rulerView) width() int {
+	n := 0
+	for _, l := range r.lines {
+		n = max(n, utf8.RuneCountInString(l))
+	}
+	return n + 10
+}
+
+// rulerText 每 10 个字符标一次位置
+func rulerText(n int) (marks, ticks string) {
+	var m, t strings.Builder
+	for i := 0; i < n; i++ {
+		switch {
+		case i%10 == 0:
+			s := fmt.Sprint(i)
+			if m.Len() <= i {
+				m.WriteString(strings.Repeat(" ", i-m.Len()) + s)
+			}
+			t.WriteByte('|')
+		case i%5 == 0:
+			t.WriteByte('+')
+		default:
+			t.WriteByte('.')
+		}
+	}
+	return m.String(), t.String()
+}
+
+func (r *rulerView) CreateRenderer() fyne.WidgetRenderer {
+	rr := &rulerRenderer{view: r}
+	rr.build()
+	return rr
+}
+
+type rulerRenderer struct {
+	view    *rulerView
+	objects []fyne.CanvasObject
+}
+
+// build 按当前样例行和边界重建绘制对象
+func (rr *rulerRenderer) build() {
+	r := rr.view
+	cell := cellSize()
+	mono := fyne.TextStyle{Monospace: true}
+	marks, ticks := rulerText(r.width())
+	texts := append([]string{marks, ticks}, r.lines...)
+
+	rr.objects = rr.objects[:0]
+	height := float32(len(texts)) * cell.Height
+	for _, b := range r.boundaries {
+		line := canvas.NewLine(theme.Color(theme.ColorNamePrimary))
+		line.StrokeWidth = 1
+		x := float32(b) * cell.Width
+		line.Position1 = fyne.NewPos(x, 0)
+		line.Position2 = fyne.NewPos(x, height)
+		rr.objects = append(rr.objects, line)
+	}
+	for i, s := range texts {
+		t := canvas.NewText(s, theme.Color(theme.ColorNameForeground))
+		if i < 2 {
+			t.Color = color.Gray{Y: 0x88}
+		}
+		t.TextStyle = mono
+		t.Move(fyne.NewPos(0, float32(i)*cell.Height))
+		t.Resize(fyne.NewSize(float32(r.width())*cell.Width, cell.Height))
+		rr.objects = append(rr.objects, t)
+	}
+}
+
+func (rr *rulerRenderer) Layout(fyne.Size) {}
+
+func (rr *rulerRenderer) MinSize() fyne.Size {
+	cell := cellSize()
+	return fyne.NewSize(float32(rr.view.width())*cell.Width, float32(len(rr.view.lines)+2)*cell.Height)
+}
+
+func (rr *rulerRenderer) Refresh() {
+	rr.build()
+	canvas.Refresh(rr.view)
+}
+
+func (rr *rulerRenderer) Objects() []fyne.CanvasObject { return rr.objects }
+func (rr *rulerRenderer) Destroy()                     {}
@@ -0,0 +1,133 @@
+package shower
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/loader"
+)
+
+const (
+	goToRow    = "Row"
+	goToLine   = "Line"
+	goToOffset = "Byte offset"
+)
+
+// ShowGoToDialog 跳转到指定的行、文件中的物理行或字节偏移；后两者通过偏移表直接定位
+func ShowGoToDialog(w fyne.Window, vt *VirtualTable) {
+	modes := []string{goToRow}
+	if _, ok := loader.AsLineLocator(vt.source); ok {
+		modes = append(modes, goToLine, goToOffset)
+	}
+	mode := widget.NewRadioGroup(modes, nil)
+	mode.Horizontal = true
+	mode.Required = true
+	mode.SetSelected(goToRow)
+	target := widget.NewEntry()
+	target.SetPlaceHolder("1,000,000 or 0x1F00 for offsets")
+
+	d := dialog.NewForm("Go To", "Go", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("", mode),
+		widget.NewFormItem("Target", target),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		n, err := ParseGoToTarget(target.Text, mode.Selected == goToOffset)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		switch mode.Selected {
+		case goToLine:
+			err = vt.GoToLine(int(n))
+		case goToOffset:
+			err = vt.GoToOffset(n)
+		default:
+			err = vt.GoToRow(int(n))
+		}
+		if err != nil {
+			dialog.ShowError(err, w)
+		}
+	}, w)
+	d.Resize(fyne.NewSize(420, 200))
+	d.Show()
+	w.Canvas().Focus(target)
+}
+
+// ParseGoToTarget 解析跳转目标，允许千位分隔符。按十进制解析，开头的 0 不表示八进制；
+// 字节偏移还可以用 0x 开头的十六进制
+func ParseGoToTarget(text string, offset bool) (int64, error) {
+	s := strings.NewReplacer(",", "", "_", "", " ", "").Replace(text)
+	base := 10
+	if hex, ok := strings.CutPrefix(strings.ToLower(s), "0x"); ok && offset {
+		s, base = hex, 16
+	}
+	n, err := strconv.ParseInt(s, base, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", text)
+	}
+	return n, nil
+}
+
+// activeCol 活动单元格所在的列，跳转到其他行时保持不变
+func (vt *VirtualTable) activeCol() int {
+	if vt.selected != nil {
+		return vt.selected.Col
+	}
+	return 0
+}
+
+// GoToRow 跳转到第 row 行（从 1 开始）；还没索引到时等索引推进后再跳转
+func (vt *VirtualTable) GoToRow(row int) error {
+	if row < 1 || vt.source.Indexed() && row > vt.source.RowCount() {
+		return fmt.Errorf("row %d is out of range, the table has %s rows", row, groupDigits(vt.source.RowCount()))
+	}
+	vt.GoTo(row-1, vt.activeCol())
+	return nil
+}
+
+// GoToLine 跳转到包含文件第 line 行的数据行
+func (vt *VirtualTable) GoToLine(line int) error {
+	ll, ok := loader.AsLineLocator(vt.source)
+	if !ok {
+		return errors.New("line numbers are not available for this table")
+	}
+	row, ok := ll.RowAtLine(line)
+	if !ok {
+		return vt.notFound(fmt.Sprintf("line %d", line))
+	}
+	vt.GoTo(row, vt.activeCol())
+	return nil
+}
+
+// GoToOffset 跳转到包含文件字节偏移 off 的数据行
+func (vt *VirtualTable) GoToOffset(off int64) error {
+	ll, ok := loader.AsLineLocator(vt.source)
+	if !ok {
+		return errors.New("byte offsets are not available for this table")
+	}
+	row, ok := ll.RowAtOffset(off)
+	if !ok {
+		return vt.notFound(fmt.Sprintf("byte offset %d", off))
+	}
+	vt.GoTo(row, vt.activeCol())
+	return nil
+}
+
+// notFound 说明找不到目标行的可能原因
+func (vt *VirtualTable) notFound(what string) error {
+	switch {
+	case !vt.source.Indexed():
+		return fmt.Errorf("%s has not been indexed yet or is the header", what)
+	case vt.mapped():
+		return fmt.Errorf("%s is the header, past the end of the file or hidden by the filter", what)
+	default:
+		return fmt.Errorf("%s is the header or past the end of the file", what)
+	}
+}
@@ -0,0 +1,110 @@
+package shower
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/loader"
+	"github.com/devbiu/CsvView/ops"
+)
+
+// ShowJoinDialog 选择两边的键列、连接方式和要带入的列，在后台把 right 连接到 left。
+// 对话框关闭后 right 会被关闭，结果通过 onDone 返回
+func ShowJoinDialog(w fyne.Window, left, right loader.RowSource, onDone func(res *loader.SpillSource)) {
+	types := make([]string, len(ops.JoinTypes))
+	for i, t := range ops.JoinTypes {
+		types[i] = string(t)
+	}
+	typeSelect := widget.NewSelect(types, nil)
+	typeSelect.SetSelected(string(ops.JoinLeft))
+
+	// 每一行是一对键列，默认选中两边第一个同名列
+	leftKey, rightKey := firstCommon(left.Header(), right.Header())
+	var leftKeys, rightKeys []*widget.Select
+	keyRows := container.NewVBox()
+	addPair := func(l, r string) {
+		ls, rs := widget.NewSelect(left.Header(), nil), widget.NewSelect(right.Header(), nil)
+		ls.SetSelected(l)
+		rs.SetSelected(r)
+		leftKeys = append(leftKeys, ls)
+		rightKeys = append(rightKeys, rs)
+		keyRows.Add(container.NewGridWithColumns(2, ls, rs))
+	}
+	addPair(leftKey, rightKey)
+	addKey := widget.NewButton("Add key", func() { addPair("", "") })
+
+	columns := widget.NewCheckGroup(right.Header(), nil)
+	for _, h := range right.Header() {
+		if h != rightKey {
+			columns.Selected = append(columns.Selected, h)
+		}
+	}
+	typeSelect.OnChanged = func(s string) {
+		if ops.JoinType(s) == ops.JoinAnti {
+			columns.Disable()
+		} else {
+			columns.Enable()
+		}
+	}
+
+	top := container.NewVBox(
+		widget.NewForm(widget.NewFormItem("Join type", typeSelect)),
+		container.NewGridWithColumns(2, widget.NewLabel("Key in this table"), widget.NewLabel("Key in joined file")),
+		keyRows,
+		addKey,
+		widget.NewLabel("Columns to bring in"),
+	)
+	content := container.NewBorder(top, nil, nil, nil, container.NewVScroll(columns))
+	d := dialog.NewCustomConfirm("Join", "Join", "Cancel", content, func(ok bool) {
+		if !ok {
+			right.Close()
+			return
+		}
+		opts := ops.JoinOptions{Type: ops.JoinType(typeSelect.Selected), Columns: []string{}}
+		for i := range leftKeys {
+			if leftKeys[i].Selected != "" && rightKeys[i].Selected != "" {
+				opts.LeftKeys = append(opts.LeftKeys, leftKeys[i].Selected)
+				opts.RightKeys = append(opts.RightKeys, rightKeys[i].Selected)
+			}
+		}
+		// 按右表列顺序带入
+		chosen := make(map[string]bool)
+		for _, c := range columns.Selected {
+			chosen[c] = true
+		}
+		for _, h := range right.Header() {
+			if chosen[h] {
+				opts.Columns = append(opts.Columns, h)
+			}
+		}
+		runOperation(w, "Join", func(cancel <-chan struct{}, progress func(int)) (*loader.SpillSource, error) {
+			defer right.Close()
+			opts.Cancel, opts.Progress = cancel, progress
+			return ops.Join(left, right, opts)
+		}, onDone)
+	}, w)
+	d.Resize(fyne.NewSize(500, 500))
+	d.Show()
+}
+
+// firstCommon 两个表头中第一个同名列；没有同名列时取各自的第一列
+func firstCommon(a, b []string) (string, string) {
+	in := make(map[string]bool)
+	for _, h := range b {
+		in[h] = true
+	}
+	for _, h := range a {
+		if in[h] {
+			return h, h
+		}
+	}
+	var x, y string
+	if len(a) > 0 {
+		x = a[0]
+	}
+	if len(b) > 0 {
+		y = b[0]
+	}
+	return x, y
+}
@@ -0,0 +1,49 @@
+package shower
+
+import (
+	"errors"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/ops"
+)
+
+// runOperation 在后台执行比较、连接等耗时操作，期间显示已读取的行数并允许取消；
+// 成功后在主线程上调用 onDone
+func runOperation[T any](w fyne.Window, title string, op func(cancel <-chan struct{}, progress func(done int)) (T, error), onDone func(T)) {
+	cancel := make(chan struct{})
+	status := widget.NewLabel("Working...")
+	progress := dialog.NewCustom(title, "Cancel", container.NewVBox(status, widget.NewProgressBarInfinite()), w)
+	progress.SetOnClosed(func() {
+		select {
+		case <-cancel:
+		default:
+			close(cancel)
+		}
+	})
+	progress.Show()
+
+	go func() {
+		res, err := op(cancel, func(done int) {
+			fyne.Do(func() { status.SetText(fmt.Sprintf("Read %d rows", done)) })
+		})
+		fyne.Do(func() {
+			select {
+			case <-cancel:
+			default:
+				close(cancel)
+				progress.Hide()
+			}
+			switch {
+			case errors.Is(err, ops.ErrCanceled):
+			case err != nil:
+				dialog.ShowError(err, w)
+			default:
+				onDone(res)
+			}
+		})
+	}()
+}
@@ -0,0 +1,174 @@
+package shower
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/loader"
+	"github.com/devbiu/CsvView/ops"
+)
+
+// pivotBuilder 透视表字段布局：把左侧的字段拖到行、列、值三个区域
+type pivotBuilder struct {
+	rows, cols []string
+	values     []ops.PivotValue
+	types      map[string]loader.ColumnType
+
+	rowsBox, colsBox, valuesBox *fyne.Container
+	rowsZone, colsZone, valZone fyne.CanvasObject
+}
+
+// ShowPivotDialog 拖放字段组成透视表，在后台聚合后通过 onDone 返回结果
+func ShowPivotDialog(w fyne.Window, src loader.RowSource, onDone func(res *loader.MemSource)) {
+	pb := &pivotBuilder{
+		types:     make(map[string]loader.ColumnType),
+		rowsBox:   container.NewVBox(),
+		colsBox:   container.NewVBox(),
+		valuesBox: container.NewVBox(),
+	}
+	if types, err := loader.InferTypes(src, loader.DefaultInferSample); err == nil {
+		for i, h := range src.Header() {
+			if i < len(types) {
+				pb.types[h] = types[i]
+			}
+		}
+	}
+	pb.rowsZone = widget.NewCard("Rows", "", pb.rowsBox)
+	pb.colsZone = widget.NewCard("Columns", "", pb.colsBox)
+	pb.valZone = widget.NewCard("Values", "", pb.valuesBox)
+
+	fields := container.NewVBox()
+	for _, h := range src.Header() {
+		fields.Add(newFieldChip(h, pb.drop))
+	}
+	left := container.NewBorder(widget.NewLabel("Drag fields"), nil, nil, nil, container.NewVScroll(fields))
+	right := container.NewVScroll(container.NewVBox(pb.rowsZone, pb.colsZone, pb.valZone))
+	content := container.NewGridWithColumns(2, left, right)
+
+	d := dialog.NewCustomConfirm("Pivot", "Build", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		opts := ops.PivotOptions{Rows: pb.rows, Columns: pb.cols, Values: pb.values}
+		runOperation(w, "Pivot", func(cancel <-chan struct{}, progress func(int)) (*loader.MemSource, error) {
+			opts.Cancel, opts.Progress = cancel, progress
+			return ops.Pivot(src, opts)
+		}, onDone)
+	}, w)
+	d.Resize(fyne.NewSize(700, 520))
+	d.Show()
+}
+
+// drop 字段在 pos（窗口坐标）处松开，落在哪个区域就加入哪个区域
+func (pb *pivotBuilder) drop(name string, pos fyne.Position) {
+	switch {
+	case contains(pb.rowsZone, pos):
+		pb.rows = appendUnique(pb.rows, name)
+	case contains(pb.colsZone, pos):
+		pb.cols = appendUnique(pb.cols, name)
+	case contains(pb.valZone, pos):
+		// 数值列默认求和，其他列默认计数
+		agg := ops.AggCount
+		if t := pb.types[name]; t == loader.TypeInt || t == loader.TypeFloat {
+			agg = ops.AggSum
+		}
+		pb.values = append(pb.values, ops.PivotValue{Column: name, Agg: agg})
+	default:
+		return
+	}
+	pb.refresh()
+}
+
+// refresh 按当前布局重建三个区域的内容
+func (pb *pivotBuilder) refresh() {
+	pb.rowsBox.Objects = nil
+	for i, name := range pb.rows {
+		pb.rowsBox.Add(removableRow(widget.NewLabel(name), func() {
+			pb.rows = append(pb.rows[:i:i], pb.rows[i+1:]...)
+			pb.refresh()
+		}))
+	}
+	pb.colsBox.Objects = nil
+	for i, name := range pb.cols {
+		pb.colsBox.Add(removableRow(widget.NewLabel(name), func() {
+			pb.cols = append(pb.cols[:i:i], pb.cols[i+1:]...)
+			pb.refresh()
+		}))
+	}
+	pb.valuesBox.Objects = nil
+	aggs := make([]string, len(ops.Aggregates))
+	for i, a := range ops.Aggregates {
+		aggs[i] = string(a)
+	}
+	for i, v := range pb.values {
+		sel := widget.NewSelect(aggs, nil)
+		sel.SetSelected(string(v.Agg))
+		sel.OnChanged = func(s string) { pb.values[i].Agg = ops.Aggregate(s) }
+		pb.valuesBox.Add(removableRow(container.NewGridWithColumns(2, widget.NewLabel(v.Column), sel), func() {
+			pb.values = append(pb.values[:i:i], pb.values[i+1:]...)
+			pb.refresh()
+		}))
+	}
+	pb.rowsBox.Refresh()
+	pb.colsBox.Refresh()
+	pb.valuesBox.Refresh()
+}
+
+func removableRow(content fyne.CanvasObject, onRemove func()) fyne.CanvasObject {
+	return container.NewBorder(nil, nil, nil, widget.NewButtonWithIcon("", theme.DeleteIcon(), onRemove), content)
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// contains 窗口坐标 pos 是否落在 obj 内
+func contains(obj fyne.CanvasObject, pos fyne.Position) bool {
+	p := fyne.CurrentApp().Driver().AbsolutePositionForObject(obj)
+	s := obj.Size()
+	return pos.X >= p.X && pos.Y >= p.Y && pos.X < p.X+s.Width && pos.Y < p.Y+s.Height
+}
+
+// fieldChip 可以拖动的字段名，松开时回调 onDrop
+type fieldChip struct {
+	widget.BaseWidget
+	name   string
+	bg     *canvas.Rectangle
+	last   fyne.Position
+	onDrop func(name string, pos fyne.Position)
+}
+
+func newFieldChip(name string, onDrop func(string, fyne.Position)) *fieldChip {
+	c := &fieldChip{name: name, onDrop: onDrop, bg: canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground))}
+	c.bg.CornerRadius = theme.InputRadiusSize()
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+func (c *fieldChip) CreateRenderer() fyne.WidgetRenderer {
+	label := widget.NewLabel(c.name)
+	label.Truncation = fyne.TextTruncateEllipsis
+	return widget.NewSimpleRenderer(container.NewStack(c.bg, label))
+}
+
+func (c *fieldChip) Dragged(e *fyne.DragEvent) {
+	c.last = e.AbsolutePosition
+	if c.bg.FillColor != theme.Color(theme.ColorNameHover) {
+		c.bg.FillColor = theme.Color(theme.ColorNameHover)
+		c.bg.Refresh()
+	}
+}
+
+func (c *fieldChip) DragEnd() {
+	c.bg.FillColor = theme.Color(theme.ColorNameInputBackground)
+	c.bg.Refresh()
+	c.onDrop(c.name, c.last)
+}
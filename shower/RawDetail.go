@@ -0,0 +1,38 @@
+package shower
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/loader"
+)
+
+// NewRawDetail 表格右侧显示选中行的原始记录，JSON 会格式化缩进
+func NewRawDetail(vt *VirtualTable, src loader.RawRecorder) fyne.CanvasObject {
+	text := widget.NewLabel("")
+	text.TextStyle = fyne.TextStyle{Monospace: true}
+	text.Selectable = true
+	vt.OnSelectionChanged(func() {
+		sel, ok := vt.Selection()
+		if !ok {
+			text.SetText("")
+			return
+		}
+		raw, err := src.RawRecord(sel.Top)
+		if err != nil {
+			text.SetText(err.Error())
+			return
+		}
+		var buf bytes.Buffer
+		if json.Indent(&buf, raw, "", "  ") == nil {
+			raw = buf.Bytes()
+		}
+		text.SetText(string(raw))
+	})
+	split := container.NewHSplit(vt.Scroll, container.NewScroll(text))
+	split.Offset = 0.7
+	return split
+}
@@ -0,0 +1,45 @@
+package shower
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/loader"
+)
+
+// ShowSQLiteTables 列出数据库中的表和视图，选中一个后打开
+func ShowSQLiteTables(w fyne.Window, path string, onOpen func(table string)) {
+	objs, err := loader.SQLiteTables(path)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	if len(objs) == 0 {
+		dialog.ShowInformation("Open SQLite", "The database has no tables or views", w)
+		return
+	}
+	selected := 0
+	list := widget.NewList(
+		func() int { return len(objs) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(i widget.ListItemID, obj fyne.CanvasObject) {
+			label := objs[i].Name
+			if objs[i].Type == "view" {
+				label += " (view)"
+			}
+			obj.(*widget.Label).SetText(label)
+		},
+	)
+	list.OnSelected = func(i widget.ListItemID) {
+		selected = i
+	}
+	list.Select(0)
+
+	d := dialog.NewCustomConfirm("Open SQLite", "Open", "Cancel", list, func(ok bool) {
+		if ok {
+			onOpen(objs[selected].Name)
+		}
+	}, w)
+	d.Resize(fyne.NewSize(400, 400))
+	d.Show()
+}
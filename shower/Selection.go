@@ -0,0 +1,340 @@
+package shower
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"image/color"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/export"
+	"github.com/devbiu/CsvView/loader"
+)
+
+// Selection 选中的矩形区域，行列均为闭区间
+type Selection struct {
+	Top, Left, Bottom, Right int
+}
+
+// Rows 选区的行数
+func (s Selection) Rows() int { return s.Bottom - s.Top + 1 }
+
+// Cols 选区的列数
+func (s Selection) Cols() int { return s.Right - s.Left + 1 }
+
+// Selection 返回当前选区
+func (vt *VirtualTable) Selection() (Selection, bool) {
+	if vt.selected == nil || vt.anchor == nil {
+		return Selection{}, false
+	}
+	a, b := *vt.anchor, *vt.selected
+	return Selection{
+		Top: min(a.Row, b.Row), Left: min(a.Col, b.Col),
+		Bottom: max(a.Row, b.Row), Right: max(a.Col, b.Col),
+	}, true
+}
+
+// inSelection 单元格是否在选区内
+func (vt *VirtualTable) inSelection(id widget.TableCellID) bool {
+	s, ok := vt.Selection()
+	return ok && id.Row >= s.Top && id.Row <= s.Bottom && id.Col >= s.Left && id.Col <= s.Right
+}
+
+// selectRange 选中 from 到 to 的矩形；extend 为 true 时保留原来的锚点，只移动活动单元格
+func (vt *VirtualTable) selectRange(from, to widget.TableCellID, extend bool) {
+	if !extend || vt.anchor == nil {
+		vt.anchor = &from
+	}
+	vt.selected = &to
+	vt.Table.Refresh()
+	vt.selectionChanged()
+}
+
+// SelectAll 选中所有已知的行和列
+func (vt *VirtualTable) SelectAll() {
+	rows, cols := vt.source.RowCount(), vt.colCount()
+	if rows == 0 || cols == 0 {
+		return
+	}
+	vt.selectRange(widget.TableCellID{}, widget.TableCellID{Row: rows - 1, Col: cols - 1}, false)
+}
+
+// shiftHeld 当前是否按着 Shift
+func (vt *VirtualTable) shiftHeld() bool {
+	if d, ok := fyne.CurrentApp().Driver().(desktop.Driver); ok {
+		return d.CurrentKeyModifiers()&fyne.KeyModifierShift != 0
+	}
+	return false
+}
+
+// press 鼠标在单元格上按下：开始新的选区，按住 Shift 时从锚点扩展
+func (vt *VirtualTable) press(id widget.TableCellID, shift bool) {
+	// 选区由 VirtualTable 自己绘制，去掉表格自带的单格选中标记
+	vt.Table.UnselectAll()
+	vt.dragging = true
+	vt.selectRange(id, id, shift)
+	if c := fyne.CurrentApp().Driver().CanvasForObject(vt.Table); c != nil {
+		c.Focus(vt.Table)
+	}
+}
+
+// tapHeader 点击表头选中整列，点击行号选中整行
+func (vt *VirtualTable) tapHeader(id widget.TableCellID) {
+	rows, cols := vt.source.RowCount(), vt.colCount()
+	if rows == 0 || cols == 0 {
+		return
+	}
+	vt.Table.UnselectAll()
+	shift := vt.shiftHeld()
+	if id.Row < 0 {
+		from, to := widget.TableCellID{Row: 0, Col: id.Col}, widget.TableCellID{Row: rows - 1, Col: id.Col}
+		if shift && vt.anchor != nil {
+			vt.anchor.Row = 0
+		}
+		vt.selectRange(from, to, shift)
+		return
+	}
+	from, to := widget.TableCellID{Row: id.Row, Col: 0}, widget.TableCellID{Row: id.Row, Col: cols - 1}
+	if shift && vt.anchor != nil {
+		vt.anchor.Col = 0
+	}
+	vt.selectRange(from, to, shift)
+}
+
+// maxCopyCells 复制到剪贴板的单元格上限，更大的区域应该使用导出
+const maxCopyCells = 1_000_000
+
+// SelectionText 把选区格式化为文本；TSV、CSV 不带表头，便于粘贴到电子表格
+func (vt *VirtualTable) SelectionText(f export.Format) (string, error) {
+	sel, ok := vt.Selection()
+	if !ok {
+		return "", errors.New("no cells selected")
+	}
+	if sel.Rows()*sel.Cols() > maxCopyCells {
+		return "", fmt.Errorf("selection has more than %d cells, use Export instead", maxCopyCells)
+	}
+	opts := export.Options{
+		Format:   f,
+		Rows:     &export.Range{From: sel.Top, To: sel.Bottom + 1},
+		NoHeader: f == export.FormatTSV || f == export.FormatCSV,
+	}
+	for c := sel.Left; c <= sel.Right; c++ {
+		opts.Cols = append(opts.Cols, vt.SourceCol(c))
+	}
+	var sb strings.Builder
+	if err := export.Export(&sb, vt.source, opts); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// ErrReadOnly 数据源不支持编辑
+var ErrReadOnly = errors.New("this table is read-only")
+
+// Paste 把 TSV 文本（电子表格复制出的格式）从活动单元格开始写入编辑覆盖层，
+// 超出表格范围的部分被忽略，粘贴后选中写入的区域
+func (vt *VirtualTable) Paste(text string) error {
+	ed, ok := loader.AsEditor(vt.source)
+	if !ok {
+		return ErrReadOnly
+	}
+	if vt.selected == nil {
+		return errors.New("select the cell to paste into first")
+	}
+	records, err := parseTSV(text)
+	if err != nil {
+		return err
+	}
+	start := *vt.selected
+	rows, cols := vt.source.RowCount(), vt.colCount()
+	end := start
+	for i, rec := range records {
+		r := start.Row + i
+		if r >= rows {
+			break
+		}
+		for j, v := range rec {
+			c := start.Col + j
+			if c >= cols {
+				break
+			}
+			ed.SetEdit(r, vt.SourceCol(c), v)
+			end = widget.TableCellID{Row: max(end.Row, r), Col: max(end.Col, c)}
+		}
+	}
+	vt.selectRange(start, end, false)
+	return nil
+}
+
+// SetDeleted 把行标记（或取消标记）为删除，rows 为 nil 时作用于选区中的行
+func (vt *VirtualTable) SetDeleted(rows []int, deleted bool) error {
+	ed, ok := loader.AsEditor(vt.source)
+	if !ok {
+		return ErrReadOnly
+	}
+	if rows == nil {
+		sel, ok := vt.Selection()
+		if !ok {
+			return errors.New("select the rows first")
+		}
+		for r := sel.Top; r <= sel.Bottom; r++ {
+			rows = append(rows, r)
+		}
+	}
+	ed.SetDeleted(rows, deleted)
+	vt.Table.Refresh()
+	return nil
+}
+
+// parseTSV 解析制表符分隔的文本，支持电子表格为含换行的单元格加的引号
+func parseTSV(text string) ([][]string, error) {
+	text = strings.TrimRight(text, "\r\n")
+	if text == "" {
+		return nil, nil
+	}
+	cr := csv.NewReader(strings.NewReader(text))
+	cr.Comma = '\t'
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	return cr.ReadAll()
+}
+
+// tableCell 数据单元格：按下开始选区，按住拖过其他单元格时扩展选区
+type tableCell struct {
+	widget.BaseWidget
+	vt     *VirtualTable
+	id     widget.TableCellID
+	label  *widget.Label
+	bg     *canvas.Rectangle
+	bgName fyne.ThemeColorName
+}
+
+func newTableCell(vt *VirtualTable) *tableCell {
+	c := &tableCell{vt: vt, label: widget.NewLabel(""), bg: canvas.NewRectangle(theme.Color(theme.ColorNameSelection))}
+	c.label.Truncation = fyne.TextTruncateEllipsis
+	c.bg.Hide()
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+// setBackground 设置单元格背景色，name 为空时不绘制背景；
+// 选区以外的标记色调成半透明，避免盖住文字
+func (c *tableCell) setBackground(name fyne.ThemeColorName) {
+	if name == c.bgName {
+		return
+	}
+	c.bgName = name
+	if name == "" {
+		c.bg.Hide()
+		return
+	}
+	col := theme.Color(name)
+	if name != theme.ColorNameSelection {
+		r, g, b, _ := col.RGBA()
+		col = color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: 0x50}
+	}
+	c.bg.FillColor = col
+	c.bg.Show()
+	c.bg.Refresh()
+}
+
+func (c *tableCell) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewStack(c.bg, c.label))
+}
+
+func (c *tableCell) MouseDown(e *desktop.MouseEvent) {
+	if e.Button == desktop.MouseButtonPrimary {
+		c.vt.press(c.id, e.Modifier&fyne.KeyModifierShift != 0)
+	}
+}
+
+func (c *tableCell) MouseUp(*desktop.MouseEvent) {
+	c.vt.dragging = false
+}
+
+func (c *tableCell) MouseIn(e *desktop.MouseEvent) {
+	c.vt.dropCol = c.id.Col
+	c.MouseMoved(e)
+}
+
+func (c *tableCell) MouseMoved(e *desktop.MouseEvent) {
+	if !c.vt.dragging {
+		return
+	}
+	if e.Button != desktop.MouseButtonPrimary {
+		c.vt.dragging = false
+		return
+	}
+	if c.vt.selected == nil || *c.vt.selected != c.id {
+		c.vt.selectRange(c.id, c.id, true)
+	}
+}
+
+func (c *tableCell) MouseOut() {}
+
+// headerCell 列头或行号，点击选中整列或整行；拖动列头到另一列上调整列顺序
+type headerCell struct {
+	widget.BaseWidget
+	vt    *VirtualTable
+	id    widget.TableCellID
+	label *widget.Label
+}
+
+func newHeaderCell(vt *VirtualTable) *headerCell {
+	h := &headerCell{vt: vt, label: widget.NewLabel("")}
+	h.label.TextStyle.Bold = true
+	h.label.Truncation = fyne.TextTruncateEllipsis
+	h.ExtendBaseWidget(h)
+	return h
+}
+
+func (h *headerCell) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(h.label)
+}
+
+func (h *headerCell) Tapped(*fyne.PointEvent) {
+	h.vt.tapHeader(h.id)
+}
+
+// autoFitZone 列头右边缘双击自动调整列宽的范围（像素）
+const autoFitZone = 6
+
+// DoubleTapped 双击列头右边缘按内容调整列宽；双击其他位置和单击一样选中整列
+func (h *headerCell) DoubleTapped(e *fyne.PointEvent) {
+	if h.id.Row < 0 && h.id.Col >= 0 && e.Position.X >= h.Size().Width-autoFitZone {
+		h.vt.AutoFitColumns(h.id.Col)
+		return
+	}
+	h.vt.tapHeader(h.id)
+}
+
+func (h *headerCell) Dragged(*fyne.DragEvent) {
+	if h.id.Row < 0 && h.id.Col >= 0 {
+		h.vt.dragCol = &h.id.Col
+	}
+}
+
+func (h *headerCell) DragEnd() {
+	if from, to := h.vt.dragCol, h.vt.dropCol; from != nil && to >= 0 {
+		h.vt.MoveColumn(*from, to)
+	}
+	h.vt.dragCol = nil
+}
+
+// MouseIn 拖动列头经过的列作为放下的位置；拖动中其他列头仍会收到悬停事件
+func (h *headerCell) MouseIn(*desktop.MouseEvent) {
+	h.vt.dropCol = -1
+	if h.id.Row < 0 {
+		h.vt.dropCol = h.id.Col
+	}
+}
+
+func (h *headerCell) MouseMoved(*desktop.MouseEvent) {}
+
+func (h *headerCell) MouseOut() {}
@@ -2,23 +2,54 @@ package shower
 
 import (
 	"fmt"
-	"log"
-	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/devbiu/CsvView/loader"
 )
 
 type VirtualTable struct {
-	Table       *widget.Table
-	loader      *loader.CSVLoader
-	Scroll      *container.Scroll
-	visibleRows int
-	startRow    int
-	totalRows   int
+	Table  *widget.Table
+	source loader.RowSource
+	Scroll *container.Scroll
+
+	pendingGoTo *widget.TableCellID // 目标行尚未索引到时，等索引推进后再跳转
+	// anchor 与 selected 是选区的两个对角，selected 为当前活动单元格
+	anchor, selected *widget.TableCellID
+	dragging         bool // 正在拖动鼠标扩展选区
+	// loading 显示为 loading... 的单元格，行加载完成后只刷新这些单元格（仅在主线程访问）
+	loading     map[widget.TableCellID]struct{}
+	unsubscribe func()
+	// projected 已告知数据源需要解码的列（仅对 ColumnProjector 数据源）
+	projected  map[int]bool
+	sizedCols  int             // 已计算宽度的源列数
+	widths     map[int]float32 // 按源列记录的列宽，列顺序变化时重新应用
+	userWidths map[int]bool    // 用户调整或自动适配过宽度的源列，保存到文件的列布局
+	applying   bool            // 正在把 widths 设置到表格上，期间的列头尺寸变化不是用户拖动
+	saveTimer  *time.Timer
+	lastRow    int // 最近显示的行，自动列宽时参考附近的行
+
+	// 列布局：order 为按显示顺序排列的源列（可以只列出一部分），hidden 为隐藏的源列，
+	// cols 是由它们算出的显示列到源列的映射，源列数 colsFor 变化时重新计算
+	order         []int
+	hidden        map[int]bool
+	cols          []int
+	colsFor       int
+	layoutKey     string        // 保存列布局用的文件路径
+	pendingLayout *ColumnLayout // 表头到达前读到的布局
+	dragCol       *int          // 正在拖动的列头
+	dropCol       int           // 鼠标所在的列，拖动列头结束时移到这里
+
+	onSelection []func()
+	// cellColor 按单元格返回标记背景色（如比较结果中的修改），为空表示不标记
+	cellColor func(row, col int) fyne.ThemeColorName
+	// autoScroll 行数增加时滚动到最后一行（跟踪文件追加时使用）
+	autoScroll bool
 }
 
 var CSVLoaderDebug = [][]string{
@@ -45,134 +76,293 @@ func DebugTable(l *loader.CSVLoader) *widget.Table {
 	for i := range len(CSVLoaderDebug[0]) {
 		table.SetColumnWidth(i, float32((len((CSVLoaderDebug[0])[i])*8)+5))
 	}
-	go updateDebugTable(table, l)
+	updateDebugTable(table, l)
+	l.Subscribe(func(loader.Event) {
+		fyne.Do(func() { updateDebugTable(table, l) })
+	})
 	return table
 }
 
+// updateDebugTable 在主线程上刷新加载器状态
 func updateDebugTable(table *widget.Table, l *loader.CSVLoader) {
-	for {
-		time.Sleep(1 * time.Second)
-		l.TryRLock()
-		CSVLoaderDebug[1][0] = l.ErrMsg
-		CSVLoaderDebug[1][1] = fmt.Sprintf("%d", l.CacheStart)
-		CSVLoaderDebug[1][2] = fmt.Sprintf("%d", l.CacheEnd)
-		CSVLoaderDebug[1][3] = fmt.Sprintf("%d", l.ActiveIndex)
-		CSVLoaderDebug[1][4] = fmt.Sprintf("%d", len(l.Cache))
-		l.Mu.RUnlock()
-		fyne.Do(func() {
-			table.Refresh()
-		})
-		time.Sleep(1 * time.Second)
-	}
-
+	l.Mu.RLock()
+	CSVLoaderDebug[1][0] = l.ErrMsg
+	CSVLoaderDebug[1][1] = fmt.Sprintf("%d", l.CacheStart)
+	CSVLoaderDebug[1][2] = fmt.Sprintf("%d", l.CacheEnd)
+	CSVLoaderDebug[1][3] = fmt.Sprintf("%d", l.ActiveIndex)
+	CSVLoaderDebug[1][4] = fmt.Sprintf("%d", len(l.Cache))
+	l.Mu.RUnlock()
+	table.Refresh()
 }
 
-func NewVirtualTable(l *loader.CSVLoader) *VirtualTable {
+// NewVirtualTable 创建由 RowSource 驱动的虚拟表格，只渲染可见单元格
+func NewVirtualTable(src loader.RowSource) *VirtualTable {
 	vt := &VirtualTable{
-		loader:      l,
-		visibleRows: 100,
-		//totalRows:   len(l.Cache),
-		totalRows: int(l.CacheEnd),
+		source:     src,
+		loading:    make(map[widget.TableCellID]struct{}),
+		widths:     make(map[int]float32),
+		userWidths: make(map[int]bool),
+		dropCol:    -1,
 	}
 	vt.Table = widget.NewTable(
 		func() (int, int) {
-			// TODO 根据实际需要返回总行数和列数
-			//return vt.totalRows, len(l.Cache[1])
-			return int(l.CacheEnd), len(l.Cache[1])
+			return src.RowCount(), vt.colCount()
 		},
 
 		func() fyne.CanvasObject {
-			label := widget.NewLabel("")
-			label.Truncation = fyne.TextTruncateEllipsis
-			label.Selectable = true
-			return label
+			return newTableCell(vt)
 		},
 
 		func(id widget.TableCellID, obj fyne.CanvasObject) {
-			lbl := obj.(*widget.Label)
-
-			//if id.Row < vt.startRow || id.Row >= vt.startRow+vt.visibleRows {
-			//	lbl.SetText("") // 不在可见范围内，设置为空
-			//	return
-			//}
-			// 加载实际内容
-			row := vt.startRow + (id.Row - vt.startRow)
-			data, update := l.GetRowV2(row)
-			if id.Col < len(data) && data != nil {
-				lbl.SetText(data[id.Col])
-			} else {
-				lbl.SetText("loading...")
+			cell := obj.(*tableCell)
+			cell.id = id
+			cell.setBackground(vt.cellBackground(id))
+			col := vt.SourceCol(id.Col)
+			vt.project(col)
+			vt.lastRow = id.Row
+			// 未命中缓存时数据源会在后台读取，读完后通过 EventRowsLoaded 刷新
+			data, ok := src.GetRow(id.Row)
+			if !ok {
+				vt.loading[id] = struct{}{}
+				cell.label.SetText("loading...")
+				return
 			}
-			if update {
-				fyne.Do(func() {
-					vt.Table.Refresh()
-				})
+			delete(vt.loading, id)
+			if col >= 0 && col < len(data) {
+				cell.label.SetText(data[col])
+			} else {
+				cell.label.SetText("")
 			}
-
 		},
 	)
-
-	// 设置列宽
-	setWidthInd := int(math.Min(float64(len(l.Cache)), 2))
-	for i := range len(l.Cache[setWidthInd]) {
-		vt.Table.SetColumnWidth(i, float32(len(l.Cache[setWidthInd][i])*8)+50)
+	vt.Table.ShowHeaderRow = true
+	vt.Table.ShowHeaderColumn = true
+	// 键盘导航（方向键 + 空格）和 GoTo 通过表格自身的选中进入区域选择
+	vt.Table.OnSelected = func(id widget.TableCellID) {
+		vt.selectRange(id, id, vt.shiftHeld())
+	}
+	vt.Table.CreateHeader = func() fyne.CanvasObject {
+		return newHeaderCell(vt)
+	}
+	vt.Table.UpdateHeader = func(id widget.TableCellID, obj fyne.CanvasObject) {
+		cell := obj.(*headerCell)
+		cell.id = id
+		col := vt.SourceCol(id.Col)
+		if id.Row < 0 && col >= 0 {
+			vt.noteHeaderWidth(col, obj.Size().Width)
+		}
+		switch {
+		case id.Col < 0:
+			cell.label.SetText(vt.rowLabel(id.Row))
+		case col >= 0 && col < len(src.Header()):
+			cell.label.SetText(src.Header()[col])
+		default:
+			cell.label.SetText("")
+		}
 	}
 
-	// 监听滚动事件，更新 startRow
+	vt.sizeColumns()
+	vt.sizeRowHeader()
+
+	vt.unsubscribe = src.Subscribe(func(e loader.Event) {
+		switch e.Kind {
+		case loader.EventRowsLoaded, loader.EventRowCountChanged:
+			fyne.Do(func() { vt.handleEvent(e) })
+		}
+	})
+
 	vt.Scroll = container.NewScroll(container.NewStack(vt.Table))
 	vt.Scroll.Direction = fyne.ScrollNone
-	//vt.Scroll.OnScrolled = func(offset fyne.Position) {
-	//	log.Printf("y: %f \n", offset.Y)
-	//}
-	vt.Scroll.OnScrolled = nil
-
-	go vt.onScrollListener()
 	return vt
 }
 
-func (vt *VirtualTable) onScroll(offset fyne.Position) {
-	log.Printf("y: %f \n", offset.Y)
-	// 获取滚动条进度
-	progress := offset.Y / vt.Table.MinSize().Height
-	if progress > 0.8 {
-		vt.startRow += vt.visibleRows
-		if vt.startRow+vt.visibleRows > vt.totalRows {
-			vt.startRow = vt.totalRows - vt.visibleRows
+// sizeColumns 按表头和已加载的样本行测量列宽，已保存宽度的列不变；
+// 流式数据源的表头可能稍后才到，届时再计算
+func (vt *VirtualTable) sizeColumns() {
+	header, rows := vt.source.Header(), vt.sampleRows()
+	for i := vt.sizedCols; i < vt.source.ColCount(); i++ {
+		if !vt.userWidths[i] {
+			vt.widths[i] = fitWidth(i, header, rows)
 		}
-		vt.Table.Refresh()
 	}
+	if len(header) > 0 || len(rows) > 0 {
+		vt.sizedCols = vt.source.ColCount()
+	}
+	vt.applyWidths()
+}
 
-	if progress < 0.2 && vt.startRow > 0 {
-		vt.startRow -= vt.visibleRows
-		if vt.startRow < 0 {
-			vt.startRow = 0
+// applyWidths 把按源列记录的列宽设置到对应的显示列上
+func (vt *VirtualTable) applyWidths() {
+	vt.applying = true
+	defer func() { vt.applying = false }()
+	for i, c := range vt.columns() {
+		if w, ok := vt.widths[c]; ok {
+			vt.Table.SetColumnWidth(i, w)
 		}
-		vt.Table.Refresh()
 	}
 }
 
-func onScrollListener(sr *container.Scroll) {
-	log.Println("start scroll listener")
-	prev := fyne.NewPos(0, 0)
-	for {
-		pos := sr.Offset
-		if pos != prev {
-			log.Printf("y: %f \n", pos)
-			prev = pos
+// rowLabel 行号列的文字：能换算时显示文件中的物理行号，筛选或排序后显示为“行 [物理行]”
+func (vt *VirtualTable) rowLabel(row int) string {
+	ll, ok := loader.AsLineLocator(vt.source)
+	if !ok {
+		return strconv.Itoa(row + 1)
+	}
+	line := ll.LineOf(row)
+	switch {
+	case line == 0:
+		return strconv.Itoa(row + 1)
+	case vt.mapped():
+		return strconv.Itoa(row+1) + " [" + strconv.Itoa(line) + "]"
+	default:
+		return strconv.Itoa(line)
+	}
+}
+
+// mapped 视图是否经过筛选或排序
+func (vt *VirtualTable) mapped() bool {
+	v, ok := vt.source.(*loader.View)
+	return ok && v.Mapped()
+}
+
+// sizeRowHeader 行号列的宽度随行数和最大物理行号的位数变化
+func (vt *VirtualTable) sizeRowHeader() {
+	n := max(vt.source.RowCount(), 1)
+	label := strconv.Itoa(n)
+	base := vt.source
+	if v, ok := base.(*loader.View); ok {
+		base = v.Base()
+	}
+	if ll, ok := base.(loader.LineLocator); ok {
+		if line := ll.LineOf(base.RowCount() - 1); line > 0 {
+			label = strconv.Itoa(max(line, n))
+			if vt.mapped() {
+				label = strconv.Itoa(n) + " [" + strconv.Itoa(line) + "]"
+			}
 		}
-		time.Sleep(500 * time.Millisecond)
 	}
+	// 数字都按 0 测量，宽度只随位数变化
+	label = strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return '0'
+		}
+		return r
+	}, label)
+	vt.Table.SetColumnWidth(-1, cellTextWidth(label, fyne.TextStyle{Bold: true}))
 }
 
-func (vt *VirtualTable) onScrollListener() {
-	log.Println("start scroll listener")
-	prev := fyne.NewPos(0, 0)
-	for {
-		pos := vt.Scroll.Offset
-		if pos != prev {
-			log.Printf("y: %f \n", pos)
-			prev = pos
+// maxProjectedCols 投影列数上限，超过后只保留当前列附近的列
+const maxProjectedCols = 64
+
+// project 把渲染到的列加入数据源的解码投影
+func (vt *VirtualTable) project(col int) {
+	p, ok := vt.source.(loader.ColumnProjector)
+	if !ok || vt.projected[col] {
+		return
+	}
+	if vt.projected == nil || len(vt.projected) >= maxProjectedCols {
+		vt.projected = make(map[int]bool)
+		for c := max(col-maxProjectedCols/2, 0); c < min(col+maxProjectedCols/2, vt.source.ColCount()); c++ {
+			vt.projected[c] = true
+		}
+	}
+	vt.projected[col] = true
+	cols := make([]int, 0, len(vt.projected))
+	for c := range vt.projected {
+		cols = append(cols, c)
+	}
+	p.SetProjection(cols)
+}
+
+// handleEvent 在主线程上处理数据源事件，只刷新受影响的单元格
+func (vt *VirtualTable) handleEvent(e loader.Event) {
+	switch e.Kind {
+	case loader.EventRowsLoaded:
+		for id := range vt.loading {
+			if e.Contains(id.Row) {
+				delete(vt.loading, id)
+				vt.Table.RefreshItem(id)
+			}
+		}
+	case loader.EventRowCountChanged:
+		// 行数变化需要重新计算滚动范围
+		if vt.pendingLayout != nil {
+			vt.applyPendingLayout()
+		}
+		if vt.sizedCols < vt.source.ColCount() {
+			vt.sizeColumns()
 		}
-		time.Sleep(time.Second)
+		vt.sizeRowHeader()
+		vt.Table.Refresh()
+		if vt.autoScroll {
+			vt.Table.ScrollToBottom()
+		}
+		if p := vt.pendingGoTo; p != nil && (p.Row < e.Rows || vt.source.Indexed()) {
+			vt.pendingGoTo = nil
+			vt.GoTo(p.Row, p.Col)
+		}
+	}
+}
+
+// Close 取消订阅并关闭数据源
+func (vt *VirtualTable) Close() {
+	if vt.unsubscribe != nil {
+		vt.unsubscribe()
+	}
+	vt.source.Close()
+}
+
+// SetCellColor 设置单元格标记色，选中的单元格仍显示选区颜色
+func (vt *VirtualTable) SetCellColor(fn func(row, col int) fyne.ThemeColorName) {
+	vt.cellColor = fn
+	vt.Table.Refresh()
+}
+
+// cellBackground 单元格的背景色：选区优先，其次是标记删除的行，最后是标记色
+func (vt *VirtualTable) cellBackground(id widget.TableCellID) fyne.ThemeColorName {
+	if vt.inSelection(id) {
+		return theme.ColorNameSelection
+	}
+	if ed, ok := loader.AsEditor(vt.source); ok && ed.Deleted(id.Row) {
+		return theme.ColorNameError
+	}
+	if vt.cellColor != nil {
+		return vt.cellColor(id.Row, vt.SourceCol(id.Col))
+	}
+	return ""
+}
+
+// SetAutoScroll 设置行数增加时是否自动滚动到最后一行
+func (vt *VirtualTable) SetAutoScroll(on bool) {
+	vt.autoScroll = on
+	if on {
+		vt.Table.ScrollToBottom()
+	}
+}
+
+// OnSelectionChanged 注册选区变化回调（在主线程上调用）
+func (vt *VirtualTable) OnSelectionChanged(fn func()) {
+	vt.onSelection = append(vt.onSelection, fn)
+}
+
+func (vt *VirtualTable) selectionChanged() {
+	for _, fn := range vt.onSelection {
+		fn()
+	}
+}
+
+// Source 返回表格背后的数据源
+func (vt *VirtualTable) Source() loader.RowSource {
+	return vt.source
+}
+
+// GoTo 滚动到指定单元格（从 0 开始）并选中
+func (vt *VirtualTable) GoTo(row, col int) {
+	id := widget.TableCellID{Row: row, Col: col}
+	if row >= vt.source.RowCount() && !vt.source.Indexed() {
+		vt.pendingGoTo = &id
+		return
 	}
+	vt.Table.ScrollTo(id)
+	vt.Table.Select(id)
 }
@@ -0,0 +1,166 @@
+package shower
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/loader"
+	"github.com/devbiu/CsvView/ops"
+)
+
+// ShowSplitDialog 选择拆分方式把 CSV/TSV 文件拆成多个文件，输出目录默认与输入文件相同
+func ShowSplitDialog(w fyne.Window, path string) {
+	rf, err := loader.OpenRawFile(path)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	columns := rf.Columns()
+	rf.Close()
+
+	rowsEntry := widget.NewEntry()
+	rowsEntry.SetText("100000")
+	mbEntry := widget.NewEntry()
+	mbEntry.SetText("100")
+	column := widget.NewSelect(columns, nil)
+	if len(columns) > 0 {
+		column.SetSelected(columns[0])
+	}
+	modes := make([]string, len(ops.SplitBys))
+	for i, m := range ops.SplitBys {
+		modes[i] = string(m)
+	}
+	mode := widget.NewRadioGroup(modes, func(m string) {
+		rowsEntry.Disable()
+		mbEntry.Disable()
+		column.Disable()
+		switch ops.SplitBy(m) {
+		case ops.SplitRows:
+			rowsEntry.Enable()
+		case ops.SplitBytes:
+			mbEntry.Enable()
+		case ops.SplitColumn:
+			column.Enable()
+		}
+	})
+	mode.Horizontal = true
+	mode.SetSelected(string(ops.SplitRows))
+
+	dir := filepath.Dir(path)
+	dirLabel := widget.NewLabel(dir)
+	dirLabel.Truncation = fyne.TextTruncateEllipsis
+	dirButton := widget.NewButton("Choose...", func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if uri != nil {
+				dir = uri.Path()
+				dirLabel.SetText(dir)
+			}
+		}, w)
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem("Split by", mode),
+		widget.NewFormItem("Rows per file", rowsEntry),
+		widget.NewFormItem("MB per file", mbEntry),
+		widget.NewFormItem("Column", column),
+		widget.NewFormItem("Output folder", container.NewBorder(nil, nil, nil, dirButton, dirLabel)),
+	)
+	d := dialog.NewCustomConfirm("Split "+filepath.Base(path), "Split", "Cancel", form, func(ok bool) {
+		if !ok {
+			return
+		}
+		opts := ops.SplitOptions{By: ops.SplitBy(mode.Selected), Column: column.Selected, Dir: dir}
+		switch opts.By {
+		case ops.SplitRows:
+			opts.Rows, _ = strconv.Atoi(strings.TrimSpace(rowsEntry.Text))
+		case ops.SplitBytes:
+			mb, _ := strconv.ParseFloat(strings.TrimSpace(mbEntry.Text), 64)
+			opts.Bytes = int64(mb * (1 << 20))
+		}
+		runOperation(w, "Split", func(cancel <-chan struct{}, progress func(int)) ([]string, error) {
+			opts.Cancel, opts.Progress = cancel, progress
+			return ops.SplitFile(path, opts)
+		}, func(files []string) {
+			dialog.ShowInformation("Split", fmt.Sprintf("Wrote %d files to %s", len(files), dir), w)
+		})
+	}, w)
+	d.Resize(fyne.NewSize(520, 340))
+	d.Show()
+}
+
+// ShowMergeDialog 选择多个文件上下拼接，列按名称对应，结果通过 onDone 返回
+func ShowMergeDialog(w fyne.Window, onDone func(title string, res *loader.SpillSource)) {
+	var paths []string
+	list := container.NewVBox()
+	var refresh func()
+	refresh = func() {
+		list.Objects = nil
+		for i, p := range paths {
+			list.Add(removableRow(widget.NewLabel(filepath.Base(p)), func() {
+				paths = append(paths[:i:i], paths[i+1:]...)
+				refresh()
+			}))
+		}
+		list.Refresh()
+	}
+	addButton := widget.NewButton("Add File...", func() {
+		fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			paths = appendUnique(paths, reader.URI().Path())
+			reader.Close()
+			refresh()
+		}, w)
+		fd.SetFilter(storage.NewExtensionFileFilter(loader.OpenExtensions))
+		fd.Show()
+	})
+
+	content := container.NewBorder(widget.NewLabel("Files are appended in this order"), addButton, nil, nil,
+		container.NewVScroll(list))
+	d := dialog.NewCustomConfirm("Merge Files", "Merge", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		if len(paths) < 2 {
+			dialog.ShowInformation("Merge Files", "Add at least two files", w)
+			return
+		}
+		files := append([]string(nil), paths...)
+		runOperation(w, "Merge", func(cancel <-chan struct{}, progress func(int)) (*loader.SpillSource, error) {
+			var srcs []loader.RowSource
+			defer func() {
+				for _, src := range srcs {
+					src.Close()
+				}
+			}()
+			for _, p := range files {
+				src, err := loader.Open(p)
+				if err != nil {
+					return nil, err
+				}
+				srcs = append(srcs, src)
+			}
+			return ops.Merge(srcs, ops.MergeOptions{Cancel: cancel, Progress: progress})
+		}, func(res *loader.SpillSource) {
+			onDone(filepath.Base(files[0])+" (merged)", res)
+		})
+	}, w)
+	d.Resize(fyne.NewSize(450, 400))
+	d.Show()
+}
@@ -0,0 +1,79 @@
+package shower
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/loader"
+)
+
+// NewStatusBar 表格下方的状态栏：活动单元格所在的行和物理行号、总行数（筛选后同时显示筛选前的行数），
+// 以及后台索引的进度。索引推进时行数随之更新
+func NewStatusBar(vt *VirtualTable) fyne.CanvasObject {
+	position := widget.NewLabel("")
+	indexing := widget.NewLabel("")
+	var progress float64
+	update := func() {
+		position.SetText(statusText(vt))
+		if vt.source.Indexed() {
+			indexing.SetText("")
+		} else {
+			indexing.SetText(fmt.Sprintf("Indexing... %.0f%%", progress*100))
+		}
+	}
+	vt.OnSelectionChanged(update)
+	vt.source.Subscribe(func(e loader.Event) {
+		switch e.Kind {
+		case loader.EventIndexProgress, loader.EventRowCountChanged:
+			fyne.Do(func() {
+				if e.Kind == loader.EventIndexProgress {
+					progress = e.Progress
+				}
+				update()
+			})
+		}
+	})
+	update()
+	return container.NewHBox(position, layout.NewSpacer(), indexing)
+}
+
+// statusText 状态栏中的位置和行数
+func statusText(vt *VirtualTable) string {
+	n := vt.source.RowCount()
+	var sb strings.Builder
+	if vt.selected != nil {
+		row := vt.selected.Row
+		fmt.Fprintf(&sb, "Row %s of %s", groupDigits(row+1), groupDigits(n))
+		if ll, ok := loader.AsLineLocator(vt.source); ok {
+			if line := ll.LineOf(row); line > 0 {
+				sb.WriteString(" · Line " + groupDigits(line))
+			}
+		}
+	} else {
+		sb.WriteString(groupDigits(n) + " rows")
+	}
+	if !vt.source.Indexed() {
+		sb.WriteString(" so far")
+	}
+	if v, ok := vt.source.(*loader.View); ok && v.Filter() != "" {
+		sb.WriteString(" (filtered from " + groupDigits(v.Base().RowCount()) + ")")
+	}
+	return sb.String()
+}
+
+// groupDigits 按千位分隔显示数字
+func groupDigits(n int) string {
+	if n < 0 {
+		return "-" + groupDigits(-n)
+	}
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
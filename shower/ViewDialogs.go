@@ -0,0 +1,106 @@
+package shower
+
+import (
+	"errors"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devbiu/CsvView/expr"
+	"github.com/devbiu/CsvView/loader"
+)
+
+// ShowComputedDialog 输入列名和表达式，在视图末尾追加计算列
+func ShowComputedDialog(w fyne.Window, view *loader.View) {
+	name := widget.NewEntry()
+	name.SetPlaceHolder("column name")
+	source := widget.NewMultiLineEntry()
+	source.SetPlaceHolder("price * qty")
+	source.Wrapping = fyne.TextWrapWord
+	status := expressionStatus(source, view.Header())
+
+	form := widget.NewForm(
+		widget.NewFormItem("Name", name),
+		widget.NewFormItem("Expression", source),
+	)
+	content := container.NewBorder(form, nil, nil, nil, status)
+	d := dialog.NewCustomConfirm("Add Column", "Add", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		e, err := expr.Compile(source.Text, view.Header())
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		title := strings.TrimSpace(name.Text)
+		if title == "" {
+			title = e.String()
+		}
+		view.AddComputed(e.Computed(title))
+	}, w)
+	d.Resize(fyne.NewSize(520, 320))
+	d.Show()
+}
+
+// ShowFilterDialog 编辑视图的筛选条件，条件为空时显示全部行
+func ShowFilterDialog(w fyne.Window, view *loader.View) {
+	source := widget.NewMultiLineEntry()
+	source.SetPlaceHolder("status == 'open' and amount > 100")
+	source.Wrapping = fyne.TextWrapWord
+	source.SetText(view.Filter())
+	status := expressionStatus(source, view.Header())
+
+	content := container.NewBorder(source, nil, nil, nil, status)
+	d := dialog.NewCustomConfirm("Filter", "Apply", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		if strings.TrimSpace(source.Text) == "" {
+			view.SetFilter("", nil)
+			return
+		}
+		e, err := expr.Compile(source.Text, view.Header())
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		view.SetFilter(e.String(), e.Predicate())
+	}, w)
+	d.Resize(fyne.NewSize(520, 320))
+	d.Show()
+}
+
+// SortBy 按活动单元格所在的列排序
+func SortBy(vt *VirtualTable, desc bool) error {
+	view, ok := vt.Source().(*loader.View)
+	if !ok {
+		return errors.New("this table cannot be sorted")
+	}
+	if vt.selected == nil {
+		return errors.New("select a cell in the column to sort by first")
+	}
+	view.SetSort([]loader.SortKey{{Col: vt.SourceCol(vt.selected.Col), Desc: desc}})
+	return nil
+}
+
+// expressionStatus 随输入检查表达式，显示编译错误或可用的函数
+func expressionStatus(source *widget.Entry, header []string) fyne.CanvasObject {
+	hint := "Functions: " + strings.Join(expr.Functions(), ", ")
+	status := widget.NewLabel(hint)
+	status.Wrapping = fyne.TextWrapWord
+	source.OnChanged = func(text string) {
+		if strings.TrimSpace(text) == "" {
+			status.SetText(hint)
+			return
+		}
+		if _, err := expr.Compile(text, header); err != nil {
+			status.SetText(err.Error())
+			return
+		}
+		status.SetText(hint)
+	}
+	return container.NewVScroll(status)
+}